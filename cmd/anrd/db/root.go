@@ -0,0 +1,80 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sobadon/anrd/cmd/anrd/internal/setup"
+	"github.com/sobadon/anrd/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func Command() *cobra.Command {
+	var configFile string
+	rootCmd := &cobra.Command{
+		Use:   "db",
+		Short: "manage database",
+	}
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", os.Getenv("ATR_CONFIG_FILE"), "path to config file (YAML)")
+
+	rootCmd.AddCommand(migrateCommand(&configFile))
+	return rootCmd
+}
+
+func migrateCommand(configFile *string) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "apply pending schema migrations (sqlite is backed up before migration)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+
+			db, err := setup.OpenDB(c)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			out := cmd.OutOrStdout()
+			current, latest, err := setup.SchemaVersion(c, db)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%s: current version: %d, latest version: %d\n", c.DatabaseDriver, current, latest)
+
+			if dryRun {
+				pending, err := setup.PendingMigrations(c, db)
+				if err != nil {
+					return err
+				}
+				if len(pending) == 0 {
+					fmt.Fprintln(out, "no pending migrations")
+					return nil
+				}
+				for _, migration := range pending {
+					fmt.Fprintf(out, "-- pending: %s\n%s\n", migration, migration.SQL)
+				}
+				return nil
+			}
+
+			applied, err := setup.Migrate(c, db, time.Now())
+			for _, migration := range applied {
+				fmt.Fprintf(out, "applied: %s\n", migration)
+			}
+			if err != nil {
+				return err
+			}
+			if len(applied) == 0 {
+				fmt.Fprintln(out, "no pending migrations")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show pending migrations without applying them")
+	return cmd
+}
@@ -0,0 +1,205 @@
+// サブコマンド間で共通する、設定からの組み立て
+package setup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/infrastructures/agqr"
+	"github.com/sobadon/anrd/infrastructures/onsen"
+	"github.com/sobadon/anrd/infrastructures/postgres"
+	"github.com/sobadon/anrd/infrastructures/s3"
+	"github.com/sobadon/anrd/infrastructures/sqlite"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/config"
+	"github.com/sobadon/anrd/internal/migration"
+)
+
+// database_driver のデータベースを開き、スキーマを最新にする
+// 呼び出し元で db を Close すること
+func OpenProgramPersistence(c config.Config) (repository.ProgramPersistence, *sqlx.DB, error) {
+	db, err := OpenDB(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = Migrate(c, db, time.Now())
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return NewProgramPersistence(c, db), db, nil
+}
+
+// OpenDB で開いた db を使う
+func NewProgramPersistence(c config.Config, db *sqlx.DB) repository.ProgramPersistence {
+	if c.DatabaseDriver == config.DatabaseDriverPostgres {
+		return postgres.New(db)
+	}
+	return sqlite.New(db)
+}
+
+// database_driver のデータベースを開く
+// スキーマは変更しない
+func OpenDB(c config.Config) (*sqlx.DB, error) {
+	if c.DatabaseDriver == config.DatabaseDriverPostgres {
+		return postgres.NewDB(c.PostgresDSN)
+	}
+	return sqlite.NewDB(c.SqlitePath)
+}
+
+// 未適用のマイグレーションを適用し、適用したものを返す
+// sqlite であれば、適用前にバックアップをとる
+// postgres のバックアップは運用側で pg_dump などによってとること
+func Migrate(c config.Config, db *sqlx.DB, now time.Time) ([]migration.Migration, error) {
+	if c.DatabaseDriver == config.DatabaseDriverPostgres {
+		return postgres.Migrate(db)
+	}
+
+	pending, err := sqlite.PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	// まっさらなデータベースであればバックアップするものがない
+	current, err := sqlite.CurrentVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if current != 0 {
+		backupPath := fmt.Sprintf("%s.%s.bak", c.SqlitePath, now.Format("20060102150405"))
+		err = sqlite.Backup(db, backupPath)
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Msgf("backup database to %s before migration", backupPath)
+	}
+
+	return sqlite.Migrate(db)
+}
+
+// 適用済みの version と、最新の version
+func SchemaVersion(c config.Config, db *sqlx.DB) (current int, latest int, err error) {
+	if c.DatabaseDriver == config.DatabaseDriverPostgres {
+		current, err = postgres.CurrentVersion(db)
+		if err != nil {
+			return 0, 0, err
+		}
+		latest, err = postgres.LatestVersion()
+		return current, latest, err
+	}
+
+	current, err = sqlite.CurrentVersion(db)
+	if err != nil {
+		return 0, 0, err
+	}
+	latest, err = sqlite.LatestVersion()
+	return current, latest, err
+}
+
+// まだ適用されていないマイグレーション
+func PendingMigrations(c config.Config, db *sqlx.DB) ([]migration.Migration, error) {
+	if c.DatabaseDriver == config.DatabaseDriverPostgres {
+		return postgres.PendingMigrations(db)
+	}
+	return sqlite.PendingMigrations(db)
+}
+
+func NewStations(c config.Config, clk clock.Clock) (stationOnsen repository.Station, stationAgqr repository.Station, err error) {
+	stationOnsen = onsen.New(onsen.Config{
+		ProgramURL:     c.Stations.Onsen.ProgramURL,
+		FfmpegLoglevel: ffmpegLoglevel(c, c.Stations.Onsen.FfmpegLoglevel),
+		Clock:          clk,
+	})
+	stationAgqr, err = agqr.New(agqr.Config{
+		ProgramURL:         c.Stations.Agqr.ProgramURL,
+		StreamURL:          c.Stations.Agqr.StreamURL,
+		PlayerURL:          c.Stations.Agqr.PlayerURL,
+		StreamURLCacheTTL:  c.Stations.Agqr.StreamURLCacheTTL,
+		SecondaryStreamURL: c.Stations.Agqr.SecondaryStreamURL,
+		FfmpegLoglevel:     ffmpegLoglevel(c, c.Stations.Agqr.FfmpegLoglevel),
+		Clock:              clk,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return stationOnsen, stationAgqr, nil
+}
+
+// station 個別の指定がなければ recorder.ffmpeg_loglevel
+func ffmpegLoglevel(c config.Config, stationLoglevel string) string {
+	if stationLoglevel != "" {
+		return stationLoglevel
+	}
+	return c.Recorder.FfmpegLoglevel
+}
+
+// s3.endpoint が空であれば nil
+func NewObjectStorage(c config.Config) (repository.ObjectStorage, error) {
+	if c.S3.Endpoint == "" {
+		return nil, nil
+	}
+	return s3.New(s3.Config{
+		Endpoint:  c.S3.Endpoint,
+		AccessKey: c.S3.AccessKey,
+		SecretKey: c.S3.SecretKey,
+		Bucket:    c.S3.Bucket,
+		Region:    c.S3.Region,
+		UseSSL:    c.S3.UseSSL,
+		PartSize:  c.S3.PartSizeMiB * 1024 * 1024,
+	})
+}
+
+func RecorderConfig(c config.Config) recorder.Config {
+	return recorder.Config{
+		ArchiveDir:   c.ArchiveDir,
+		PrepareAfter: c.Recorder.PrepareAfter,
+		Margin:       c.Recorder.Margin,
+
+		RetryMaxCount: c.Recorder.RetryMaxCount,
+		ResumeBackoff: c.Recorder.ResumeBackoff,
+		MaxGap:        c.Recorder.MaxGap,
+
+		ContinuousCapture:    c.Recorder.ContinuousCapture,
+		ContinuousCaptureMax: c.Recorder.ContinuousCaptureMax,
+
+		RedundantRules: redundantRules(c.Redundant),
+
+		OndemandLimit:    c.Recorder.OndemandLimit,
+		OndemandInterval: c.Recorder.OndemandInterval,
+
+		MinFreeSpace:      c.Recorder.MinFreeSpaceMiB * 1024 * 1024,
+		DiskCheckInterval: c.Recorder.DiskCheckInterval,
+
+		RecordingLog:          c.Recorder.RecordingLog,
+		RecordingLogDir:       c.Recorder.RecordingLogDir,
+		RecordingLogRetention: time.Duration(c.Recorder.RecordingLogRetentionDays) * 24 * time.Hour,
+
+		StallTimeout:    c.Recorder.StallTimeout,
+		DeadlineGrace:   c.Recorder.DeadlineGrace,
+		OndemandTimeout: c.Recorder.OndemandTimeout,
+
+		ObjectKeyTemplate: c.S3.KeyTemplate,
+		RemoveAfterUpload: c.S3.RemoveAfterUpload,
+	}
+}
+
+func redundantRules(rulesConfig []config.RedundantRule) []recorder.RedundantRule {
+	var rules []recorder.RedundantRule
+	for _, ruleConfig := range rulesConfig {
+		rules = append(rules, recorder.RedundantRule{
+			Station: program.Station(ruleConfig.Station),
+			Title:   ruleConfig.Title,
+		})
+	}
+	return rules
+}
@@ -0,0 +1,163 @@
+package programs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type programJSON struct {
+	UUID        string    `json:"uuid"`
+	ID          int       `json:"id"`
+	Station     string    `json:"station"`
+	Title       string    `json:"title"`
+	Episode     string    `json:"episode,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Status      string    `json:"status"`
+	StreamType  string    `json:"stream_type"`
+	PlaylistURL string    `json:"playlist_url,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	FilePath    string    `json:"file_path,omitempty"`
+	Protected   bool      `json:"protected"`
+	ObjectKey   string    `json:"object_key,omitempty"`
+
+	Description   string   `json:"description,omitempty"`
+	Personalities []string `json:"personalities,omitempty"`
+}
+
+func toProgramJSON(pgram program.Program) programJSON {
+	return programJSON{
+		UUID:        pgram.UUID,
+		ID:          pgram.ID,
+		Station:     pgram.Station.String(),
+		Title:       pgram.Title,
+		Episode:     pgram.Episode,
+		Start:       pgram.Start,
+		End:         pgram.End,
+		Status:      pgram.Status.String(),
+		StreamType:  pgram.StreamType.String(),
+		PlaylistURL: pgram.PlaylistURL,
+		ImageURL:    pgram.ImageURL,
+		FilePath:    pgram.FilePath,
+		Protected:   pgram.Protected,
+		ObjectKey:   pgram.ObjectKey,
+
+		Description:   pgram.Description,
+		Personalities: pgram.Personalities,
+	}
+}
+
+// 一覧は 1 番組 1 行
+func writePrograms(w io.Writer, output string, pgrams []program.Program) error {
+	if output == outputJSON {
+		pgramsJSON := []programJSON{}
+		for _, pgram := range pgrams {
+			pgramsJSON = append(pgramsJSON, toProgramJSON(pgram))
+		}
+		return writeJSON(w, pgramsJSON)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tSTATION\tSTREAM TYPE\tSTATUS\tSTART\tTITLE\tEPISODE")
+	for _, pgram := range pgrams {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			pgram.UUID, pgram.Station, pgram.StreamType, pgram.Status, formatTime(pgram.Start), pgram.Title, orDash(pgram.Episode))
+	}
+	return flush(tw)
+}
+
+// 1 番組のすべての項目
+func writeProgram(w io.Writer, output string, pgram program.Program) error {
+	if output == outputJSON {
+		return writeJSON(w, toProgramJSON(pgram))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "UUID\t%s\n", pgram.UUID)
+	fmt.Fprintf(tw, "ID\t%d\n", pgram.ID)
+	fmt.Fprintf(tw, "STATION\t%s\n", pgram.Station)
+	fmt.Fprintf(tw, "TITLE\t%s\n", pgram.Title)
+	fmt.Fprintf(tw, "EPISODE\t%s\n", orDash(pgram.Episode))
+	fmt.Fprintf(tw, "START\t%s\n", formatTime(pgram.Start))
+	fmt.Fprintf(tw, "END\t%s\n", formatTime(pgram.End))
+	fmt.Fprintf(tw, "STATUS\t%s\n", pgram.Status)
+	fmt.Fprintf(tw, "STREAM TYPE\t%s\n", pgram.StreamType)
+	fmt.Fprintf(tw, "PLAYLIST URL\t%s\n", orDash(pgram.PlaylistURL))
+	fmt.Fprintf(tw, "IMAGE URL\t%s\n", orDash(pgram.ImageURL))
+	fmt.Fprintf(tw, "FILE PATH\t%s\n", orDash(pgram.FilePath))
+	fmt.Fprintf(tw, "PROTECTED\t%v\n", pgram.Protected)
+	fmt.Fprintf(tw, "OBJECT KEY\t%s\n", orDash(pgram.ObjectKey))
+	fmt.Fprintf(tw, "PERSONALITIES\t%s\n", orDash(strings.Join(pgram.Personalities, ", ")))
+	fmt.Fprintf(tw, "DESCRIPTION\t%s\n", orDash(pgram.Description))
+	return flush(tw)
+}
+
+type recordingLogJSON struct {
+	FilePath  string    `json:"file_path"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// 1 録画 1 行
+func writeRecordingLogs(w io.Writer, output string, recLogs []recorder.RecordingLog) error {
+	if output == outputJSON {
+		recLogsJSON := []recordingLogJSON{}
+		for _, recLog := range recLogs {
+			recLogsJSON = append(recLogsJSON, recordingLogJSON{FilePath: recLog.FilePath, StartedAt: recLog.StartedAt})
+		}
+		return writeJSON(w, recLogsJSON)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED AT\tFILE PATH")
+	for _, recLog := range recLogs {
+		fmt.Fprintf(tw, "%s\t%s\n", formatTime(recLog.StartedAt), recLog.FilePath)
+	}
+	return flush(tw)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(v)
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	return nil
+}
+
+func flush(tw *tabwriter.Writer) error {
+	err := tw.Flush()
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(timeutil.LocationJST()).Format("2006-01-02 15:04")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,321 @@
+package programs
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/cmd/anrd/internal/setup"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/config"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/ical"
+	"github.com/sobadon/anrd/internal/timeutil"
+	"github.com/sobadon/anrd/internal/xmltv"
+	"github.com/sobadon/anrd/usecase"
+	"github.com/spf13/cobra"
+)
+
+type options struct {
+	configFile string
+	output     string
+}
+
+func Command() *cobra.Command {
+	var opts options
+	rootCmd := &cobra.Command{
+		Use:   "programs",
+		Short: "query and edit programs in database",
+	}
+	rootCmd.PersistentFlags().StringVarP(&opts.configFile, "config", "c", os.Getenv("ATR_CONFIG_FILE"), "path to config file (YAML)")
+	rootCmd.PersistentFlags().StringVarP(&opts.output, "output", "o", outputTable, "output format (table, json)")
+
+	rootCmd.AddCommand(listCommand(&opts))
+	rootCmd.AddCommand(showCommand(&opts))
+	rootCmd.AddCommand(logsCommand(&opts))
+	rootCmd.AddCommand(calendarCommand(&opts))
+	rootCmd.AddCommand(xmltvCommand(&opts))
+	rootCmd.AddCommand(setStatusCommand(&opts))
+	rootCmd.AddCommand(changeCommand(&opts, "skip <uuid>", "exclude program from recording", func(ctx context.Context, p programsUsecase, uuid string) (program.Program, error) {
+		return p.Skip(ctx, uuid)
+	}))
+	rootCmd.AddCommand(changeCommand(&opts, "requeue <uuid>", "schedule program to be recorded again", func(ctx context.Context, p programsUsecase, uuid string) (program.Program, error) {
+		return p.Requeue(ctx, uuid, time.Now().In(timeutil.LocationJST()))
+	}))
+	rootCmd.AddCommand(changeCommand(&opts, "delete <uuid>", "mark program as deleted so that it is hidden and never scheduled again (archived file is kept)", func(ctx context.Context, p programsUsecase, uuid string) (program.Program, error) {
+		return p.Delete(ctx, uuid)
+	}))
+	rootCmd.AddCommand(changeCommand(&opts, "protect <uuid>", "protect program from retention", func(ctx context.Context, p programsUsecase, uuid string) (program.Program, error) {
+		return p.SetProtected(ctx, uuid, true)
+	}))
+	rootCmd.AddCommand(changeCommand(&opts, "unprotect <uuid>", "allow retention to purge program", func(ctx context.Context, p programsUsecase, uuid string) (program.Program, error) {
+		return p.SetProtected(ctx, uuid, false)
+	}))
+	return rootCmd
+}
+
+type programsUsecase interface {
+	List(ctx context.Context, filter program.Filter) ([]program.Program, error)
+	Show(ctx context.Context, uuid string) (program.Program, error)
+	RecordingLogs(ctx context.Context, uuid string) ([]recorder.RecordingLog, error)
+	SetStatus(ctx context.Context, uuid string, status program.Status) (program.Program, error)
+	Skip(ctx context.Context, uuid string) (program.Program, error)
+	Requeue(ctx context.Context, uuid string, now time.Time) (program.Program, error)
+	Delete(ctx context.Context, uuid string) (program.Program, error)
+	SetProtected(ctx context.Context, uuid string, protected bool) (program.Program, error)
+}
+
+// 設定ファイルの database_driver のデータベースを開く
+// 呼び出し元で db を Close すること
+func open(opts *options) (programsUsecase, *sqlx.DB, error) {
+	if opts.output != outputTable && opts.output != outputJSON {
+		return nil, nil, errors.Wrapf(errutil.ErrConfig, "unknown output format %q (table, json)", opts.output)
+	}
+
+	c, err := config.Load(opts.configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	programPersistence, db, err := setup.OpenProgramPersistence(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	return usecase.NewPrograms(programPersistence), db, nil
+}
+
+func listCommand(opts *options) *cobra.Command {
+	var (
+		station    string
+		status     string
+		streamType string
+		title      string
+		from       string
+		to         string
+		limit      int
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list programs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := buildFilter(station, status, streamType, title, from, to, limit)
+			if err != nil {
+				return err
+			}
+
+			p, db, err := open(opts)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			pgrams, err := p.List(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+			return writePrograms(cmd.OutOrStdout(), opts.output, pgrams)
+		},
+	}
+	cmd.Flags().StringVar(&station, "station", "", "filter by station (agqr, onsen)")
+	cmd.Flags().StringVar(&status, "status", "", "filter by status (scheduled, recording, done, failed, purged, skipped, deleted)")
+	cmd.Flags().StringVar(&streamType, "stream-type", "", "filter by stream type (broadcast, ondemand)")
+	cmd.Flags().StringVar(&title, "title", "", "filter by title (substring)")
+	cmd.Flags().StringVar(&from, "from", "", "filter by start date, inclusive (2006-01-02, JST)")
+	cmd.Flags().StringVar(&to, "to", "", "filter by start date, inclusive (2006-01-02, JST)")
+	cmd.Flags().IntVar(&limit, "limit", 0, "max number of programs (0 = unlimited)")
+	return cmd
+}
+
+func buildFilter(station string, status string, streamType string, title string, from string, to string, limit int) (program.Filter, error) {
+	filter := program.Filter{
+		Station:    program.Station(station),
+		Status:     program.Status(status),
+		StreamType: program.StreamType(streamType),
+		Title:      title,
+		Limit:      limit,
+	}
+	if station != "" && !filter.Station.Valid() {
+		return program.Filter{}, errors.Wrapf(errutil.ErrConfig, "unknown station %q", station)
+	}
+	if status != "" && !filter.Status.Valid() {
+		return program.Filter{}, errors.Wrapf(errutil.ErrInvalidStatus, "unknown status %q", status)
+	}
+	if streamType != "" && !filter.StreamType.Valid() {
+		return program.Filter{}, errors.Wrapf(errutil.ErrConfig, "unknown stream type %q", streamType)
+	}
+
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, timeutil.LocationJST())
+		if err != nil {
+			return program.Filter{}, errors.Wrap(errutil.ErrTimeParse, err.Error())
+		}
+		filter.StartFrom = t
+	}
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, timeutil.LocationJST())
+		if err != nil {
+			return program.Filter{}, errors.Wrap(errutil.ErrTimeParse, err.Error())
+		}
+		// その日の終わりまで含める
+		filter.StartTo = t.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
+
+func showCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <uuid>",
+		Short: "show program",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, db, err := open(opts)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			pgram, err := p.Show(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return writeProgram(cmd.OutOrStdout(), opts.output, pgram)
+		},
+	}
+}
+
+func logsCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logs <uuid>",
+		Short: "list recording log files of program",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, db, err := open(opts)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			recLogs, err := p.RecordingLogs(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return writeRecordingLogs(cmd.OutOrStdout(), opts.output, recLogs)
+		},
+	}
+}
+
+// --output に関わらず iCalendar を書き出す
+func calendarCommand(opts *options) *cobra.Command {
+	var station string
+	cmd := &cobra.Command{
+		Use:   "calendar",
+		Short: "export scheduled recordings as iCalendar (.ics)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if station != "" && !program.Station(station).Valid() {
+				return errors.Wrapf(errutil.ErrConfig, "unknown station %q", station)
+			}
+
+			c, err := config.Load(opts.configFile)
+			if err != nil {
+				return err
+			}
+			programPersistence, db, err := setup.OpenProgramPersistence(c)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			ucCalendar := usecase.NewCalendar(programPersistence, clock.New(), time.Duration(c.Calendar.PastDays)*24*time.Hour)
+			cal, err := ucCalendar.Calendar(cmd.Context(), program.Station(station))
+			if err != nil {
+				return err
+			}
+			return ical.Encode(cmd.OutOrStdout(), cal)
+		},
+	}
+	cmd.Flags().StringVar(&station, "station", "", "only programs of station (agqr, onsen)")
+	return cmd
+}
+
+// --output に関わらず XMLTV を書き出す
+func xmltvCommand(opts *options) *cobra.Command {
+	var station string
+	cmd := &cobra.Command{
+		Use:   "xmltv",
+		Short: "export program guide as XMLTV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if station != "" && !program.Station(station).Valid() {
+				return errors.Wrapf(errutil.ErrConfig, "unknown station %q", station)
+			}
+
+			c, err := config.Load(opts.configFile)
+			if err != nil {
+				return err
+			}
+			programPersistence, db, err := setup.OpenProgramPersistence(c)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			ucGuide := usecase.NewGuide(programPersistence, clock.New(), time.Duration(c.XMLTV.PastDays)*24*time.Hour)
+			guide, err := ucGuide.Guide(cmd.Context(), program.Station(station))
+			if err != nil {
+				return err
+			}
+			return xmltv.Encode(cmd.OutOrStdout(), guide)
+		},
+	}
+	cmd.Flags().StringVar(&station, "station", "", "only programs of station (agqr, onsen)")
+	return cmd
+}
+
+func setStatusCommand(opts *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-status <uuid> <status>",
+		Short: "force program status (scheduled, recording, done, failed, purged, skipped, deleted)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, db, err := open(opts)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			pgram, err := p.SetStatus(cmd.Context(), args[0], program.Status(args[1]))
+			if err != nil {
+				return err
+			}
+			return writeProgram(cmd.OutOrStdout(), opts.output, pgram)
+		},
+	}
+}
+
+// uuid を 1 つとって番組を変更するだけのサブコマンド
+func changeCommand(opts *options, use string, short string, change func(ctx context.Context, p programsUsecase, uuid string) (program.Program, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, db, err := open(opts)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			pgram, err := change(cmd.Context(), p, args[0])
+			if err != nil {
+				return err
+			}
+			return writeProgram(cmd.OutOrStdout(), opts.output, pgram)
+		},
+	}
+}
@@ -0,0 +1,201 @@
+package rec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/cmd/anrd/internal/setup"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/config"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/logutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	"github.com/sobadon/anrd/usecase"
+	"github.com/spf13/cobra"
+)
+
+// 進捗を表示する間隔
+const progressInterval = 10 * time.Second
+
+type options struct {
+	configFile string
+
+	station  string
+	title    string
+	episode  string
+	start    string
+	duration time.Duration
+	margin   time.Duration
+}
+
+func Command() *cobra.Command {
+	var opts options
+	cmd := &cobra.Command{
+		Use:   "rec [uuid]",
+		Short: "rec one program right now, outside the scheduler",
+		Long: `rec one program right now, outside the scheduler.
+
+  # program in database
+  anrd rec <uuid>
+
+  # ondemand program in guide (latest episode by default)
+  anrd rec --station onsen --title <title> [--episode <episode>]
+
+  # broadcast from start (now by default)
+  anrd rec --station agqr --duration 30m [--start 2006-01-02T15:04]`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd, opts, args)
+		},
+	}
+	cmd.Flags().StringVarP(&opts.configFile, "config", "c", os.Getenv("ATR_CONFIG_FILE"), "path to config file (YAML)")
+	cmd.Flags().StringVar(&opts.station, "station", "", "station (agqr, onsen)")
+	cmd.Flags().StringVar(&opts.title, "title", "", "ondemand: title to search in guide (substring), broadcast: title to save (default \"manual\")")
+	cmd.Flags().StringVar(&opts.episode, "episode", "", "ondemand: episode to search in guide (substring)")
+	cmd.Flags().StringVar(&opts.start, "start", "", "broadcast: start time (2006-01-02T15:04, JST), now if empty")
+	cmd.Flags().DurationVar(&opts.duration, "duration", 0, "broadcast: duration to rec")
+	cmd.Flags().DurationVar(&opts.margin, "margin", -1, "margin before and after program (default recorder.margin)")
+	return cmd
+}
+
+func run(cmd *cobra.Command, opts options, args []string) error {
+	c, err := config.Load(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	programPersistence, db, err := setup.OpenProgramPersistence(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	clk := clock.New()
+	stationOnsen, stationAgqr, err := setup.NewStations(c, clk)
+	if err != nil {
+		return err
+	}
+	objectStorage, err := setup.NewObjectStorage(c)
+	if err != nil {
+		return err
+	}
+	// 通知はしない
+	ucRecorder := usecase.NewRecorder(programPersistence, stationOnsen, stationAgqr, objectStorage, nil, clk)
+
+	recorderConfig := setup.RecorderConfig(c)
+	if opts.margin >= 0 {
+		recorderConfig.Margin = opts.margin
+	}
+
+	ctx := logutil.NewLogger().With().Str("job", "rec_manual").Logger().WithContext(cmd.Context())
+	now := clk.Now().In(timeutil.LocationJST())
+
+	var pgram program.Program
+	switch {
+	case len(args) == 1:
+		pgram, err = programPersistence.LoadByUUID(ctx, args[0])
+	case opts.duration > 0:
+		pgram, err = buildBroadcast(opts, now)
+	default:
+		if opts.title == "" {
+			return errors.Wrap(errutil.ErrConfig, "uuid, --title (ondemand) or --duration (broadcast) is required")
+		}
+		pgram, err = ucRecorder.FindOndemand(ctx, program.Station(opts.station), opts.title, opts.episode)
+	}
+	if err != nil {
+		return err
+	}
+
+	out := cmd.ErrOrStderr()
+	fmt.Fprintf(out, "rec %s %s %s (%s, start = %s)\n", pgram.Station, pgram.Title, pgram.Episode, pgram.StreamType, pgram.Start.Format("2006-01-02 15:04"))
+
+	var station repository.Station
+	switch pgram.Station {
+	case program.StationOnsen:
+		station = stationOnsen
+	case program.StationAgqr:
+		station = stationAgqr
+	}
+	if station != nil {
+		progressCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go showProgress(progressCtx, out, station.ArchiveFilePath(recorderConfig, pgram))
+	}
+
+	result, err := ucRecorder.RecManual(ctx, recorderConfig, pgram)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", result.UUID, result.FilePath)
+	return nil
+}
+
+func buildBroadcast(opts options, now time.Time) (program.Program, error) {
+	station := program.Station(opts.station)
+	if station != program.StationAgqr {
+		return program.Program{}, errors.Wrapf(errutil.ErrConfig, "station %q does not support broadcast", opts.station)
+	}
+
+	start := now
+	if opts.start != "" {
+		var err error
+		start, err = time.ParseInLocation("2006-01-02T15:04", opts.start, timeutil.LocationJST())
+		if err != nil {
+			return program.Program{}, errors.Wrap(errutil.ErrTimeParse, err.Error())
+		}
+	}
+
+	title := opts.title
+	if title == "" {
+		title = "manual"
+	}
+	return program.NewProgramManualBroadcast(station, title, start, start.Add(opts.duration)), nil
+}
+
+// 録画中のファイルサイズを定期的に表示する
+func showProgress(ctx context.Context, w io.Writer, filePath string) {
+	start := time.Now()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			elapsed := time.Since(start).Truncate(time.Second)
+			size, ok := recordedSize(filePath)
+			if !ok {
+				fmt.Fprintf(w, "waiting ... (elapsed = %s)\n", elapsed)
+				continue
+			}
+			fmt.Fprintf(w, "recording ... (elapsed = %s, size = %.1f MiB)\n", elapsed, float64(size)/1024/1024)
+		}
+	}
+}
+
+// filePath と、録画を再開したときに分かれる部分ファイル（file.part0.ts など）の合計サイズ
+// どれも存在しなければ false
+func recordedSize(filePath string) (int64, bool) {
+	ext := filepath.Ext(filePath)
+	parts, _ := filepath.Glob(strings.TrimSuffix(filePath, ext) + ".part*" + ext)
+
+	var size int64
+	found := false
+	for _, file := range append(parts, filePath) {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		size += info.Size()
+		found = true
+	}
+	return size, found
+}
@@ -3,7 +3,11 @@ package main
 import (
 	"log"
 
+	"github.com/sobadon/anrd/cmd/anrd/db"
+	"github.com/sobadon/anrd/cmd/anrd/programs"
+	"github.com/sobadon/anrd/cmd/anrd/rec"
 	"github.com/sobadon/anrd/cmd/anrd/run"
+	"github.com/sobadon/anrd/cmd/anrd/transfer"
 	"github.com/sobadon/anrd/cmd/anrd/version"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +28,11 @@ func execute() {
 
 	rootCmd.Run = runRoot
 	rootCmd.AddCommand(run.Command())
+	rootCmd.AddCommand(programs.Command())
+	rootCmd.AddCommand(rec.Command())
+	rootCmd.AddCommand(db.Command())
+	rootCmd.AddCommand(transfer.ExportCommand())
+	rootCmd.AddCommand(transfer.ImportCommand())
 	rootCmd.AddCommand(version.Command())
 
 	rootCmd.Flags().BoolVarP(&flagVersion, "version", "V", false, "Print the version number")
@@ -0,0 +1,76 @@
+package run
+
+import (
+	"context"
+	"errors"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sobadon/anrd/cmd/anrd/internal/setup"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	appconfig "github.com/sobadon/anrd/internal/config"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+// dry-run ではデータベースを変更しないよう、登録済みの番組をメモリ上にコピーして使う
+// マイグレーションもしないので、スキーマが古ければエラー
+func openDryRunPersistence(ctx context.Context, c appconfig.Config) (repository.ProgramPersistence, error) {
+	db, err := setup.OpenDB(c)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	src := setup.NewProgramPersistence(c, db)
+	err = src.CheckSchema(ctx)
+	if err != nil {
+		return nil, pkgerrors.Wrapf(errutil.ErrDatabaseQuery, "run `anrd db migrate` before dry-run: %v", err)
+	}
+
+	dst := memory.New()
+	err = copyPrograms(ctx, dst, src)
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// src のすべての番組を dst に登録する
+func copyPrograms(ctx context.Context, dst repository.ProgramPersistence, src repository.ProgramPersistence) error {
+	pgrams, err := src.Load(ctx, program.Filter{})
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, pgram := range pgrams {
+		// Save は録画後に設定される項目を登録しない
+		err := dst.Save(ctx, pgram)
+		if err != nil {
+			return err
+		}
+		if pgram.FilePath != "" {
+			err = dst.ChangeFilePath(ctx, pgram, pgram.FilePath)
+			if err != nil {
+				return err
+			}
+		}
+		if pgram.ObjectKey != "" {
+			err = dst.ChangeObjectKey(ctx, pgram, pgram.ObjectKey)
+			if err != nil {
+				return err
+			}
+		}
+		if pgram.Protected {
+			err = dst.ChangeProtected(ctx, pgram, true)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	log.Info().Msgf("dry-run: copied %d programs from database into memory", len(pgrams))
+	return nil
+}
@@ -0,0 +1,89 @@
+package run
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/model/retention"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/infrastructures/webhook"
+	"github.com/sobadon/anrd/internal/config"
+)
+
+// SIGHUP で差し替えられる設定
+// retention ルールと webhook の通知先のみ
+// それ以外の項目は再起動するまで反映されない
+type reloadable struct {
+	mu             sync.RWMutex
+	config         config.Config
+	retentionRules []retention.Rule
+	// 通知先が設定されていなければ nil
+	notifier repository.Notifier
+}
+
+func newReloadable(c config.Config) *reloadable {
+	r := &reloadable{}
+	r.apply(c)
+	return r
+}
+
+func (r *reloadable) apply(c config.Config) {
+	var notifier repository.Notifier
+	if len(c.Webhook.Targets) != 0 {
+		notifier = webhook.New(buildWebhookTargets(c.Webhook.Targets), c.Webhook.RetryMaxCount)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = c
+	r.retentionRules = buildRetentionRules(c.Retention)
+	r.notifier = notifier
+}
+
+// 新しい設定を反映する
+// 反映されない項目が変更されていれば、その名前を返す
+func (r *reloadable) reload(c config.Config) []string {
+	r.mu.RLock()
+	old := r.config
+	r.mu.RUnlock()
+
+	r.apply(c)
+	return restartRequiredChanges(old, c)
+}
+
+func (r *reloadable) RetentionRules() []retention.Rule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.retentionRules
+}
+
+// repository.Notifier
+// 呼び出されたときの通知先に送る
+func (r *reloadable) Notify(ctx context.Context, n notification.Notification) error {
+	r.mu.RLock()
+	notifier := r.notifier
+	r.mu.RUnlock()
+
+	if notifier == nil {
+		return nil
+	}
+	return notifier.Notify(ctx, n)
+}
+
+// 再起動しなければ反映されない項目のうち、変更されたもの（YAML のキー名）
+func restartRequiredChanges(old config.Config, new config.Config) []string {
+	// 反映される項目は比較対象から外す
+	old.Retention, new.Retention = nil, nil
+	old.Webhook, new.Webhook = config.Webhook{}, config.Webhook{}
+
+	var changed []string
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < oldValue.NumField(); i++ {
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, oldValue.Type().Field(i).Tag.Get("yaml"))
+		}
+	}
+	return changed
+}
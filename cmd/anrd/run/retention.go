@@ -0,0 +1,23 @@
+package run
+
+import (
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/retention"
+	"github.com/sobadon/anrd/internal/config"
+)
+
+func buildRetentionRules(rulesConfig []config.RetentionRule) []retention.Rule {
+	var rules []retention.Rule
+	for _, ruleConfig := range rulesConfig {
+		rules = append(rules, retention.Rule{
+			Station:      program.Station(ruleConfig.Station),
+			Title:        ruleConfig.Title,
+			KeepLast:     ruleConfig.KeepLast,
+			MaxAge:       time.Duration(ruleConfig.MaxAgeDays) * 24 * time.Hour,
+			MaxTotalSize: ruleConfig.MaxTotalSizeMiB * 1024 * 1024,
+		})
+	}
+	return rules
+}
@@ -2,20 +2,28 @@ package run
 
 import (
 	"context"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/caarlos0/env/v6"
 	"github.com/go-co-op/gocron"
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	zlog "github.com/rs/zerolog/log"
-	"github.com/sobadon/anrd/domain/model/recorder"
-	"github.com/sobadon/anrd/infrastructures/agqr"
-	"github.com/sobadon/anrd/infrastructures/onsen"
-	"github.com/sobadon/anrd/infrastructures/sqlite"
+	"github.com/sobadon/anrd/cmd/anrd/internal/setup"
+	"github.com/sobadon/anrd/domain/model/feed"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/handler"
+	"github.com/sobadon/anrd/infrastructures/dryrun"
+	"github.com/sobadon/anrd/internal/clock"
+	appconfig "github.com/sobadon/anrd/internal/config"
 	"github.com/sobadon/anrd/internal/errutil"
 	"github.com/sobadon/anrd/internal/logutil"
+	"github.com/sobadon/anrd/internal/metrics"
 	"github.com/sobadon/anrd/internal/timeutil"
 	"github.com/sobadon/anrd/usecase"
 	"github.com/spf13/cobra"
@@ -25,48 +33,95 @@ var (
 	log = logutil.NewLogger()
 )
 
+type options struct {
+	configFile string
+
+	dryRun   bool
+	planFile string
+}
+
 func Command() *cobra.Command {
+	var opts options
 	rootCmd := &cobra.Command{
 		Use:   "run",
 		Short: "run components",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return run()
+			return run(opts)
 		},
 	}
+	rootCmd.Flags().StringVarP(&opts.configFile, "config", "c", os.Getenv("ATR_CONFIG_FILE"), "path to config file (YAML)")
+	rootCmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "fetch guides and run scheduler without recording, deleting files or writing to database")
+	rootCmd.Flags().StringVar(&opts.planFile, "plan-file", "", "dry-run: write planned recordings to this file (JSON lines)")
 	return rootCmd
 }
 
-func run() error {
+func run(opts options) error {
 	log.Info().Msg("start")
 
-	var config config
-	err := env.Parse(&config, env.Options{
-		Prefix: "ATR_",
-		OnSet: func(tag string, value interface{}, isDefault bool) {
-			log.Info().Msgf("Set %s to %v (default? %v)\n", tag, value, isDefault)
-		},
-	})
-	if err != nil {
-		return err
+	if opts.planFile != "" && !opts.dryRun {
+		return errors.Wrap(errutil.ErrConfig, "--plan-file requires --dry-run")
 	}
 
-	db, err := sqlite.NewDB(config.SqlitePath)
+	config, err := appconfig.Load(opts.configFile)
 	if err != nil {
 		return err
 	}
+	settings := newReloadable(config)
+
+	ctx := context.Background()
+
+	var infraProgramPersistence repository.ProgramPersistence
+	if opts.dryRun {
+		log.Warn().Msg("dry-run: nothing is recorded, deleted, uploaded or written to database")
+		infraProgramPersistence, err = openDryRunPersistence(ctx, config)
+		if err != nil {
+			return err
+		}
+	} else {
+		var db *sqlx.DB
+		infraProgramPersistence, db, err = setup.OpenProgramPersistence(config)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+	}
+	log.Info().Msg("setup done")
 
-	err = sqlite.Setup(db)
+	// ジョブに渡す現在日時も含め、時刻はすべて clk から得る
+	clk := clock.New()
+	stationOnsen, stationAgqr, err := setup.NewStations(config, clk)
 	if err != nil {
 		return err
 	}
-	log.Info().Msg("setup done")
 
-	infraProgramPersistence := sqlite.New(db)
-	stationOnsen := onsen.New()
-	stationAgqr := agqr.New()
-	ucRecorder := usecase.NewRecorder(infraProgramPersistence, stationOnsen, stationAgqr)
+	var (
+		objectStorage repository.ObjectStorage
+		// 通知先は SIGHUP で差し替えられる
+		notifier repository.Notifier = settings
+	)
+	if opts.dryRun {
+		var report io.Writer
+		if opts.planFile != "" {
+			f, err := os.Create(opts.planFile)
+			if err != nil {
+				return errors.Wrap(errutil.ErrConfig, err.Error())
+			}
+			defer f.Close()
+			report = f
+			log.Info().Msgf("dry-run: write plan to %s", opts.planFile)
+		}
+		stationOnsen = dryrun.New(stationOnsen, report)
+		stationAgqr = dryrun.New(stationAgqr, report)
+		// アップロードも通知もしない
+		notifier = nil
+	} else {
+		objectStorage, err = setup.NewObjectStorage(config)
+		if err != nil {
+			return err
+		}
+	}
+	ucRecorder := usecase.NewRecorder(infraProgramPersistence, stationOnsen, stationAgqr, objectStorage, notifier, clk)
 
-	ctx := context.Background()
 	scheduler := gocron.NewScheduler(timeutil.LocationJST())
 
 	jobUpdate := func(ctx context.Context, job gocron.Job) {
@@ -74,35 +129,34 @@ func run() error {
 			Int("job_count", job.RunCount()).
 			Str("job", "update").
 			Logger().WithContext(ctx)
+		defer observeJobDuration("update", time.Now())
 		zlog.Ctx(ctx).Info().Msg("job start")
 		err := ucRecorder.UpdateProgram(ctx)
 		if err != nil {
 			zlog.Ctx(ctx).Error().Msgf("%+v", err)
 		}
 	}
-	_, err = scheduler.Every(29*time.Minute).DoWithJobDetails(jobUpdate, ctx)
+	_, err = scheduler.Every(config.Jobs.UpdateInterval).DoWithJobDetails(jobUpdate, ctx)
 	if err != nil {
 		return errors.Wrap(errutil.ErrScheduler, err.Error())
 	}
 
-	recorderConfig := recorder.Config{
-		ArchiveDir:   config.ArchiveDir,
-		PrepareAfter: config.PrepareAfter,
-		Margin:       config.Margin,
-	}
+	recorderConfig := setup.RecorderConfig(config)
+	recorderConfig.DryRun = opts.dryRun
 
 	jobRecOndemand := func(ctx context.Context, job gocron.Job) {
 		ctx = logutil.NewLogger().With().
 			Int("job_count", job.RunCount()).
 			Str("job", "rec_ondemand").
 			Logger().WithContext(ctx)
+		defer observeJobDuration("rec_ondemand", time.Now())
 
-		err = ucRecorder.RecOndemandPrepare(ctx, recorderConfig, time.Now().In(timeutil.LocationJST()))
+		err = ucRecorder.RecOndemandPrepare(ctx, recorderConfig, clk.Now().In(timeutil.LocationJST()))
 		if err != nil {
 			zlog.Ctx(ctx).Error().Msgf("%+v", err)
 		}
 	}
-	_, err = scheduler.Every(5*time.Minute).DoWithJobDetails(jobRecOndemand, ctx)
+	_, err = scheduler.Every(config.Jobs.RecOndemandInterval).DoWithJobDetails(jobRecOndemand, ctx)
 	if err != nil {
 		return errors.Wrap(errutil.ErrScheduler, err.Error())
 	}
@@ -112,25 +166,119 @@ func run() error {
 			Int("job_count", job.RunCount()).
 			Str("job", "rec_broadcast").
 			Logger().WithContext(ctx)
+		defer observeJobDuration("rec_broadcast", time.Now())
+
+		err = ucRecorder.RecBroadcastPrepare(ctx, recorderConfig, clk.Now().In(timeutil.LocationJST()))
+		if err != nil {
+			zlog.Ctx(ctx).Error().Msgf("%+v", err)
+		}
+	}
+	_, err = scheduler.Every(config.Jobs.RecBroadcastInterval).DoWithJobDetails(jobRecBroadcast, ctx)
+	if err != nil {
+		return errors.Wrap(errutil.ErrScheduler, err.Error())
+	}
+
+	// ルールは SIGHUP で差し替えられるので、空であっても登録しておく
+	ucRetention := usecase.NewRetention(infraProgramPersistence)
+	if opts.dryRun {
+		ucRetention = usecase.NewRetentionDryRun(infraProgramPersistence)
+	}
+	jobRetention := func(ctx context.Context, job gocron.Job) {
+		ctx = logutil.NewLogger().With().
+			Int("job_count", job.RunCount()).
+			Str("job", "retention").
+			Logger().WithContext(ctx)
+		defer observeJobDuration("retention", time.Now())
 
-		err = ucRecorder.RecBroadcastPrepare(ctx, recorderConfig, time.Now().In(timeutil.LocationJST()))
+		now := clk.Now().In(timeutil.LocationJST())
+		err := ucRetention.Purge(ctx, settings.RetentionRules(), now)
+		if err != nil {
+			zlog.Ctx(ctx).Error().Msgf("%+v", err)
+		}
+		err = ucRetention.PurgeRecordingLogs(ctx, recorderConfig.RecordingLogRetention, now)
 		if err != nil {
 			zlog.Ctx(ctx).Error().Msgf("%+v", err)
 		}
 	}
-	_, err = scheduler.Every(30*time.Second).DoWithJobDetails(jobRecBroadcast, ctx)
+	_, err = scheduler.Every(config.Jobs.RetentionInterval).DoWithJobDetails(jobRetention, ctx)
 	if err != nil {
 		return errors.Wrap(errutil.ErrScheduler, err.Error())
 	}
 
 	scheduler.StartAsync()
-	scheduler.RunAllWithDelay(10 * time.Second)
+	scheduler.RunAllWithDelay(config.Jobs.StartDelay)
+
+	var server *http.Server
+	if opts.dryRun && config.HTTPAddr != "" {
+		// 動いている anrd とポートが衝突しないように
+		log.Info().Msg("dry-run: http server is disabled")
+	}
+	if config.HTTPAddr != "" && !opts.dryRun {
+		ucFeed := usecase.NewFeed(infraProgramPersistence, feed.Config{
+			ArchiveDir:    config.ArchiveDir,
+			BaseURL:       config.Feed.BaseURL,
+			ObjectBaseURL: config.Feed.ObjectBaseURL,
+		})
+		ucCalendar := usecase.NewCalendar(infraProgramPersistence, clk, time.Duration(config.Calendar.PastDays)*24*time.Hour)
+		ucGuide := usecase.NewGuide(infraProgramPersistence, clk, time.Duration(config.XMLTV.PastDays)*24*time.Hour)
+		ucHealth := usecase.NewHealth(infraProgramPersistence, ucRecorder, scheduler, config.Health.ReadyUpdateThreshold)
+		server = &http.Server{
+			Addr:    config.HTTPAddr,
+			Handler: handler.New(ucFeed, ucCalendar, ucGuide, ucHealth, ucRecorder, config.HTTPToken),
+		}
+		go func() {
+			log.Info().Msgf("http server listen on %s", config.HTTPAddr)
+			err := server.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				log.Error().Msgf("%+v", errors.Wrap(errutil.ErrInternal, err.Error()))
+			}
+		}()
+	}
 
-	quit := make(chan os.Signal)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
-	<-quit
+loop:
+	for {
+		select {
+		case <-hup:
+			reloadConfig(opts.configFile, settings)
+		case <-quit:
+			break loop
+		}
+	}
 	log.Info().Msg("Interrupt")
-	defer db.Close()
+
+	if server != nil {
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		err = server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Error().Msgf("%+v", err)
+		}
+	}
 
 	return nil
 }
+
+// 設定を読み直し、retention ルールと通知先を差し替える
+// 読み込みや検証に失敗すれば、それまでの設定のまま動き続ける
+func reloadConfig(configFile string, settings *reloadable) {
+	log.Info().Msgf("reload config (file = %s)", configFile)
+	config, err := appconfig.Load(configFile)
+	if err != nil {
+		log.Error().Msgf("failed to reload config, keep current config: %+v", err)
+		return
+	}
+
+	changed := settings.reload(config)
+	if len(changed) != 0 {
+		log.Warn().Msgf("these settings are changed but require restart: %s", strings.Join(changed, ", "))
+	}
+	log.Info().Msg("successfully reload config (retention, webhook)")
+}
+
+func observeJobDuration(job string, start time.Time) {
+	metrics.JobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+}
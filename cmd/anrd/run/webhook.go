@@ -0,0 +1,25 @@
+package run
+
+import (
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/infrastructures/webhook"
+	"github.com/sobadon/anrd/internal/config"
+)
+
+func buildWebhookTargets(targetsConfig []config.WebhookTarget) []webhook.Target {
+	var targets []webhook.Target
+	for _, targetConfig := range targetsConfig {
+		target := webhook.Target{
+			URL:    targetConfig.URL,
+			Format: webhook.Format(targetConfig.Format),
+		}
+		if target.Format == "" {
+			target.Format = webhook.FormatGeneric
+		}
+		for _, event := range targetConfig.Events {
+			target.Events = append(target.Events, notification.Event(event))
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
@@ -0,0 +1,107 @@
+// 番組データベースの書き出し（anrd export）と読み込み（anrd import）
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/cmd/anrd/internal/setup"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/config"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/programio"
+	"github.com/sobadon/anrd/usecase"
+	"github.com/spf13/cobra"
+)
+
+func ExportCommand() *cobra.Command {
+	var (
+		configFile string
+		format     string
+		station    string
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "dump programs with their attempts as JSON Lines or CSV to stdout",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !programio.Format(format).Valid() {
+				return errors.Wrapf(errutil.ErrConfig, "unknown format %q (jsonl, csv)", format)
+			}
+			if station != "" && !program.Station(station).Valid() {
+				return errors.Wrapf(errutil.ErrConfig, "unknown station %q", station)
+			}
+
+			c, err := config.Load(configFile)
+			if err != nil {
+				return err
+			}
+			programPersistence, db, err := setup.OpenProgramPersistence(c)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			records, err := usecase.NewTransfer(programPersistence).Export(cmd.Context(), program.Filter{Station: program.Station(station)})
+			if err != nil {
+				return err
+			}
+			return programio.Encode(cmd.OutOrStdout(), programio.Format(format), records)
+		},
+	}
+	cmd.Flags().StringVarP(&configFile, "config", "c", os.Getenv("ATR_CONFIG_FILE"), "path to config file (YAML)")
+	cmd.Flags().StringVar(&format, "format", string(programio.FormatJSONL), "output format (jsonl, csv)")
+	cmd.Flags().StringVar(&station, "station", "", "only programs of station (agqr, onsen)")
+	return cmd
+}
+
+func ImportCommand() *cobra.Command {
+	var (
+		configFile string
+		format     string
+	)
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "load programs from JSON Lines or CSV (- for stdin), updating programs with the same station and id",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !programio.Format(format).Valid() {
+				return errors.Wrapf(errutil.ErrConfig, "unknown format %q (jsonl, csv)", format)
+			}
+
+			var r io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return errors.Wrap(errutil.ErrInvalidImport, err.Error())
+				}
+				defer f.Close()
+				r = f
+			}
+			// データベースを開く前にすべて検証する
+			records, err := programio.Decode(r, programio.Format(format))
+			if err != nil {
+				return err
+			}
+
+			c, err := config.Load(configFile)
+			if err != nil {
+				return err
+			}
+			programPersistence, db, err := setup.OpenProgramPersistence(c)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			created, updated, err := usecase.NewTransfer(programPersistence).Import(cmd.Context(), records)
+			fmt.Fprintf(cmd.OutOrStdout(), "created: %d, updated: %d\n", created, updated)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&configFile, "config", "c", os.Getenv("ATR_CONFIG_FILE"), "path to config file (YAML)")
+	cmd.Flags().StringVar(&format, "format", string(programio.FormatJSONL), "input format (jsonl, csv)")
+	return cmd
+}
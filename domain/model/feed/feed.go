@@ -0,0 +1,15 @@
+package feed
+
+type Config struct {
+	// 録画済みファイルの保存先ディレクトリ
+	ArchiveDir string
+
+	// フィードや enclosure の URL の起点
+	// 例: http://anrd.local:8080
+	BaseURL string
+
+	// ObjectKey をもつ番組の enclosure に使う URL の起点
+	// 例: https://minio.local/archive
+	// 空であれば ArchiveDir 以下のファイルを BaseURL から配信する
+	ObjectBaseURL string
+}
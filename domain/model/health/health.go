@@ -0,0 +1,38 @@
+package health
+
+type Status string
+
+const (
+	StatusOK   = Status("ok")
+	StatusFail = Status("fail")
+)
+
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	// 失敗した理由など
+	Message string `json:"message,omitempty"`
+}
+
+type Report struct {
+	// Checks がすべて ok であれば ok
+	Status Status  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+func NewReport(checks []Check) Report {
+	status := StatusOK
+	for _, check := range checks {
+		if check.Status != StatusOK {
+			status = StatusFail
+		}
+	}
+	return Report{
+		Status: status,
+		Checks: checks,
+	}
+}
+
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+)
+
+type Event string
+
+const (
+	EventRecStarted          = Event("rec_started")
+	EventRecSucceeded        = Event("rec_succeeded")
+	EventRecFailed           = Event("rec_failed")
+	EventUpdateProgramFailed = Event("update_program_failed")
+	EventDiskLow             = Event("disk_low")
+)
+
+func (e Event) String() string {
+	return string(e)
+}
+
+// 定義されているイベントであるか
+func (e Event) Valid() bool {
+	switch e {
+	case EventRecStarted, EventRecSucceeded, EventRecFailed, EventUpdateProgramFailed, EventDiskLow:
+		return true
+	}
+	return false
+}
+
+type Notification struct {
+	Event Event
+	Time  time.Time
+
+	// 番組に関係しないイベントでは nil
+	Program *program.Program
+
+	Message string
+}
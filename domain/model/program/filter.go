@@ -0,0 +1,21 @@
+package program
+
+import "time"
+
+// 番組を検索する条件
+// ゼロ値の項目は条件に含めない
+type Filter struct {
+	Station    Station
+	Status     Status
+	StreamType StreamType
+
+	// 部分一致
+	Title string
+
+	// StartFrom <= Start < StartTo
+	StartFrom time.Time
+	StartTo   time.Time
+
+	// 0 であれば制限しない
+	Limit int
+}
@@ -36,8 +36,28 @@ type Program struct {
 	// StreamType が ondemand のとき PlaylistURL に m3u8 の URL が存在する
 	PlaylistURL string
 
-	// すぐは必要ないならあとで
-	// Personality []string
+	// 番組のアートワーク
+	// 取得できなければ空文字
+	ImageURL string
+
+	// 録画済みファイルのパス
+	// 録画が完了するまでは空文字
+	FilePath string
+
+	// オブジェクトストレージにアップロードした際の object key
+	// アップロードしていなければ空文字
+	ObjectKey string
+
+	// true であれば retention などによって録画済みファイルを削除しない
+	Protected bool
+
+	// 番組の説明
+	// 取得できなければ空文字
+	Description string
+
+	// パーソナリティ
+	// 取得できなければ空
+	Personalities []string
 }
 
 /*
@@ -73,3 +93,19 @@ func NewProgramOndemand(id int, station Station, title string, episode string, d
 		PlaylistURL: playlistURL,
 	}
 }
+
+// 番組表によらず手動で録画する broadcast な番組
+// 番組表の ID と衝突しないよう、開始日時から負の ID をつくる
+func NewProgramManualBroadcast(station Station, title string, start time.Time, end time.Time) Program {
+	return Program{
+		// panic 許容
+		UUID:       uuid.NewString(),
+		ID:         -int(start.Unix()),
+		Station:    station,
+		Title:      title,
+		Start:      start,
+		End:        end,
+		Status:     StatusScheduled,
+		StreamType: StreamTypeBroadcast,
+	}
+}
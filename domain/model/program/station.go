@@ -10,3 +10,24 @@ const (
 func (s Station) String() string {
 	return string(s)
 }
+
+// 定義されている station であるか
+func (s Station) Valid() bool {
+	switch s {
+	case StationOnsen, StationAgqr:
+		return true
+	}
+	return false
+}
+
+// 人が読むための station の名前
+// 定義されていなければ String()
+func (s Station) DisplayName() string {
+	switch s {
+	case StationOnsen:
+		return "インターネットラジオステーション＜音泉＞"
+	case StationAgqr:
+		return "超！A&G+"
+	}
+	return s.String()
+}
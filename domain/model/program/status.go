@@ -7,8 +7,27 @@ const (
 	StatusRecording = Status("recording")
 	StatusDone      = Status("done")
 	StatusFailed    = Status("failed")
+
+	// 録画済みファイルを retention によって削除した
+	StatusPurged = Status("purged")
+
+	// 手動で録画対象から外した
+	StatusSkipped = Status("skipped")
+
+	// 手動で削除した
+	// 番組表の更新で scheduled として登録しなおされないよう、行は残しておく
+	StatusDeleted = Status("deleted")
 )
 
 func (s Status) String() string {
 	return string(s)
 }
+
+// 定義されている status であるか
+func (s Status) Valid() bool {
+	switch s {
+	case StatusScheduled, StatusRecording, StatusDone, StatusFailed, StatusPurged, StatusSkipped, StatusDeleted:
+		return true
+	}
+	return false
+}
@@ -10,3 +10,12 @@ const (
 func (s StreamType) String() string {
 	return string(s)
 }
+
+// 定義されている stream type であるか
+func (s StreamType) Valid() bool {
+	switch s {
+	case StreamTypeBroadcast, StreamTypeOndemand:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package recorder
+
+import "time"
+
+// 録画 1 回分のログファイル
+// ffmpeg のコマンドと出力、anrd のログを記録する
+type RecordingLog struct {
+	ProgramUUID string
+	FilePath    string
+	// 録画を始めた日時
+	StartedAt time.Time
+}
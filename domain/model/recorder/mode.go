@@ -0,0 +1,19 @@
+package recorder
+
+// 番組をどのように録画するか
+type Mode string
+
+const (
+	// Station.Rec で録画し、失敗すればはじめから録画しなおす
+	ModeSingle = Mode("single")
+	// 続けて放送される番組を ContinuousStation.RecContinuous でまとめて録画する
+	ModeContinuous = Mode("continuous")
+	// RedundantStation.RecSource ですべての配信元から同時に録画する
+	ModeRedundant = Mode("redundant")
+	// ResumableStation.RecPart で録画し、失敗すれば残りの時間だけ録画しなおす
+	ModeResumable = Mode("resumable")
+)
+
+func (m Mode) String() string {
+	return string(m)
+}
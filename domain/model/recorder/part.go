@@ -0,0 +1,44 @@
+package recorder
+
+import "time"
+
+// 録画が途中で失敗し、再開した場合に分かれたファイルのひとつ
+type Part struct {
+	// 0 から始まる通し番号
+	Index int
+
+	// ffmpeg を開始・終了した日時
+	Start time.Time
+	End   time.Time
+}
+
+// 部分ファイルの間で録画できていない区間
+type Gap struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (g Gap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// parts（Index の昇順）の間の欠落を返す
+// 最後の部分ファイルが until より前に終わっていれば、そこから until までも欠落とする
+// until がゼロ値であれば含めない
+func Gaps(parts []Part, until time.Time) []Gap {
+	var gaps []Gap
+	for i := 1; i < len(parts); i++ {
+		gap := Gap{Start: parts[i-1].End, End: parts[i].Start}
+		if gap.Duration() <= 0 {
+			continue
+		}
+		gaps = append(gaps, gap)
+	}
+	if len(parts) != 0 && !until.IsZero() {
+		gap := Gap{Start: parts[len(parts)-1].End, End: until}
+		if gap.Duration() > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	return gaps
+}
@@ -0,0 +1,28 @@
+package recorder
+
+import (
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+)
+
+// ffmpeg の -progress で報告される録画の進み具合
+type Progress struct {
+	// 録画できた長さ
+	OutTime time.Duration
+	// kbit/s
+	// 不明であれば 0
+	Bitrate float64
+	// 出力ファイルの大きさ（byte）
+	TotalSize int64
+	// 報告された日時
+	UpdatedAt time.Time
+}
+
+// 録画中の番組
+type Recording struct {
+	Program   program.Program
+	StartedAt time.Time
+	// ffmpeg から報告されていなければゼロ値
+	Progress Progress
+}
@@ -12,4 +12,71 @@ type Config struct {
 	// 録画前後のマージン
 	// 録画全体時間 = マージン + 番組時間 + マージン
 	Margin time.Duration
+
+	// 録画に失敗した際のリトライ回数
+	// RetryMaxCount=3 であれば計 4 回試みる
+	RetryMaxCount int
+	// broadcast を途中から録画しなおすまでに待つ時間
+	// 失敗する度に倍にする
+	ResumeBackoff time.Duration
+	// 途中から録画しなおした broadcast の欠落の合計がこれを超えれば、結合したファイルは残して failed にする
+	// 0 であれば欠落があっても done にする
+	MaxGap time.Duration
+
+	// true であれば、続けて放送される broadcast な番組を 1 つの接続でまとめて録画し、番組毎に分割する
+	// Station が repository.ContinuousStation でなければ番組毎に録画する
+	ContinuousCapture bool
+	// まとめて録画する長さの上限（最初の番組の Start から最後の番組の End まで）
+	ContinuousCaptureMax time.Duration
+
+	// これに該当する broadcast な番組は、Station が repository.RedundantStation であればすべての配信元から同時に録画し、良い方を残す
+	RedundantRules []RedundantRule
+
+	// ondemand を一度に録画する件数
+	OndemandLimit int
+	// ondemand の録画開始をずらす間隔
+	OndemandInterval time.Duration
+
+	// 録画後に ArchiveDir の空き容量がこれ（byte）を下回る見込みであれば録画を開始しない
+	// 0 であれば空き容量を確認しない
+	MinFreeSpace uint64
+
+	// ffmpeg の出力ファイルがこの間大きくならなければ、止まったとみなして kill する
+	// 0 であれば確認しない
+	StallTimeout time.Duration
+	// broadcast は録画する長さにこれを加えた時間、ondemand は OndemandTimeout を過ぎても終わらなければ kill する
+	// 0 であれば制限しない
+	DeadlineGrace   time.Duration
+	OndemandTimeout time.Duration
+
+	// true であれば録画 1 回毎に ffmpeg のコマンドと出力、anrd のログをファイルに書き込む
+	RecordingLog bool
+	// 録画ログを書き込むディレクトリ
+	// 空であれば録画済みファイルと同じディレクトリ
+	RecordingLogDir string
+	// これより前に始めた録画のログを削除する
+	// 0 であれば削除しない
+	RecordingLogRetention time.Duration
+
+	// 録画中に空き容量を確認する間隔
+	DiskCheckInterval time.Duration
+
+	// オブジェクトストレージにアップロードする際の object key のテンプレート（text/template）
+	ObjectKeyTemplate string
+
+	// アップロード後にローカルのファイルを削除する
+	RemoveAfterUpload bool
+
+	// true であれば録画せず、録画するはずだったものを repository.PlanStation に記録させる
+	// status を done にせず、アップロードや通知もしない
+	DryRun bool
+}
+
+// 長さ duration の broadcast を録画する ffmpeg の制限時間
+// 0 であれば制限しない
+func (c Config) BroadcastTimeout(duration time.Duration) time.Duration {
+	if c.DeadlineGrace <= 0 {
+		return 0
+	}
+	return duration + c.DeadlineGrace
 }
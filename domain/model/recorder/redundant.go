@@ -0,0 +1,88 @@
+package recorder
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+)
+
+// 複数の配信元から同時に録画する番組の条件
+type RedundantRule struct {
+	// 空文字であればすべての station
+	Station program.Station
+
+	// 番組タイトルにこれを含むものを対象とする
+	// 空文字であればすべての番組
+	Title string
+}
+
+func (r RedundantRule) Match(pgram program.Program) bool {
+	if r.Station != "" && r.Station != pgram.Station {
+		return false
+	}
+	if r.Title != "" && !strings.Contains(pgram.Title, r.Title) {
+		return false
+	}
+	return true
+}
+
+// 録画済みファイルを調べた結果
+type Probe struct {
+	// 録画できた長さ
+	Duration time.Duration
+	// タイムスタンプが飛んでいる（欠落している）箇所の数
+	Discontinuities int
+}
+
+// 冗長録画での配信元毎の録画結果
+type Attempt struct {
+	ProgramUUID string
+	// 0 から始まる配信元の番号
+	Source int
+
+	// 残したものは ArchiveFilePath、残さなかったものは削除済みのパス
+	FilePath string
+	Probe    Probe
+	// 録画や調査に失敗していれば空でない
+	Error string
+
+	// 録画済みファイルとして残したもの
+	Chosen bool
+}
+
+// 録画の開始の遅れや HLS のセグメント単位の途切れを考え、この分までは短くても番組全体を録画できたとみなす
+const coverageTolerance = 30 * time.Second
+
+// 録画済みファイルとして使えるか
+// expected は録画するはずだった長さ（End - Start + 2 * Margin）
+func (a Attempt) Usable(expected time.Duration) bool {
+	return a.recorded() && a.Probe.Duration >= expected-coverageTolerance
+}
+
+// 少しでも録画できているか
+func (a Attempt) recorded() bool {
+	return a.Probe.Duration > 0
+}
+
+// この差までは同じ長さとみなす
+const durationTolerance = 2 * time.Second
+
+// a の方が b より良い録画結果であるか
+// 失敗していないもの、長いもの、欠落の少ないもの、配信元の番号が小さいものの順に優先する
+func (a Attempt) Better(b Attempt) bool {
+	if a.recorded() != b.recorded() {
+		return a.recorded()
+	}
+	if (a.Error == "") != (b.Error == "") {
+		return a.Error == ""
+	}
+	diff := a.Probe.Duration - b.Probe.Duration
+	if diff > durationTolerance || diff < -durationTolerance {
+		return diff > 0
+	}
+	if a.Probe.Discontinuities != b.Probe.Discontinuities {
+		return a.Probe.Discontinuities < b.Probe.Discontinuities
+	}
+	return a.Source < b.Source
+}
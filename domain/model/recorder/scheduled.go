@@ -0,0 +1,19 @@
+package recorder
+
+import (
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+)
+
+// 録画の開始を待っている番組
+type Scheduled struct {
+	Program program.Program
+
+	// 録画（ffmpeg）を開始する日時
+	// 通常は Program.Start - Margin
+	At time.Time
+
+	// 手動で At が変更されている
+	Rescheduled bool
+}
@@ -0,0 +1,39 @@
+package retention
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+)
+
+// 録画済みファイルの保持ルール
+// 各条件は 0 であれば無効
+type Rule struct {
+	// 対象とする station
+	// 空文字であればすべての station
+	Station program.Station
+
+	// 番組タイトルにこれを含むものを対象とする
+	// 空文字であればすべての番組
+	Title string
+
+	// 番組タイトル毎に新しいものから KeepLast 件だけ残す
+	KeepLast int
+
+	// 番組開始日時から MaxAge 以上経過したものを削除する
+	MaxAge time.Duration
+
+	// ルールに該当するファイルの合計サイズ（byte）が MaxTotalSize 以下になるまで古いものから削除する
+	MaxTotalSize int64
+}
+
+func (r Rule) Match(pgram program.Program) bool {
+	if r.Station != "" && r.Station != pgram.Station {
+		return false
+	}
+	if r.Title != "" && !strings.Contains(pgram.Title, r.Title) {
+		return false
+	}
+	return true
+}
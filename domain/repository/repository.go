@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/sobadon/anrd/domain/model/date"
+	"github.com/sobadon/anrd/domain/model/notification"
 	"github.com/sobadon/anrd/domain/model/program"
 	"github.com/sobadon/anrd/domain/model/recorder"
 )
@@ -13,11 +14,85 @@ import (
 type Station interface {
 	GetPrograms(ctx context.Context, date date.Date) ([]program.Program, error)
 	Rec(ctx context.Context, config recorder.Config, targetPgram program.Program) error
+
+	// Rec によって保存されるファイルのパス
+	ArchiveFilePath(config recorder.Config, pgram program.Program) string
+
+	// Rec が実行するコマンド
+	RecCommand(config recorder.Config, pgram program.Program) []string
+}
+
+// 続けて放送される番組をまとめて録画できる Station
+type ContinuousStation interface {
+	Station
+
+	// 前の番組の End と次の番組の Start が一致する pgrams を 1 つの接続で録画し、番組毎のファイルに分割する
+	// 分割後のファイルは Rec と同じく前後にマージンを含み、ArchiveFilePath に保存される
+	// 途中で失敗しても、それまでに録画できた番組は分割して保存する
+	// 保存できた先頭からの番組数を返す
+	RecContinuous(ctx context.Context, config recorder.Config, pgrams []program.Program) (int, error)
+}
+
+// 失敗した録画を途中から再開できる Station
+// 再開するたびに別の部分ファイルに録画し、最後に結合する
+type ResumableStation interface {
+	Station
+
+	// pgram を index 番目の部分ファイルに duration だけ録画する
+	RecPart(ctx context.Context, config recorder.Config, pgram program.Program, index int, duration time.Duration) error
+
+	// 部分ファイルを parts の順に無劣化で結合して ArchiveFilePath に保存し、部分ファイルを削除する
+	// 部分ファイルの間や End + Margin までの欠落は、保存するファイルのメタデータに記録する
+	// 空でない部分ファイルがなければエラー
+	ConcatParts(ctx context.Context, config recorder.Config, pgram program.Program, parts []recorder.Part) error
+}
+
+// 同じ番組を複数の配信元から同時に録画できる Station
+type RedundantStation interface {
+	Station
+
+	// 配信元の数
+	Sources() int
+
+	// source 番目の配信元から SourceFilePath に録画する
+	RecSource(ctx context.Context, config recorder.Config, pgram program.Program, source int) error
+
+	// RecSource によって保存されるファイルのパス
+	SourceFilePath(config recorder.Config, pgram program.Program, source int) string
+
+	// 録画済みファイルの長さと欠落を調べる
+	Probe(ctx context.Context, file string) (recorder.Probe, error)
+}
+
+// 実際には録画せず、録画するはずだったものを記録する Station
+// recorder.Config の DryRun が true のときに使う
+type PlanStation interface {
+	Station
+
+	// 差し替える前の Station
+	// 録画の仕方はこれが対応しているかで決める
+	Unwrap() Station
+
+	// pgrams を mode で録画するはずだったことを記録する
+	// mode が recorder.ModeContinuous のときだけ pgrams は複数になる
+	Plan(ctx context.Context, config recorder.Config, mode recorder.Mode, pgrams []program.Program) error
 }
 
 type ProgramPersistence interface {
+	// データベースに接続できるか確認
+	Ping(ctx context.Context) error
+
+	// 必要なテーブルが作成済みであるか確認
+	CheckSchema(ctx context.Context) error
+
+	// 番組表から取得した番組を登録する
+	// station と id が同じ番組があれば追加せず、scheduled であれば Title, Episode, Start, End を pgram に合わせる
 	Save(ctx context.Context, pgram program.Program) error
 
+	// station と id が同じ番組があれば UUID 以外のすべての項目を pgram で置き換え、なければ pgram を追加する
+	// 保存した番組と、追加したか否かを返す
+	Upsert(ctx context.Context, pgram program.Program) (stored program.Program, created bool, err error)
+
 	// duration 後までに始まる番組を取得
 	// 返されるエラー
 	// - errutil.ErrDatabaseNotFoundProgram
@@ -28,6 +103,82 @@ type ProgramPersistence interface {
 	// - errutil.ErrDatabaseNotFoundProgram
 	LoadOndemandScheduled(ctx context.Context, limit int) (*[]program.Program, error)
 
+	// LoadOndemandScheduled の対象となる番組の件数
+	CountOndemandScheduled(ctx context.Context) (int, error)
+
 	// pgram の status を newStatus に変更
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
 	ChangeStatus(ctx context.Context, pgram program.Program, newStatus program.Status) error
+
+	// pgram の録画済みファイルのパスを filePath に変更
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
+	ChangeFilePath(ctx context.Context, pgram program.Program, filePath string) error
+
+	// pgram のアップロード先の object key を objectKey に変更
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
+	ChangeObjectKey(ctx context.Context, pgram program.Program, objectKey string) error
+
+	// pgram を retention による削除から保護するか否か
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
+	ChangeProtected(ctx context.Context, pgram program.Program, protected bool) error
+
+	// status が done な番組をすべて取得
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
+	LoadDone(ctx context.Context) ([]program.Program, error)
+
+	// filter に該当する番組を start の昇順で取得
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
+	Load(ctx context.Context, filter program.Filter) ([]program.Program, error)
+
+	// uuid の番組を取得
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
+	LoadByUUID(ctx context.Context, uuid string) (program.Program, error)
+
+	// uuids の番組がデータベース上で最後に変更された日時
+	// key は番組の UUID、存在しない番組は含めない
+	LoadUpdatedAt(ctx context.Context, uuids []string) (map[string]time.Time, error)
+
+	// pgram の冗長録画の結果を保存する
+	// 既に保存されていれば置き換える
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
+	SaveAttempts(ctx context.Context, pgram program.Program, attempts []recorder.Attempt) error
+
+	// uuid の番組の冗長録画の結果を Source の昇順で取得
+	// 冗長録画していなければ空
+	LoadAttempts(ctx context.Context, uuid string) ([]recorder.Attempt, error)
+
+	// pgram の録画ログを追加
+	// 返されるエラー
+	// - errutil.ErrDatabaseNotFoundProgram
+	SaveRecordingLog(ctx context.Context, pgram program.Program, recLog recorder.RecordingLog) error
+
+	// uuid の番組の録画ログを StartedAt の昇順で取得
+	// なければ空
+	LoadRecordingLogs(ctx context.Context, uuid string) ([]recorder.RecordingLog, error)
+
+	// StartedAt が before より前の録画ログを削除し、削除したものを返す
+	// ログファイルは削除しない
+	DeleteRecordingLogsBefore(ctx context.Context, before time.Time) ([]recorder.RecordingLog, error)
+}
+
+// 録画済みファイルの保存先（S3 互換のオブジェクトストレージなど）
+type ObjectStorage interface {
+	// localPath のファイルを key としてアップロードし、破損なくアップロードできたか確認する
+	// 返されるエラー
+	// - errutil.ErrObjectStorage
+	// - errutil.ErrChecksumMismatch
+	Upload(ctx context.Context, localPath string, key string) error
+}
+
+// 録画などのイベントを外部に通知する
+type Notifier interface {
+	Notify(ctx context.Context, n notification.Notification) error
 }
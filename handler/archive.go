@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/fileutil"
+)
+
+// GET /archive/{path}
+// フィードの enclosure として公開している録画済みファイルだけを返す
+// ログや録画途中のファイル、ディレクトリの一覧は返さない
+func (h *handler) archive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// r.URL.Path は unescape 済み
+	rel := strings.TrimPrefix(r.URL.Path, "/archive/")
+	path, err := h.ucFeed.ArchiveFile(r.Context(), rel)
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		// アップロード後に削除されていることもある
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", fileutil.ContentType(path))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/ical"
+)
+
+// GET /calendar.ics
+// GET /calendar/{station}.ics
+func (h *handler) calendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var station program.Station
+	if r.URL.Path != "/calendar.ics" {
+		p := strings.TrimPrefix(r.URL.Path, "/calendar/")
+		if !strings.HasSuffix(p, ".ics") {
+			http.NotFound(w, r)
+			return
+		}
+		station = program.Station(strings.TrimSuffix(p, ".ics"))
+		if !station.Valid() {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	cal, err := h.ucCalendar.Calendar(r.Context(), station)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	err = ical.Encode(w, cal)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+	}
+}
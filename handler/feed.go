@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/podcast"
+)
+
+// GET /feeds/{station}.xml
+// GET /feeds/{station}/{title}.xml
+func (h *handler) feed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// r.URL.Path は unescape 済み
+	p := strings.TrimPrefix(r.URL.Path, "/feeds/")
+	if !strings.HasSuffix(p, ".xml") {
+		http.NotFound(w, r)
+		return
+	}
+	p = strings.TrimSuffix(p, ".xml")
+
+	var ch podcast.Channel
+	var err error
+	station, title, hasTitle := strings.Cut(p, "/")
+	if hasTitle {
+		ch, err = h.ucFeed.ProgramFeed(r.Context(), program.Station(station), title)
+	} else {
+		ch, err = h.ucFeed.StationFeed(r.Context(), program.Station(station))
+	}
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	err = podcast.Encode(w, ch)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/xmltv"
+)
+
+// GET /xmltv.xml
+// GET /xmltv/{station}.xml
+func (h *handler) guide(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var station program.Station
+	if r.URL.Path != "/xmltv.xml" {
+		p := strings.TrimPrefix(r.URL.Path, "/xmltv/")
+		if !strings.HasSuffix(p, ".xml") {
+			http.NotFound(w, r)
+			return
+		}
+		station = program.Station(strings.TrimSuffix(p, ".xml"))
+		if !station.Valid() {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	guide, err := h.ucGuide.Guide(r.Context(), station)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	err = xmltv.Encode(w, guide)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+	}
+}
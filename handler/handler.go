@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/health"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/ical"
+	"github.com/sobadon/anrd/internal/podcast"
+	"github.com/sobadon/anrd/internal/xmltv"
+)
+
+type feedUsecase interface {
+	StationFeed(ctx context.Context, station program.Station) (podcast.Channel, error)
+	ProgramFeed(ctx context.Context, station program.Station, title string) (podcast.Channel, error)
+	ArchiveFile(ctx context.Context, rel string) (string, error)
+}
+
+type calendarUsecase interface {
+	Calendar(ctx context.Context, station program.Station) (ical.Calendar, error)
+}
+
+type guideUsecase interface {
+	Guide(ctx context.Context, station program.Station) (xmltv.Guide, error)
+}
+
+type healthUsecase interface {
+	Liveness(ctx context.Context) health.Report
+	Readiness(ctx context.Context, now time.Time) health.Report
+}
+
+type scheduleUsecase interface {
+	Scheduled() []recorder.Scheduled
+	CancelScheduled(ctx context.Context, uuid string) (recorder.Scheduled, error)
+	Reschedule(uuid string, at time.Time) (recorder.Scheduled, error)
+	Recordings() []recorder.Recording
+}
+
+type handler struct {
+	ucFeed     feedUsecase
+	ucCalendar calendarUsecase
+	ucGuide    guideUsecase
+	ucHealth   healthUsecase
+	ucSchedule scheduleUsecase
+
+	// 録画予定を変更するリクエストに必要な Bearer token
+	// 空であれば HTTP からは変更させない
+	token string
+}
+
+func New(ucFeed feedUsecase, ucCalendar calendarUsecase, ucGuide guideUsecase, ucHealth healthUsecase, ucSchedule scheduleUsecase, token string) http.Handler {
+	h := &handler{
+		ucFeed:     ucFeed,
+		ucCalendar: ucCalendar,
+		ucGuide:    ucGuide,
+		ucHealth:   ucHealth,
+		ucSchedule: ucSchedule,
+		token:      token,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", h.healthz)
+	mux.HandleFunc("/readyz", h.readyz)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/feeds/", h.feed)
+	mux.HandleFunc("/calendar.ics", h.calendar)
+	mux.HandleFunc("/calendar/", h.calendar)
+	mux.HandleFunc("/xmltv.xml", h.guide)
+	mux.HandleFunc("/xmltv/", h.guide)
+	mux.HandleFunc("/schedule", h.scheduleList)
+	mux.HandleFunc("/schedule/", h.scheduleItem)
+	mux.HandleFunc("/recordings", h.recordingList)
+	mux.HandleFunc("/archive/", h.archive)
+	return withLogger(mux)
+}
+
+// Authorization: Bearer <token> が設定と一致するか
+// 一致しなければレスポンスを書き込んで false を返す
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if h.token == "" {
+		http.Error(w, "disabled (http_token is not set)", http.StatusForbidden)
+		return false
+	}
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// リクエスト毎の logger を context に入れる
+func withLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := log.Logger.With().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Logger().WithContext(r.Context())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/health"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/ical"
+	"github.com/sobadon/anrd/internal/podcast"
+	"github.com/sobadon/anrd/internal/timeutil"
+	"github.com/sobadon/anrd/internal/xmltv"
+)
+
+type fakeFeedUsecase struct {
+	archiveDir string
+}
+
+func (fakeFeedUsecase) StationFeed(ctx context.Context, station program.Station) (podcast.Channel, error) {
+	return podcast.Channel{Title: "station:" + station.String()}, nil
+}
+
+func (fakeFeedUsecase) ProgramFeed(ctx context.Context, station program.Station, title string) (podcast.Channel, error) {
+	if title != "鷲崎健のヨルナイト×ヨルナイト" {
+		return podcast.Channel{}, errutil.ErrDatabaseNotFoundProgram
+	}
+	return podcast.Channel{Title: "program:" + station.String() + "/" + title}, nil
+}
+
+func (f fakeFeedUsecase) ArchiveFile(ctx context.Context, rel string) (string, error) {
+	if rel != "agqr/yorunight.ts" && rel != "agqr/deleted.ts" {
+		return "", errutil.ErrDatabaseNotFoundProgram
+	}
+	return filepath.Join(f.archiveDir, filepath.FromSlash(rel)), nil
+}
+
+type fakeCalendarUsecase struct{}
+
+func (fakeCalendarUsecase) Calendar(ctx context.Context, station program.Station) (ical.Calendar, error) {
+	return ical.Calendar{Name: "calendar:" + station.String()}, nil
+}
+
+type fakeGuideUsecase struct{}
+
+func (fakeGuideUsecase) Guide(ctx context.Context, station program.Station) (xmltv.Guide, error) {
+	return xmltv.Guide{Channels: []xmltv.Channel{{ID: "guide:" + station.String()}}}, nil
+}
+
+type fakeHealthUsecase struct {
+	ready bool
+}
+
+func (fakeHealthUsecase) Liveness(ctx context.Context) health.Report {
+	return health.NewReport([]health.Check{{Name: "database", Status: health.StatusOK}})
+}
+
+func (f fakeHealthUsecase) Readiness(ctx context.Context, now time.Time) health.Report {
+	check := health.Check{Name: "scheduler", Status: health.StatusOK}
+	if !f.ready {
+		check.Status = health.StatusFail
+		check.Message = "scheduler is not running"
+	}
+	return health.NewReport([]health.Check{check})
+}
+
+type fakeScheduleUsecase struct{}
+
+var scheduledYorunight = recorder.Scheduled{
+	Program: program.Program{
+		UUID:    "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		Station: program.StationAgqr,
+		Title:   "鷲崎健のヨルナイト×ヨルナイト",
+		Start:   time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:     time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+	},
+	At: time.Date(2022, 8, 17, 22, 59, 0, 0, timeutil.LocationJST()),
+}
+
+func (fakeScheduleUsecase) Scheduled() []recorder.Scheduled {
+	return []recorder.Scheduled{scheduledYorunight}
+}
+
+func (fakeScheduleUsecase) CancelScheduled(ctx context.Context, uuid string) (recorder.Scheduled, error) {
+	if uuid != scheduledYorunight.Program.UUID {
+		return recorder.Scheduled{}, errutil.ErrNotScheduled
+	}
+	return scheduledYorunight, nil
+}
+
+func (fakeScheduleUsecase) Reschedule(uuid string, at time.Time) (recorder.Scheduled, error) {
+	if uuid != scheduledYorunight.Program.UUID {
+		return recorder.Scheduled{}, errutil.ErrNotScheduled
+	}
+	s := scheduledYorunight
+	s.At = at
+	s.Rescheduled = true
+	return s, nil
+}
+
+func (fakeScheduleUsecase) Recordings() []recorder.Recording {
+	return []recorder.Recording{{
+		Program:   scheduledYorunight.Program,
+		StartedAt: scheduledYorunight.At,
+		Progress: recorder.Progress{
+			OutTime:   90 * time.Second,
+			Bitrate:   129.4,
+			TotalSize: 1456128,
+			UpdatedAt: time.Date(2022, 8, 17, 23, 0, 30, 0, timeutil.LocationJST()),
+		},
+	}}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name          string
+		ready         bool
+		method        string
+		path          string
+		authorization string
+		wantCode      int
+		wantContains  string
+	}{
+		{
+			name:         "healthz",
+			path:         "/healthz",
+			wantCode:     http.StatusOK,
+			wantContains: `{"status":"ok","checks":[{"name":"database","status":"ok"}]}`,
+		},
+		{
+			name:         "readyz は失敗していれば 503 とその理由",
+			ready:        false,
+			path:         "/readyz",
+			wantCode:     http.StatusServiceUnavailable,
+			wantContains: `{"status":"fail","checks":[{"name":"scheduler","status":"fail","message":"scheduler is not running"}]}`,
+		},
+		{
+			name:         "station のフィード",
+			path:         "/feeds/agqr.xml",
+			wantCode:     http.StatusOK,
+			wantContains: "<title>station:agqr</title>",
+		},
+		{
+			name:         "番組のフィード",
+			path:         "/feeds/agqr/%E9%B7%B2%E5%B4%8E%E5%81%A5%E3%81%AE%E3%83%A8%E3%83%AB%E3%83%8A%E3%82%A4%E3%83%88%C3%97%E3%83%A8%E3%83%AB%E3%83%8A%E3%82%A4%E3%83%88.xml",
+			wantCode:     http.StatusOK,
+			wantContains: "<title>program:agqr/鷲崎健のヨルナイト×ヨルナイト</title>",
+		},
+		{
+			name:     "存在しない番組のフィードは 404",
+			path:     "/feeds/agqr/unknown.xml",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "すべての station のカレンダー",
+			path:         "/calendar.ics",
+			wantCode:     http.StatusOK,
+			wantContains: "X-WR-CALNAME:calendar:\r\n",
+		},
+		{
+			name:         "station のカレンダー",
+			path:         "/calendar/onsen.ics",
+			wantCode:     http.StatusOK,
+			wantContains: "X-WR-CALNAME:calendar:onsen\r\n",
+		},
+		{
+			name:     "存在しない station のカレンダーは 404",
+			path:     "/calendar/unknown.ics",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "すべての station の XMLTV",
+			path:         "/xmltv.xml",
+			wantCode:     http.StatusOK,
+			wantContains: `<channel id="guide:">`,
+		},
+		{
+			name:         "station の XMLTV",
+			path:         "/xmltv/agqr.xml",
+			wantCode:     http.StatusOK,
+			wantContains: `<channel id="guide:agqr">`,
+		},
+		{
+			name:     "存在しない station の XMLTV は 404",
+			path:     "/xmltv/unknown.xml",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "録画開始を待っている番組の一覧",
+			path:         "/schedule",
+			wantCode:     http.StatusOK,
+			wantContains: `[{"uuid":"b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a","station":"agqr","title":"鷲崎健のヨルナイト×ヨルナイト","start":"2022-08-17T23:00:00+09:00","end":"2022-08-18T00:00:00+09:00","at":"2022-08-17T22:59:00+09:00","rescheduled":false}]`,
+		},
+		{
+			name:          "録画開始日時の変更",
+			method:        http.MethodPost,
+			path:          "/schedule/b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a?at=2022-08-17T23:05:00%2B09:00",
+			authorization: "Bearer secret",
+			wantCode:      http.StatusOK,
+			wantContains:  `"at":"2022-08-17T23:05:00+09:00","rescheduled":true`,
+		},
+		{
+			name:          "録画開始日時が読めなければ 400",
+			method:        http.MethodPost,
+			path:          "/schedule/b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a?at=23:05",
+			authorization: "Bearer secret",
+			wantCode:      http.StatusBadRequest,
+		},
+		{
+			name:     "token がなければ録画開始日時を変更できない",
+			method:   http.MethodPost,
+			path:     "/schedule/b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a?at=2022-08-17T23:05:00%2B09:00",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "token が違えば取り消せない",
+			method:        http.MethodDelete,
+			path:          "/schedule/b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+			authorization: "Bearer wrong",
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:         "録画中の番組と進み具合",
+			path:         "/recordings",
+			wantCode:     http.StatusOK,
+			wantContains: `[{"uuid":"b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a","station":"agqr","title":"鷲崎健のヨルナイト×ヨルナイト","started_at":"2022-08-17T22:59:00+09:00","out_time":90,"bitrate_kbps":129.4,"total_size":1456128,"updated_at":"2022-08-17T23:00:30+09:00"}]`,
+		},
+		{
+			name:          "待っていない番組の取り消しは 404",
+			method:        http.MethodDelete,
+			path:          "/schedule/0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10",
+			authorization: "Bearer secret",
+			wantCode:      http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(fakeFeedUsecase{}, fakeCalendarUsecase{}, fakeGuideUsecase{}, fakeHealthUsecase{ready: tt.ready}, fakeScheduleUsecase{}, "secret")
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantContains) {
+				t.Errorf("body = %s, want contains %s", rec.Body.String(), tt.wantContains)
+			}
+		})
+	}
+}
+
+func TestNew_scheduleWithoutToken(t *testing.T) {
+	// http_token が設定されていなければ HTTP からは変更させない
+	h := New(fakeFeedUsecase{}, fakeCalendarUsecase{}, fakeGuideUsecase{}, fakeHealthUsecase{}, fakeScheduleUsecase{}, "")
+	req := httptest.NewRequest(http.MethodDelete, "/schedule/b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status code = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestNew_archive(t *testing.T) {
+	archiveDir := t.TempDir()
+	for name, content := range map[string]string{
+		"agqr/yorunight.ts":  "recorded",
+		"agqr/yorunight.log": "ffmpeg log",
+	} {
+		path := filepath.Join(archiveDir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantBody string
+		// 空であれば確認しない
+		wantContentType string
+	}{
+		{
+			name:            "done な番組の録画済みファイル",
+			path:            "/archive/agqr/yorunight.ts",
+			wantCode:        http.StatusOK,
+			wantBody:        "recorded",
+			wantContentType: "video/mp2t",
+		},
+		{
+			name:     "録画済みファイル以外は 404",
+			path:     "/archive/agqr/yorunight.log",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "ディレクトリの一覧は返さない",
+			path:     "/archive/agqr/",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "削除されていれば 404",
+			path:     "/archive/agqr/deleted.ts",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "GET と HEAD 以外は 405",
+			method:   http.MethodPost,
+			path:     "/archive/agqr/yorunight.ts",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(fakeFeedUsecase{archiveDir: archiveDir}, fakeCalendarUsecase{}, fakeGuideUsecase{}, fakeHealthUsecase{}, fakeScheduleUsecase{}, "")
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Content-Type"); tt.wantContentType != "" && got != tt.wantContentType {
+				t.Errorf("Content-Type = %s, want %s", got, tt.wantContentType)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/health"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+// GET /healthz
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, r, h.ucHealth.Liveness(r.Context()))
+}
+
+// GET /readyz
+func (h *handler) readyz(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, r, h.ucHealth.Readiness(r.Context(), time.Now().In(timeutil.LocationJST())))
+}
+
+func writeReport(w http.ResponseWriter, r *http.Request, report health.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.OK() {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	err := json.NewEncoder(w).Encode(report)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/recorder"
+)
+
+type recordingResponse struct {
+	UUID      string    `json:"uuid"`
+	Station   string    `json:"station"`
+	Title     string    `json:"title"`
+	Episode   string    `json:"episode,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	// 以下は ffmpeg から報告されていなければ 0
+	// 秒
+	OutTime     float64 `json:"out_time"`
+	BitrateKbps float64 `json:"bitrate_kbps"`
+	TotalSize   int64   `json:"total_size"`
+	// ffmpeg から報告されていなければ null
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+func newRecordingResponse(r recorder.Recording) recordingResponse {
+	res := recordingResponse{
+		UUID:        r.Program.UUID,
+		Station:     r.Program.Station.String(),
+		Title:       r.Program.Title,
+		Episode:     r.Program.Episode,
+		StartedAt:   r.StartedAt,
+		OutTime:     r.Progress.OutTime.Seconds(),
+		BitrateKbps: r.Progress.Bitrate,
+		TotalSize:   r.Progress.TotalSize,
+	}
+	if !r.Progress.UpdatedAt.IsZero() {
+		res.UpdatedAt = &r.Progress.UpdatedAt
+	}
+	return res
+}
+
+// GET /recordings
+// 録画中の番組とその進み具合を録画開始日時の昇順で返す
+func (h *handler) recordingList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	res := []recordingResponse{}
+	for _, recording := range h.ucSchedule.Recordings() {
+		res = append(res, newRecordingResponse(recording))
+	}
+	writeJSON(w, r, http.StatusOK, res)
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+type scheduledResponse struct {
+	UUID        string    `json:"uuid"`
+	Station     string    `json:"station"`
+	Title       string    `json:"title"`
+	Episode     string    `json:"episode,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	At          time.Time `json:"at"`
+	Rescheduled bool      `json:"rescheduled"`
+}
+
+func newScheduledResponse(s recorder.Scheduled) scheduledResponse {
+	return scheduledResponse{
+		UUID:        s.Program.UUID,
+		Station:     s.Program.Station.String(),
+		Title:       s.Program.Title,
+		Episode:     s.Program.Episode,
+		Start:       s.Program.Start,
+		End:         s.Program.End,
+		At:          s.At,
+		Rescheduled: s.Rescheduled,
+	}
+}
+
+// GET /schedule
+// 録画開始を待っている番組を録画開始日時の昇順で返す
+func (h *handler) scheduleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	res := []scheduledResponse{}
+	for _, s := range h.ucSchedule.Scheduled() {
+		res = append(res, newScheduledResponse(s))
+	}
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// DELETE /schedule/{uuid}
+// 録画を取り消す（status は skipped になる）
+// POST /schedule/{uuid}?at=2022-08-17T23:05:00+09:00
+// 録画開始日時を変更する
+// いずれも Authorization: Bearer <http_token> が必要
+func (h *handler) scheduleItem(w http.ResponseWriter, r *http.Request) {
+	uuid := strings.TrimPrefix(r.URL.Path, "/schedule/")
+	if uuid == "" || strings.Contains(uuid, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorize(w, r) {
+		return
+	}
+
+	var scheduled recorder.Scheduled
+	var err error
+	switch r.Method {
+	case http.MethodDelete:
+		scheduled, err = h.ucSchedule.CancelScheduled(r.Context(), uuid)
+	case http.MethodPost:
+		at, parseErr := time.Parse(time.RFC3339, r.FormValue("at"))
+		if parseErr != nil {
+			http.Error(w, "at must be RFC 3339", http.StatusBadRequest)
+			return
+		}
+		scheduled, err = h.ucSchedule.Reschedule(uuid, at)
+	}
+	if errors.Is(err, errutil.ErrNotScheduled) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, newScheduledResponse(scheduled))
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Msgf("%+v", err)
+	}
+}
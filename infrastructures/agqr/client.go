@@ -1,34 +1,138 @@
 package agqr
 
 import (
+	"context"
 	"net/http"
 	"net/url"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
 )
 
+type Config struct {
+	// 番組表の URL
+	ProgramURL string
+
+	// 録画する HLS ストリームの URL
+	// PlayerURL から調べられなかったときにも使う
+	StreamURL string
+
+	// HLS ストリームの URL を調べる公式プレイヤーのページ
+	// 空であれば StreamURL のみを使う
+	PlayerURL string
+
+	// PlayerURL から調べた URL を使い続ける間
+	// 0 であれば 1 時間
+	StreamURLCacheTTL time.Duration
+
+	// 冗長録画で StreamURL と同時に録画する HLS ストリームの URL
+	// 空であれば配信元は StreamURL のみ
+	SecondaryStreamURL string
+
+	// ffmpeg の -loglevel
+	// 空であれば warning
+	FfmpegLoglevel string
+
+	// nil であれば実際の時刻
+	Clock clock.Clock
+}
+
 type client struct {
 	httpClient     *http.Client
 	programBaseURL *url.URL
 	streamURL      *url.URL
+	// nil であれば streamURL のみを使う
+	resolver *streamResolver
+	// nil であれば冗長録画しない
+	secondaryStreamURL *url.URL
+	ffmpegLoglevel     string
 }
 
-func New() repository.Station {
-	programBaseURL, err := url.Parse("https://www.joqr.co.jp/rss/program/json.php?type=ag")
+func New(config Config) (repository.Station, error) {
+	programBaseURL, err := url.Parse(config.ProgramURL)
 	if err != nil {
-		panic(err)
+		return nil, errors.Wrap(errutil.ErrInternal, err.Error())
 	}
 
-	// 低画質
-	// https://www.uniqueradio.jp/agplayer5/player.php から取得されるもの
-	streamURL, err := url.Parse("https://hlsb2.cdnext.stream.ne.jp/agqr1next/aandg1next.m3u8")
+	streamURL, err := url.Parse(config.StreamURL)
 	if err != nil {
-		panic(err)
+		return nil, errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+
+	var secondaryStreamURL *url.URL
+	if config.SecondaryStreamURL != "" {
+		secondaryStreamURL, err = url.Parse(config.SecondaryStreamURL)
+		if err != nil {
+			return nil, errors.Wrap(errutil.ErrInternal, err.Error())
+		}
+	}
+
+	ffmpegLoglevel := config.FfmpegLoglevel
+	if ffmpegLoglevel == "" {
+		ffmpegLoglevel = "warning"
+	}
+
+	clk := config.Clock
+	if clk == nil {
+		clk = clock.New()
+	}
+
+	// 番組表の取得や URL の確認が返ってこないまま録画の開始を待たせない
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+
+	var resolver *streamResolver
+	if config.PlayerURL != "" {
+		playerURL, err := url.Parse(config.PlayerURL)
+		if err != nil {
+			return nil, errors.Wrap(errutil.ErrInternal, err.Error())
+		}
+		ttl := config.StreamURLCacheTTL
+		if ttl == 0 {
+			ttl = 1 * time.Hour
+		}
+		resolver = &streamResolver{
+			httpClient: httpClient,
+			playerURL:  playerURL,
+			fallback:   streamURL,
+			ttl:        ttl,
+			clock:      clk,
+		}
 	}
 
 	return &client{
-		httpClient:     &http.Client{},
-		programBaseURL: programBaseURL,
-		streamURL:      streamURL,
+		httpClient:         httpClient,
+		programBaseURL:     programBaseURL,
+		streamURL:          streamURL,
+		resolver:           resolver,
+		secondaryStreamURL: secondaryStreamURL,
+		ffmpegLoglevel:     ffmpegLoglevel,
+	}, nil
+}
+
+// 録画に使う HLS ストリームの URL
+func (c *client) primaryStreamURL() *url.URL {
+	if c.resolver == nil {
+		return c.streamURL
+	}
+	return c.resolver.Current()
+}
+
+// ffmpeg を始める前に primaryStreamURL を最新にする
+func (c *client) resolveStreamURL(ctx context.Context) {
+	if c.resolver == nil {
+		return
+	}
+	c.resolver.Resolve(ctx)
+}
+
+// primaryStreamURL を使った ffmpeg が失敗すれば、次の resolveStreamURL で URL を確認しなおす
+// err をそのまま返す
+func (c *client) checkStreamError(err error) error {
+	if err != nil && c.resolver != nil {
+		c.resolver.MarkFailed()
 	}
+	return err
 }
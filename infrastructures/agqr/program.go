@@ -3,10 +3,12 @@ package agqr
 import (
 	"context"
 	"encoding/json"
+	"html"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -173,11 +175,28 @@ func agqrProgramToProgram(agqrPgram agqrProgram) (program.Program, error) {
 		Status:      program.StatusScheduled,
 		StreamType:  program.StreamTypeBroadcast,
 		PlaylistURL: "",
-		// TODO: Personality すぐ必要なわけではないならいいや
+		// 番組表の JSON では & などが文字参照のまま入っている
+		Description:   html.UnescapeString(agqrPgram.ProgramInformation),
+		Personalities: splitPersonalities(agqrPgram.ProgramPersonality),
 	}
 	return pgram, nil
 }
 
+// "鷲崎健, 沢口けいこ" -> ["鷲崎健", "沢口けいこ"]
+// 全角の読点で区切られていることもある
+func splitPersonalities(s string) []string {
+	var personalities []string
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '、' || r == '，'
+	}) {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			personalities = append(personalities, name)
+		}
+	}
+	return personalities
+}
+
 // 24 時を超える表記もひっくるめて time.Time にパースする
 func converToTime(date date.Date, hour int, minute int) (time.Time, error) {
 	year, err := strconv.Atoi(time.Time(date).Format("2006"))
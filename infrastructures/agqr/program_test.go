@@ -201,15 +201,17 @@ func Test_agqrProgramToProgram(t *testing.T) {
 			},
 			want: program.Program{
 				// UUID: ***
-				ID:          514579,
-				Station:     program.StationAgqr,
-				Title:       "セブン-イレブンpresents 佐倉としたい大西",
-				Episode:     "",
-				Start:       time.Date(2022, 8, 3, 11, 30, 0, 0, timeutil.LocationJST()),
-				End:         time.Date(2022, 8, 3, 12, 0, 0, 0, timeutil.LocationJST()),
-				Status:      program.StatusScheduled,
-				StreamType:  program.StreamTypeBroadcast,
-				PlaylistURL: "",
+				ID:            514579,
+				Station:       program.StationAgqr,
+				Title:         "セブン-イレブンpresents 佐倉としたい大西",
+				Episode:       "",
+				Start:         time.Date(2022, 8, 3, 11, 30, 0, 0, timeutil.LocationJST()),
+				End:           time.Date(2022, 8, 3, 12, 0, 0, 0, timeutil.LocationJST()),
+				Status:        program.StatusScheduled,
+				StreamType:    program.StreamTypeBroadcast,
+				PlaylistURL:   "",
+				Description:   "この番組は、文化放送と＜音泉＞が一緒にラジオを「したい」。佐倉さんが大西さんとラジオが「したい」。大西さんも佐倉さんと繋がりたい！と始まった今までにない実験的なプロジェクトです！！文化放送では超A&G＋にて毎週火曜23時半より動画付きで放送中！＜音泉＞では毎週火曜24時よりおまけコーナーをつけてアーカイブで配信中！",
+				Personalities: []string{"佐倉綾音", "大西沙織"},
 			},
 		},
 		{
@@ -232,15 +234,17 @@ func Test_agqrProgramToProgram(t *testing.T) {
 			},
 			want: program.Program{
 				// UUID: ***
-				ID:          514569,
-				Station:     program.StationAgqr,
-				Title:       "鷲崎健のヨルナイト×ヨルナイト",
-				Episode:     "",
-				Start:       time.Date(2022, 8, 4, 0, 0, 0, 0, timeutil.LocationJST()),
-				End:         time.Date(2022, 8, 4, 0, 30, 0, 0, timeutil.LocationJST()),
-				Status:      program.StatusScheduled,
-				StreamType:  program.StreamTypeBroadcast,
-				PlaylistURL: "",
+				ID:            514569,
+				Station:       program.StationAgqr,
+				Title:         "鷲崎健のヨルナイト×ヨルナイト",
+				Episode:       "",
+				Start:         time.Date(2022, 8, 4, 0, 0, 0, 0, timeutil.LocationJST()),
+				End:           time.Date(2022, 8, 4, 0, 30, 0, 0, timeutil.LocationJST()),
+				Status:        program.StatusScheduled,
+				StreamType:    program.StreamTypeBroadcast,
+				PlaylistURL:   "",
+				Description:   "アニメ・声優・ゲーム業界のイベントの司会を多数担当するミュージシャンの鷲崎健による月曜日～木曜日　２４時～２５時の生放送。水曜日はミュージシャンの青木佑磨が登場！",
+				Personalities: []string{"鷲崎健", "青木佑磨"},
 			},
 		},
 	}
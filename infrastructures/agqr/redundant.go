@@ -0,0 +1,136 @@
+package agqr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/ffmpegutil"
+	"github.com/sobadon/anrd/internal/fileutil"
+)
+
+// パケットの間がこれ以上空いていれば欠落とみなす
+const discontinuityThreshold = 1 * time.Second
+
+func (c *client) Sources() int {
+	return len(c.sourceURLs())
+}
+
+func (c *client) sourceURLs() []*url.URL {
+	if c.secondaryStreamURL == nil {
+		return []*url.URL{c.primaryStreamURL()}
+	}
+	return []*url.URL{c.primaryStreamURL(), c.secondaryStreamURL}
+}
+
+func (c *client) RecSource(ctx context.Context, config recorder.Config, pgram program.Program, source int) error {
+	file := c.SourceFilePath(config, pgram, source)
+	err := fileutil.MkdirAllIfNotExist(filepath.Dir(file))
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	if source == 0 {
+		c.resolveStreamURL(ctx)
+	}
+	args, err := c.sourceCommand(config, pgram, source)
+	if err != nil {
+		return err
+	}
+
+	log.Ctx(ctx).Debug().Msgf("ffmpeg start ... (program = %+v, source = %d)", pgram, source)
+	err = ffmpegutil.Run(ctx, args, recOptions(config, recDuration(config, pgram)))
+	if source == 0 {
+		return c.checkStreamError(err)
+	}
+	return err
+}
+
+func (c *client) sourceCommand(config recorder.Config, pgram program.Program, source int) ([]string, error) {
+	urls := c.sourceURLs()
+	if source < 0 || len(urls) <= source {
+		return nil, errors.Wrapf(errutil.ErrInternal, "unknown source %d", source)
+	}
+	args := c.RecCommand(config, pgram)
+	for i, arg := range args {
+		if arg == "-i" {
+			args[i+1] = urls[source].String()
+		}
+	}
+	args[len(args)-1] = c.SourceFilePath(config, pgram, source)
+	return args, nil
+}
+
+func (c *client) SourceFilePath(config recorder.Config, pgram program.Program, source int) string {
+	return fmt.Sprintf("%s.source%d.ts", strings.TrimSuffix(c.ArchiveFilePath(config, pgram), ".ts"), source)
+}
+
+// ffprobe で音声のパケットのタイムスタンプを調べる
+func (c *client) Probe(ctx context.Context, file string) (recorder.Probe, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "a:0",
+		"-show_entries", "packet=pts_time,duration_time",
+		"-of", "csv=p=0",
+		file,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return recorder.Probe{}, errors.Wrapf(errutil.ErrFfmpeg, "ffprobe: %s: %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return parsePackets(bytes.NewReader(out))
+}
+
+// ffprobe の出力（1 行に pts_time,duration_time）から、長さと欠落の数を求める
+func parsePackets(r io.Reader) (recorder.Probe, error) {
+	var (
+		probe    recorder.Probe
+		first    float64
+		prevEnd  float64
+		hasFirst bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), ",")
+		pts, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			// N/A など
+			continue
+		}
+		var duration float64
+		if len(fields) >= 2 {
+			duration, _ = strconv.ParseFloat(fields[1], 64)
+		}
+
+		if !hasFirst {
+			first, hasFirst = pts, true
+		} else {
+			jump := time.Duration((pts - prevEnd) * float64(time.Second))
+			if jump >= discontinuityThreshold || jump <= -discontinuityThreshold {
+				probe.Discontinuities++
+			}
+		}
+		prevEnd = pts + duration
+	}
+	if err := scanner.Err(); err != nil {
+		return recorder.Probe{}, errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	if hasFirst {
+		probe.Duration = time.Duration((prevEnd - first) * float64(time.Second)).Round(time.Millisecond)
+	}
+	return probe, nil
+}
@@ -0,0 +1,172 @@
+package agqr
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+// 録画の開始を遅らせないよう、ページの取得とプレイリストの確認をこの間で諦める
+const resolveTimeout = 10 * time.Second
+
+// プレイヤーのページの <source src="..."> や JavaScript の設定に含まれる HLS の URL
+var streamURLPattern = regexp.MustCompile(`["']([^"'\s]+\.m3u8[^"'\s]*)["']`)
+
+// 公式プレイヤーのページから、現在の HLS ストリームの URL を調べる
+// CDN のパスが変わっても設定を変更せずに録画できるようにする
+type streamResolver struct {
+	httpClient *http.Client
+	playerURL  *url.URL
+	// ページから調べられなかったときに使う
+	fallback *url.URL
+	// 調べた URL をこの間は使い続ける
+	ttl   time.Duration
+	clock clock.Clock
+
+	// cached と resolvedAt を守る
+	// 通信の間は持たない
+	mu sync.Mutex
+	// nil であれば調べていない、または使えなかった
+	cached     *url.URL
+	resolvedAt time.Time
+	// true であれば cached を使った録画が失敗している
+	failed bool
+}
+
+// 直近に調べた URL
+// 調べていなければ fallback
+func (s *streamResolver) Current() *url.URL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached == nil {
+		return s.fallback
+	}
+	return s.cached
+}
+
+// 録画に失敗したときに呼ぶ
+// 次の Resolve でキャッシュした URL が使えるか確認する
+func (s *streamResolver) MarkFailed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failed = true
+}
+
+// 録画を始める前に呼ぶ
+// キャッシュが有効な間は通信せずにキャッシュした URL を返す
+// キャッシュが切れていればページから調べなおす
+// MarkFailed された後は、キャッシュした URL が 4xx を返せば配信の URL が変わったとみなして調べなおす
+// 調べた URL も使えなければ fallback を返す
+// 同時に呼ばれればそれぞれが調べ、最後に調べた URL が残る
+func (s *streamResolver) Resolve(ctx context.Context) *url.URL {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	s.mu.Lock()
+	cached, resolvedAt, failed := s.cached, s.resolvedAt, s.failed
+	s.failed = false
+	s.mu.Unlock()
+
+	if cached != nil && s.clock.Now().Sub(resolvedAt) < s.ttl {
+		if !failed {
+			return cached
+		}
+		err := s.check(ctx, cached)
+		if err == nil {
+			return cached
+		}
+		log.Ctx(ctx).Warn().Msgf("cached stream url is not available, re-resolve: %+v", err)
+	}
+
+	streamURL, err := s.discover(ctx)
+	if err == nil {
+		err = s.check(ctx, streamURL)
+	}
+	if err != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to resolve stream url, use %s: %+v", s.fallback, err)
+		s.mu.Lock()
+		s.cached = nil
+		s.mu.Unlock()
+		return s.fallback
+	}
+
+	if streamURL.String() != s.fallback.String() {
+		log.Ctx(ctx).Info().Msgf("resolved stream url %s (configured = %s)", streamURL, s.fallback)
+	}
+	s.mu.Lock()
+	s.cached = streamURL
+	s.resolvedAt = s.clock.Now()
+	s.mu.Unlock()
+	return streamURL
+}
+
+// プレイヤーのページで最初に見つかった m3u8 の URL
+// 返されるエラー
+// - errutil.ErrHTTPRequest
+// - errutil.ErrStreamNotFound
+func (s *streamResolver) discover(ctx context.Context) (*url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.playerURL.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrHTTPRequest, err.Error())
+	}
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrHTTPRequest, err.Error())
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(errutil.ErrHTTPRequest, "player page status code is %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrHTTPRequest, err.Error())
+	}
+	return findStreamURL(s.playerURL, body)
+}
+
+func findStreamURL(playerURL *url.URL, page []byte) (*url.URL, error) {
+	match := streamURLPattern.FindSubmatch(page)
+	if match == nil {
+		return nil, errors.Wrapf(errutil.ErrStreamNotFound, "m3u8 is not found in %s", playerURL)
+	}
+	ref, err := url.Parse(string(match[1]))
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrStreamNotFound, err.Error())
+	}
+	// 相対パスで書かれていることもある
+	return playerURL.ResolveReference(ref), nil
+}
+
+// プレイリストを取得できるか
+// 4xx であれば errutil.ErrStreamNotFound
+// 5xx や通信の失敗は一時的なものかもしれないので、URL は使える扱いにする
+func (s *streamResolver) check(ctx context.Context, streamURL *url.URL) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL.String(), nil)
+	if err != nil {
+		return errors.Wrap(errutil.ErrHTTPRequest, err.Error())
+	}
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to check stream url: %+v", err)
+		return nil
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if 400 <= res.StatusCode && res.StatusCode < 500 {
+		return errors.Wrapf(errutil.ErrStreamNotFound, "%s returns status code %d", streamURL, res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package agqr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+func Test_streamResolver_Resolve(t *testing.T) {
+	const (
+		playerPath = "/agplayer5/player.php"
+		// 設定した URL
+		oldPath = "/agqr1next/aandg1next.m3u8"
+		// CDN のパスが変わった後の URL
+		newPath = "/agqr2next/aandg1next.m3u8"
+	)
+	now := time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST())
+
+	tests := []struct {
+		name string
+		// プレイヤーのページ
+		// 空であれば 503 を返す
+		page string
+		// 空であれば調べていない
+		cached     string
+		resolvedAt time.Time
+		// cached を使った録画が失敗している
+		failed bool
+		want   string
+		// プレイヤーのページ、プレイリストを取得した回数
+		wantPageRequests     int
+		wantPlaylistRequests int
+	}{
+		{
+			name:                 "プレイヤーのページから URL を調べられる",
+			page:                 `<video id="agqr_hls"><source src="` + newPath + `" type="application/x-mpegURL"></video>`,
+			want:                 newPath,
+			wantPageRequests:     1,
+			wantPlaylistRequests: 1,
+		},
+		{
+			name:                 "録画に失敗した後、キャッシュした URL が 4xx を返せば調べなおす",
+			page:                 `<video id="agqr_hls"><source src="` + newPath + `" type="application/x-mpegURL"></video>`,
+			cached:               oldPath,
+			resolvedAt:           now.Add(-10 * time.Minute),
+			failed:               true,
+			want:                 newPath,
+			wantPageRequests:     1,
+			wantPlaylistRequests: 2,
+		},
+		{
+			name:                 "録画に失敗した後でも、キャッシュした URL が使えればページを取得しない",
+			page:                 `<video id="agqr_hls"><source src="` + newPath + `" type="application/x-mpegURL"></video>`,
+			cached:               newPath,
+			resolvedAt:           now.Add(-10 * time.Minute),
+			failed:               true,
+			want:                 newPath,
+			wantPageRequests:     0,
+			wantPlaylistRequests: 1,
+		},
+		{
+			name:                 "キャッシュが有効な間は通信しない",
+			page:                 `<video id="agqr_hls"><source src="` + newPath + `" type="application/x-mpegURL"></video>`,
+			cached:               oldPath,
+			resolvedAt:           now.Add(-10 * time.Minute),
+			want:                 oldPath,
+			wantPageRequests:     0,
+			wantPlaylistRequests: 0,
+		},
+		{
+			name:                 "ページを取得できなければ設定した URL を使う",
+			cached:               newPath,
+			resolvedAt:           now.Add(-2 * time.Hour),
+			want:                 oldPath,
+			wantPageRequests:     1,
+			wantPlaylistRequests: 0,
+		},
+		{
+			name:                 "ページに URL がなければ設定した URL を使う",
+			page:                 `<p>ただいまメンテナンス中です</p>`,
+			want:                 oldPath,
+			wantPageRequests:     1,
+			wantPlaylistRequests: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// プレイヤーのページと CDN の代わり
+			// CDN のパスが変わり、設定した URL は 404 を返す状況
+			var pageRequests, playlistRequests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case playerPath:
+					atomic.AddInt32(&pageRequests, 1)
+					if tt.page == "" {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					fmt.Fprint(w, tt.page)
+				case newPath:
+					atomic.AddInt32(&playlistRequests, 1)
+					fmt.Fprint(w, "#EXTM3U\n")
+				case oldPath:
+					atomic.AddInt32(&playlistRequests, 1)
+					w.WriteHeader(http.StatusNotFound)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			player, _ := url.Parse(server.URL + playerPath)
+			fallback, _ := url.Parse(server.URL + oldPath)
+			s := &streamResolver{
+				httpClient: server.Client(),
+				playerURL:  player,
+				fallback:   fallback,
+				ttl:        1 * time.Hour,
+				clock:      clock.NewFake(now),
+				resolvedAt: tt.resolvedAt,
+				failed:     tt.failed,
+			}
+			if tt.cached != "" {
+				s.cached, _ = url.Parse(server.URL + tt.cached)
+			}
+
+			want := server.URL + tt.want
+			got := s.Resolve(context.Background())
+			if got.String() != want {
+				t.Errorf("streamResolver.Resolve() = %s, want %s", got, want)
+			}
+			// 録画のコマンドには調べた URL が使われる
+			if current := s.Current(); current.String() != want {
+				t.Errorf("streamResolver.Current() = %s, want %s", current, want)
+			}
+			if got := atomic.LoadInt32(&pageRequests); int(got) != tt.wantPageRequests {
+				t.Errorf("page requests = %d, want %d", got, tt.wantPageRequests)
+			}
+			if got := atomic.LoadInt32(&playlistRequests); int(got) != tt.wantPlaylistRequests {
+				t.Errorf("playlist requests = %d, want %d", got, tt.wantPlaylistRequests)
+			}
+		})
+	}
+}
+
+func Test_findStreamURL(t *testing.T) {
+	player, _ := url.Parse("https://www.uniqueradio.jp/agplayer5/player.php")
+	tests := []struct {
+		name    string
+		page    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "source の src",
+			page: `<video id="agqr_hls"><source src="https://cdn.agqr.test/agqr2next/aandg1next.m3u8" type="application/x-mpegURL"></video>`,
+			want: "https://cdn.agqr.test/agqr2next/aandg1next.m3u8",
+		},
+		{
+			name: "JavaScript の設定に相対パスで書かれている",
+			page: `<script>var player = videojs('agqr', { sources: [{ src: '/hls/aandg1.m3u8?token=abc', type: 'application/x-mpegURL' }] });</script>`,
+			want: "https://www.uniqueradio.jp/hls/aandg1.m3u8?token=abc",
+		},
+		{
+			name:    "m3u8 がなければエラー",
+			page:    `<p>ただいまメンテナンス中です</p>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findStreamURL(player, []byte(tt.page))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findStreamURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("findStreamURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,59 +3,274 @@ package agqr
 import (
 	"context"
 	"fmt"
-	"os/exec"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/sobadon/anrd/domain/model/program"
 	"github.com/sobadon/anrd/domain/model/recorder"
 	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/ffmpegutil"
 	"github.com/sobadon/anrd/internal/fileutil"
 )
 
 func (c *client) Rec(ctx context.Context, config recorder.Config, targetPgram program.Program) error {
-	file := buildFilepath(config.ArchiveDir, targetPgram)
+	file := c.ArchiveFilePath(config, targetPgram)
 	err := fileutil.MkdirAllIfNotExist(filepath.Dir(file))
 	if err != nil {
 		return errors.Wrap(errutil.ErrInternal, err.Error())
 	}
 
-	// 前後にマージンをとっているため、本来の番組時間だけでなくプラスちょいの間録画する
-	duration := calculateProgramDuration(targetPgram) + 2*config.Margin
+	c.resolveStreamURL(ctx)
+	log.Ctx(ctx).Debug().Msgf("ffmpeg start ... (program = %+v)", targetPgram)
+	return c.checkStreamError(ffmpegutil.Run(ctx, c.RecCommand(config, targetPgram), recOptions(config, recDuration(config, targetPgram))))
+}
+
+// 前の番組の End と次の番組の Start が一致する pgrams を 1 つの ffmpeg で続けて録画し、番組毎のファイルに分割する
+// 分割後のファイルは Rec と同じく前後にマージンを含み、ArchiveFilePath に保存される
+// ffmpeg が途中で終了すれば、録画できた長さを ffprobe で調べ、End まで収まっている番組だけを分割する
+// 分割に失敗すれば、分割前のファイルを残しておく
+func (c *client) RecContinuous(ctx context.Context, config recorder.Config, pgrams []program.Program) (int, error) {
+	if len(pgrams) == 0 {
+		return 0, nil
+	}
+	capture := c.captureFilePath(config, pgrams)
+	err := fileutil.MkdirAllIfNotExist(filepath.Dir(capture))
+	if err != nil {
+		return 0, errors.Wrap(errutil.ErrInternal, err.Error())
+	}
 
-	cmd := exec.Command("ffmpeg",
+	c.resolveStreamURL(ctx)
+	log.Ctx(ctx).Debug().Msgf("ffmpeg start continuous capture ... (programs = %d, file = %s)", len(pgrams), capture)
+	recErr := c.checkStreamError(ffmpegutil.Run(ctx, c.captureCommand(config, pgrams), recOptions(config, captureDuration(config, pgrams))))
+	covered := len(pgrams)
+	if recErr != nil {
+		probe, err := c.Probe(ctx, capture)
+		if err != nil {
+			log.Ctx(ctx).Warn().Msgf("failed to probe capture file: %+v", err)
+			return 0, recErr
+		}
+		covered = coveredPrograms(config, pgrams, probe.Duration)
+		log.Ctx(ctx).Warn().Msgf("continuous capture stopped, split recorded programs (duration = %s, programs = %d/%d)", probe.Duration, covered, len(pgrams))
+	}
+
+	for i, pgram := range pgrams[:covered] {
+		err := fileutil.MkdirAllIfNotExist(filepath.Dir(c.ArchiveFilePath(config, pgram)))
+		if err != nil {
+			return i, errors.Wrap(errutil.ErrInternal, err.Error())
+		}
+		err = ffmpegutil.Run(ctx, c.splitCommand(config, capture, pgrams[0], pgram), ffmpegutil.Options{})
+		if err != nil {
+			return i, err
+		}
+	}
+
+	// 収まっていない番組は呼び出し元で録画しなおすので、途中で終わっていても残さない
+	err = os.Remove(capture)
+	if err != nil {
+		// 分割は済んでいるので録画としては成功
+		log.Ctx(ctx).Warn().Msgf("failed to remove capture file: %+v", err)
+	}
+	return covered, recErr
+}
+
+// 先頭から duration だけ録画できた capture に、End まで収まっている先頭からの番組数
+// 後ろのマージンは欠けていてもよい
+func coveredPrograms(config recorder.Config, pgrams []program.Program, duration time.Duration) int {
+	for i, pgram := range pgrams {
+		// capture は pgrams[0].Start - Margin から始まる
+		if pgram.End.Sub(pgrams[0].Start)+config.Margin > duration {
+			return i
+		}
+	}
+	return len(pgrams)
+}
+
+func (c *client) RecPart(ctx context.Context, config recorder.Config, pgram program.Program, index int, duration time.Duration) error {
+	file := c.partFilePath(config, pgram, index)
+	err := fileutil.MkdirAllIfNotExist(filepath.Dir(file))
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+
+	// 4xx で失敗した後の再開では別の URL になっていることがある
+	c.resolveStreamURL(ctx)
+	log.Ctx(ctx).Debug().Msgf("ffmpeg start ... (program = %+v, part = %d, duration = %s)", pgram, index, duration)
+	return c.checkStreamError(ffmpegutil.Run(ctx, c.partCommand(config, pgram, index, duration), recOptions(config, duration)))
+}
+
+func (c *client) ConcatParts(ctx context.Context, config recorder.Config, pgram program.Program, parts []recorder.Part) error {
+	// 失敗した ffmpeg が何も書き込めていないこともある
+	var files []string
+	for _, part := range parts {
+		file := c.partFilePath(config, pgram, part.Index)
+		info, err := os.Stat(file)
+		if err != nil || info.Size() == 0 {
+			log.Ctx(ctx).Warn().Msgf("skip empty part (file = %s)", file)
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return errors.Wrapf(errutil.ErrFfmpeg, "no part is recorded (program = %+v)", pgram)
+	}
+
+	archive := c.ArchiveFilePath(config, pgram)
+	gaps := recorder.Gaps(parts, pgram.End.Add(config.Margin))
+	if len(files) == 1 && len(gaps) == 0 {
+		err := os.Rename(files[0], archive)
+		if err != nil {
+			return errors.Wrap(errutil.ErrInternal, err.Error())
+		}
+		return nil
+	}
+
+	list := archive + ".parts.txt"
+	err := os.WriteFile(list, []byte(concatList(files)), 0644)
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	defer os.Remove(list)
+
+	log.Ctx(ctx).Info().Msgf("concat parts (program = %+v, parts = %d, gaps = %s)", pgram, len(files), gapsMetadata(gaps))
+	err = ffmpegutil.Run(ctx, c.concatCommand(list, gaps, archive), ffmpegutil.Options{})
+	if err != nil {
+		// 部分ファイルは残しておく
+		return err
+	}
+
+	for _, file := range files {
+		err := os.Remove(file)
+		if err != nil {
+			log.Ctx(ctx).Warn().Msgf("failed to remove part: %+v", err)
+		}
+	}
+	return nil
+}
+
+// 録画する ffmpeg は止まったものや長さ duration の録画が終わらないものを kill する
+func recOptions(config recorder.Config, duration time.Duration) ffmpegutil.Options {
+	return ffmpegutil.Options{
+		StallTimeout: config.StallTimeout,
+		Timeout:      config.BroadcastTimeout(duration),
+	}
+}
+
+func (c *client) RecCommand(config recorder.Config, pgram program.Program) []string {
+	duration := recDuration(config, pgram)
+
+	return []string{"ffmpeg",
 		"-y",
-		"-loglevel", "warning", // とりあえず決め打ち
-		"-i", c.streamURL.String(),
+		"-loglevel", c.ffmpegLoglevel,
+		"-i", c.primaryStreamURL().String(),
 		"-t", strconv.Itoa(int(duration.Seconds())),
 		"-vcodec", "copy",
 		"-acodec", "copy",
-		file,
-	)
+		c.ArchiveFilePath(config, pgram),
+	}
+}
 
-	// https://github.com/rs/zerolog/issues/398
-	// log.Level(zerolog.InfoLevel).With().Logger() などとしても
-	// 出力されるログに loglevel が含まれない
-	cmd.Stdout = log.Ctx(ctx).With().Str("level", zerolog.LevelInfoValue).Logger()
-	cmd.Stderr = log.Ctx(ctx).With().Str("level", zerolog.LevelWarnValue).Logger()
+// pgrams[0].Start - Margin から pgrams[len(pgrams)-1].End + Margin まで録画する
+func (c *client) captureCommand(config recorder.Config, pgrams []program.Program) []string {
+	duration := captureDuration(config, pgrams)
 
-	log.Ctx(ctx).Debug().Msgf("ffmpeg start ... (program = %+v)", targetPgram)
-	log.Ctx(ctx).Debug().Msg(cmd.String())
-	err = cmd.Start()
-	if err != nil {
-		return errors.Wrap(errutil.ErrFfmpeg, err.Error())
+	return []string{"ffmpeg",
+		"-y",
+		"-loglevel", c.ffmpegLoglevel,
+		"-i", c.primaryStreamURL().String(),
+		"-t", strconv.Itoa(int(duration.Seconds())),
+		"-vcodec", "copy",
+		"-acodec", "copy",
+		c.captureFilePath(config, pgrams),
 	}
+}
 
-	err = cmd.Wait()
-	if err != nil {
-		return errors.Wrap(errutil.ErrFfmpeg, err.Error())
+// first から始まる capture から、pgram の部分を前後にマージンを付けて切り出す
+// capture は first.Start - Margin から始まっているので、pgram.Start - Margin は先頭から pgram.Start - first.Start の位置
+func (c *client) splitCommand(config recorder.Config, capture string, first program.Program, pgram program.Program) []string {
+	offset := pgram.Start.Sub(first.Start)
+	duration := calculateProgramDuration(pgram) + 2*config.Margin
+
+	return []string{"ffmpeg",
+		"-y",
+		"-loglevel", c.ffmpegLoglevel,
+		"-ss", strconv.Itoa(int(offset.Seconds())),
+		"-i", capture,
+		"-t", strconv.Itoa(int(duration.Seconds())),
+		"-vcodec", "copy",
+		"-acodec", "copy",
+		c.ArchiveFilePath(config, pgram),
 	}
+}
 
-	return nil
+func (c *client) partCommand(config recorder.Config, pgram program.Program, index int, duration time.Duration) []string {
+	return []string{"ffmpeg",
+		"-y",
+		"-loglevel", c.ffmpegLoglevel,
+		"-i", c.primaryStreamURL().String(),
+		"-t", strconv.Itoa(int(duration.Seconds())),
+		"-vcodec", "copy",
+		"-acodec", "copy",
+		c.partFilePath(config, pgram, index),
+	}
+}
+
+// 欠落は comment に記録する
+func (c *client) concatCommand(list string, gaps []recorder.Gap, output string) []string {
+	args := []string{"ffmpeg",
+		"-y",
+		"-loglevel", c.ffmpegLoglevel,
+		"-f", "concat",
+		"-safe", "0",
+		"-i", list,
+		"-c", "copy",
+	}
+	if len(gaps) != 0 {
+		args = append(args, "-metadata", "comment="+gapsMetadata(gaps))
+	}
+	return append(args, output)
+}
+
+// ffmpeg の concat demuxer に渡すファイル一覧
+func concatList(files []string) string {
+	var b strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&b, "file '%s'\n", strings.ReplaceAll(file, "'", `'\''`))
+	}
+	return b.String()
+}
+
+// 例: gaps: 2022-08-17T23:20:05+09:00/2022-08-17T23:20:12+09:00 (7s)
+func gapsMetadata(gaps []recorder.Gap) string {
+	var s []string
+	for _, gap := range gaps {
+		s = append(s, fmt.Sprintf("%s/%s (%s)", gap.Start.Format(time.RFC3339), gap.End.Format(time.RFC3339), gap.Duration().Round(time.Second)))
+	}
+	return "gaps: " + strings.Join(s, ", ")
+}
+
+// 録画を再開するたびに分かれるファイル
+func (c *client) partFilePath(config recorder.Config, pgram program.Program, index int) string {
+	return fmt.Sprintf("%s.part%d.ts", strings.TrimSuffix(c.ArchiveFilePath(config, pgram), ".ts"), index)
+}
+
+// 分割前のファイル
+// 隠しファイルにしておき、フィードなどに載らないようにする
+func (c *client) captureFilePath(config recorder.Config, pgrams []program.Program) string {
+	first, last := pgrams[0], pgrams[len(pgrams)-1]
+	return filepath.Join(
+		config.ArchiveDir,
+		program.StationAgqr.String(),
+		first.Start.Format("2006-01-02"),
+		fmt.Sprintf(".capture_%s_%s.ts", first.Start.Format("2006-01-02_1504"), last.End.Format("2006-01-02_1504")))
+}
+
+func (c *client) ArchiveFilePath(config recorder.Config, pgram program.Program) string {
+	return buildFilepath(config.ArchiveDir, pgram)
 }
 
 func buildFilepath(basePath string, pgram program.Program) string {
@@ -66,6 +281,16 @@ func buildFilepath(basePath string, pgram program.Program) string {
 		fmt.Sprintf("%s_%s.ts", pgram.Start.Format("2006-01-02_1504"), fileutil.SanitizeReplaceName(pgram.Title)))
 }
 
+// 前後にマージンをとっているため、本来の番組時間だけでなくプラスちょいの間録画する
+func recDuration(config recorder.Config, pgram program.Program) time.Duration {
+	return calculateProgramDuration(pgram) + 2*config.Margin
+}
+
+func captureDuration(config recorder.Config, pgrams []program.Program) time.Duration {
+	first, last := pgrams[0], pgrams[len(pgrams)-1]
+	return last.End.Sub(first.Start) + 2*config.Margin
+}
+
 func calculateProgramDuration(pgram program.Program) time.Duration {
 	return pgram.End.Sub(pgram.Start)
 }
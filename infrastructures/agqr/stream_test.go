@@ -0,0 +1,200 @@
+package agqr
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+func Test_client_RecContinuous_commands(t *testing.T) {
+	streamURL, _ := url.Parse("https://stream.test/agqr.m3u8")
+	c := &client{streamURL: streamURL, ffmpegLoglevel: "warning"}
+	config := recorder.Config{ArchiveDir: "/archive", Margin: 1 * time.Minute}
+
+	pgramA := program.Program{
+		Station: program.StationAgqr,
+		Title:   "鷲崎健のヨルナイト×ヨルナイト",
+		Start:   time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:     time.Date(2022, 8, 17, 23, 30, 0, 0, timeutil.LocationJST()),
+	}
+	pgramB := program.Program{
+		Station: program.StationAgqr,
+		Title:   "ハチャメチャ☆ライブ",
+		Start:   time.Date(2022, 8, 17, 23, 30, 0, 0, timeutil.LocationJST()),
+		End:     time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+	}
+	pgrams := []program.Program{pgramA, pgramB}
+	capture := "/archive/agqr/2022-08-17/.capture_2022-08-17_2300_2022-08-18_0000.ts"
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{
+			name: "最初の番組の Start - Margin から最後の番組の End + Margin まで録画する",
+			got:  c.captureCommand(config, pgrams),
+			want: []string{"ffmpeg", "-y", "-loglevel", "warning", "-i", "https://stream.test/agqr.m3u8", "-t", "3720", "-vcodec", "copy", "-acodec", "copy", capture},
+		},
+		{
+			name: "最初の番組は先頭から",
+			got:  c.splitCommand(config, capture, pgramA, pgramA),
+			want: []string{"ffmpeg", "-y", "-loglevel", "warning", "-ss", "0", "-i", capture, "-t", "1920", "-vcodec", "copy", "-acodec", "copy", "/archive/agqr/2022-08-17/2022-08-17_2300_鷲崎健のヨルナイト×ヨルナイト.ts"},
+		},
+		{
+			name: "続く番組は前の番組とマージンが重なる",
+			got:  c.splitCommand(config, capture, pgramA, pgramB),
+			want: []string{"ffmpeg", "-y", "-loglevel", "warning", "-ss", "1800", "-i", capture, "-t", "1920", "-vcodec", "copy", "-acodec", "copy", "/archive/agqr/2022-08-17/2022-08-17_2330_ハチャメチャ☆ライブ.ts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.got); diff != "" {
+				t.Errorf("command mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_coveredPrograms(t *testing.T) {
+	config := recorder.Config{Margin: 1 * time.Minute}
+	start := time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST())
+	pgrams := []program.Program{
+		{Start: start, End: start.Add(30 * time.Minute)},
+		{Start: start.Add(30 * time.Minute), End: start.Add(60 * time.Minute)},
+	}
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		want     int
+	}{
+		{
+			name:     "すべて録画できた",
+			duration: 62 * time.Minute,
+			want:     2,
+		},
+		{
+			name:     "最後の番組の後ろのマージンは欠けていてもよい",
+			duration: 61 * time.Minute,
+			want:     2,
+		},
+		{
+			name:     "2 つ目の番組の途中で止まった",
+			duration: 55 * time.Minute,
+			want:     1,
+		},
+		{
+			name:     "最初の番組の途中で止まった",
+			duration: 30 * time.Minute,
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coveredPrograms(config, pgrams, tt.duration); got != tt.want {
+				t.Errorf("coveredPrograms() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_client_concatCommand(t *testing.T) {
+	c := &client{ffmpegLoglevel: "warning"}
+	gaps := recorder.Gaps([]recorder.Part{
+		{Index: 0, Start: time.Date(2022, 8, 17, 22, 59, 0, 0, timeutil.LocationJST()), End: time.Date(2022, 8, 17, 23, 20, 5, 0, timeutil.LocationJST())},
+		{Index: 1, Start: time.Date(2022, 8, 17, 23, 20, 12, 0, timeutil.LocationJST()), End: time.Date(2022, 8, 17, 23, 30, 40, 0, timeutil.LocationJST())},
+	}, time.Date(2022, 8, 17, 23, 31, 0, 0, timeutil.LocationJST()))
+
+	got := c.concatCommand("/archive/file.ts.parts.txt", gaps, "/archive/file.ts")
+	want := []string{"ffmpeg", "-y", "-loglevel", "warning", "-f", "concat", "-safe", "0", "-i", "/archive/file.ts.parts.txt", "-c", "copy",
+		"-metadata", "comment=gaps: 2022-08-17T23:20:05+09:00/2022-08-17T23:20:12+09:00 (7s), 2022-08-17T23:30:40+09:00/2022-08-17T23:31:00+09:00 (20s)",
+		"/archive/file.ts"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("client.concatCommand() mismatch (-want +got):\n%s", diff)
+	}
+
+	gotList := concatList([]string{"/archive/a.part0.ts", "/archive/it's.part1.ts"})
+	wantList := "file '/archive/a.part0.ts'\nfile '/archive/it'\\''s.part1.ts'\n"
+	if gotList != wantList {
+		t.Errorf("concatList() = %q, want %q", gotList, wantList)
+	}
+}
+
+func Test_client_ConcatParts_single(t *testing.T) {
+	c := &client{ffmpegLoglevel: "warning"}
+	config := recorder.Config{ArchiveDir: t.TempDir()}
+	pgram := program.Program{
+		Station: program.StationAgqr,
+		Title:   "鷲崎健のヨルナイト×ヨルナイト",
+		Start:   time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:     time.Date(2022, 8, 17, 23, 30, 0, 0, timeutil.LocationJST()),
+	}
+	part := c.partFilePath(config, pgram, 0)
+	if err := os.MkdirAll(filepath.Dir(part), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(part, []byte("ts"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 失敗せずに録画できたものは結合せず、そのまま ArchiveFilePath にする
+	err := c.ConcatParts(context.Background(), config, pgram, []recorder.Part{{Index: 0, Start: pgram.Start, End: pgram.End}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(c.ArchiveFilePath(config, pgram))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "ts" {
+		t.Errorf("archive = %q, want %q", got, "ts")
+	}
+	if _, err := os.Stat(part); !os.IsNotExist(err) {
+		t.Errorf("part is not removed: %v", err)
+	}
+}
+
+func Test_parsePackets(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  recorder.Probe
+	}{
+		{
+			name:  "途切れていなければ最初のパケットから最後のパケットの終わりまで",
+			input: "1.400000,0.021333\n1.421333,0.021333\n1.442666,0.021334\n",
+			want:  recorder.Probe{Duration: 64 * time.Millisecond},
+		},
+		{
+			name:  "1 秒以上空いていれば欠落とする",
+			input: "0.000000,0.500000\n0.500000,0.500000\n3.000000,0.500000\nN/A,N/A\n3.500000,0.500000\n",
+			want:  recorder.Probe{Duration: 4 * time.Second, Discontinuities: 1},
+		},
+		{
+			name:  "パケットがなければ長さ 0",
+			input: "",
+			want:  recorder.Probe{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePackets(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parsePackets() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// 実際には録画せず、何をするはずだったかを記録する repository.Station
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/date"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+// 録画するはずだったもの
+// plan report には 1 行に 1 つ JSON で書き出す
+type Plan struct {
+	PlannedAt  time.Time `json:"planned_at"`
+	UUID       string    `json:"uuid"`
+	Station    string    `json:"station"`
+	Title      string    `json:"title"`
+	Episode    string    `json:"episode,omitempty"`
+	StreamType string    `json:"stream_type"`
+
+	// 録画の仕方
+	Mode string `json:"mode"`
+	// continuous でまとめて録画する番組の UUID（Start の昇順）
+	Continuous []string `json:"continuous,omitempty"`
+	// redundant で配信元毎に録画するファイルのパス
+	SourcePaths []string `json:"source_paths,omitempty"`
+
+	// 録画する期間（マージン込み）
+	// ondemand は長さがわからないので RecEnd は nil
+	RecStart time.Time  `json:"rec_start"`
+	RecEnd   *time.Time `json:"rec_end,omitempty"`
+
+	OutputPath string `json:"output_path"`
+	// 番組を単独で録画するときのコマンド
+	Command []string `json:"command"`
+}
+
+type station struct {
+	station repository.Station
+
+	mu sync.Mutex
+	// nil であればログ出力のみ
+	report io.Writer
+
+	now func() time.Time
+}
+
+// Rec と Plan 以外は s をそのまま呼び出す
+// report が nil でなければ、Rec と Plan のたびに Plan を書き出す
+func New(s repository.Station, report io.Writer) repository.PlanStation {
+	return &station{
+		station: s,
+		report:  report,
+		now:     time.Now,
+	}
+}
+
+func (s *station) GetPrograms(ctx context.Context, d date.Date) ([]program.Program, error) {
+	return s.station.GetPrograms(ctx, d)
+}
+
+func (s *station) ArchiveFilePath(config recorder.Config, pgram program.Program) string {
+	return s.station.ArchiveFilePath(config, pgram)
+}
+
+func (s *station) RecCommand(config recorder.Config, pgram program.Program) []string {
+	return s.station.RecCommand(config, pgram)
+}
+
+func (s *station) Unwrap() repository.Station {
+	return s.station
+}
+
+// 録画はせず、Plan をログと report に出力する
+func (s *station) Rec(ctx context.Context, config recorder.Config, targetPgram program.Program) error {
+	return s.Plan(ctx, config, recorder.ModeSingle, []program.Program{targetPgram})
+}
+
+// 録画はせず、pgrams それぞれの Plan をログと report に出力する
+func (s *station) Plan(ctx context.Context, config recorder.Config, mode recorder.Mode, pgrams []program.Program) error {
+	var continuous []string
+	if mode == recorder.ModeContinuous {
+		for _, pgram := range pgrams {
+			continuous = append(continuous, pgram.UUID)
+		}
+	}
+
+	for _, pgram := range pgrams {
+		plan := s.plan(config, mode, pgram)
+		plan.Continuous = continuous
+
+		window := plan.RecStart.Format(time.RFC3339) + " - "
+		if plan.RecEnd != nil {
+			window += plan.RecEnd.Format(time.RFC3339)
+		}
+		log.Ctx(ctx).Info().Msgf("dry-run: would rec %s %s %s (mode = %s, window = %s, output = %s): %s",
+			plan.Station, plan.Title, plan.Episode, plan.Mode, window, plan.OutputPath, quoteCommand(plan.Command))
+
+		err := s.write(plan)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *station) write(plan Plan) error {
+	if s.report == nil {
+		return nil
+	}
+	b, err := json.Marshal(plan)
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.report.Write(append(b, '\n'))
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	return nil
+}
+
+func (s *station) plan(config recorder.Config, mode recorder.Mode, pgram program.Program) Plan {
+	now := s.now()
+	plan := Plan{
+		PlannedAt:  now,
+		UUID:       pgram.UUID,
+		Station:    pgram.Station.String(),
+		Title:      pgram.Title,
+		Episode:    pgram.Episode,
+		StreamType: pgram.StreamType.String(),
+		Mode:       mode.String(),
+		RecStart:   now,
+		OutputPath: s.station.ArchiveFilePath(config, pgram),
+		Command:    s.station.RecCommand(config, pgram),
+	}
+	if pgram.StreamType == program.StreamTypeBroadcast {
+		recEnd := pgram.End.Add(config.Margin)
+		plan.RecStart = pgram.Start.Add(-config.Margin)
+		plan.RecEnd = &recEnd
+	}
+	if redundant, ok := s.station.(repository.RedundantStation); ok && mode == recorder.ModeRedundant {
+		for source := 0; source < redundant.Sources(); source++ {
+			plan.SourcePaths = append(plan.SourcePaths, redundant.SourceFilePath(config, pgram, source))
+		}
+	}
+	return plan
+}
+
+// ログに出力するため、空白などを含む引数を quote してつなげる
+func quoteCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\r\n'\"") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,234 @@
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func Test_station_Rec(t *testing.T) {
+	now := time.Date(2022, 8, 17, 22, 58, 0, 0, timeutil.LocationJST())
+	config := recorder.Config{ArchiveDir: "/archive", Margin: 1 * time.Minute}
+	recEnd := time.Date(2022, 8, 18, 0, 1, 0, 0, timeutil.LocationJST())
+
+	tests := []struct {
+		name  string
+		pgram program.Program
+		want  Plan
+	}{
+		{
+			name: "broadcast は番組の開始・終了日時にマージンを加えた期間",
+			pgram: program.Program{
+				UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+				Station:    program.StationAgqr,
+				Title:      "鷲崎健のヨルナイト×ヨルナイト",
+				Start:      time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+				End:        time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+				StreamType: program.StreamTypeBroadcast,
+			},
+			want: Plan{
+				PlannedAt:  now,
+				UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+				Station:    "agqr",
+				Title:      "鷲崎健のヨルナイト×ヨルナイト",
+				StreamType: "broadcast",
+				Mode:       "single",
+				RecStart:   time.Date(2022, 8, 17, 22, 59, 0, 0, timeutil.LocationJST()),
+				RecEnd:     &recEnd,
+				OutputPath: "/archive/agqr/test.ts",
+				Command:    []string{"ffmpeg", "-i", "https://agqr.test/stream.m3u8", "/archive/agqr/test.ts"},
+			},
+		},
+		{
+			name: "ondemand は終了日時がわからない",
+			pgram: program.Program{
+				UUID:        "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+				Station:     program.StationOnsen,
+				Title:       "セブン-イレブン presents 佐倉としたい大西",
+				Episode:     "第334回",
+				Start:       time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+				StreamType:  program.StreamTypeOndemand,
+				PlaylistURL: "https://onsen.test/playlist.m3u8",
+			},
+			want: Plan{
+				PlannedAt:  now,
+				UUID:       "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+				Station:    "onsen",
+				Title:      "セブン-イレブン presents 佐倉としたい大西",
+				Episode:    "第334回",
+				StreamType: "ondemand",
+				Mode:       "single",
+				RecStart:   now,
+				RecEnd:     nil,
+				OutputPath: "/archive/agqr/test.ts",
+				Command:    []string{"ffmpeg", "-i", "https://agqr.test/stream.m3u8", "/archive/agqr/test.ts"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Rec は呼び出されない
+			mockStation := mock_repository.NewMockStation(ctrl)
+			mockStation.EXPECT().ArchiveFilePath(config, tt.pgram).Return("/archive/agqr/test.ts")
+			mockStation.EXPECT().RecCommand(config, tt.pgram).Return([]string{"ffmpeg", "-i", "https://agqr.test/stream.m3u8", "/archive/agqr/test.ts"})
+
+			var report bytes.Buffer
+			s := &station{station: mockStation, report: &report, now: func() time.Time { return now }}
+			err := s.Rec(context.Background(), config, tt.pgram)
+			if err != nil {
+				t.Fatalf("station.Rec() error = %v", err)
+			}
+
+			var got Plan
+			err = json.Unmarshal(report.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("station.Rec() plan mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_station_Plan(t *testing.T) {
+	now := time.Date(2022, 8, 17, 22, 58, 0, 0, timeutil.LocationJST())
+	config := recorder.Config{ArchiveDir: "/archive", Margin: 1 * time.Minute}
+	pgramA := program.Program{
+		UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 17, 23, 30, 0, 0, timeutil.LocationJST()),
+		StreamType: program.StreamTypeBroadcast,
+	}
+	pgramB := program.Program{
+		UUID:       "0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10",
+		Station:    program.StationAgqr,
+		Title:      "ハチャメチャ☆ライブ",
+		Start:      time.Date(2022, 8, 17, 23, 30, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+		StreamType: program.StreamTypeBroadcast,
+	}
+	recStartA := time.Date(2022, 8, 17, 22, 59, 0, 0, timeutil.LocationJST())
+	recEndA := time.Date(2022, 8, 17, 23, 31, 0, 0, timeutil.LocationJST())
+	recStartB := time.Date(2022, 8, 17, 23, 29, 0, 0, timeutil.LocationJST())
+	recEndB := time.Date(2022, 8, 18, 0, 1, 0, 0, timeutil.LocationJST())
+	command := []string{"ffmpeg", "-i", "https://agqr.test/stream.m3u8", "/archive/agqr/test.ts"}
+
+	tests := []struct {
+		name   string
+		mode   recorder.Mode
+		pgrams []program.Program
+		want   []Plan
+	}{
+		{
+			name:   "continuous はまとめて録画する番組を記録する",
+			mode:   recorder.ModeContinuous,
+			pgrams: []program.Program{pgramA, pgramB},
+			want: []Plan{
+				{
+					PlannedAt:  now,
+					UUID:       pgramA.UUID,
+					Station:    "agqr",
+					Title:      pgramA.Title,
+					StreamType: "broadcast",
+					Mode:       "continuous",
+					Continuous: []string{pgramA.UUID, pgramB.UUID},
+					RecStart:   recStartA,
+					RecEnd:     &recEndA,
+					OutputPath: "/archive/agqr/test.ts",
+					Command:    command,
+				},
+				{
+					PlannedAt:  now,
+					UUID:       pgramB.UUID,
+					Station:    "agqr",
+					Title:      pgramB.Title,
+					StreamType: "broadcast",
+					Mode:       "continuous",
+					Continuous: []string{pgramA.UUID, pgramB.UUID},
+					RecStart:   recStartB,
+					RecEnd:     &recEndB,
+					OutputPath: "/archive/agqr/test.ts",
+					Command:    command,
+				},
+			},
+		},
+		{
+			name:   "redundant は配信元毎のファイルを記録する",
+			mode:   recorder.ModeRedundant,
+			pgrams: []program.Program{pgramA},
+			want: []Plan{
+				{
+					PlannedAt:   now,
+					UUID:        pgramA.UUID,
+					Station:     "agqr",
+					Title:       pgramA.Title,
+					StreamType:  "broadcast",
+					Mode:        "redundant",
+					SourcePaths: []string{"/archive/agqr/test.source0.ts", "/archive/agqr/test.source1.ts"},
+					RecStart:    recStartA,
+					RecEnd:      &recEndA,
+					OutputPath:  "/archive/agqr/test.ts",
+					Command:     command,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// 録画に使うメソッドは呼び出されない
+			mockStation := mock_repository.NewMockRedundantStation(ctrl)
+			mockStation.EXPECT().ArchiveFilePath(config, gomock.Any()).Return("/archive/agqr/test.ts").AnyTimes()
+			mockStation.EXPECT().RecCommand(config, gomock.Any()).Return(command).AnyTimes()
+			mockStation.EXPECT().Sources().Return(2).AnyTimes()
+			mockStation.EXPECT().SourceFilePath(config, pgramA, 0).Return("/archive/agqr/test.source0.ts").AnyTimes()
+			mockStation.EXPECT().SourceFilePath(config, pgramA, 1).Return("/archive/agqr/test.source1.ts").AnyTimes()
+
+			var report bytes.Buffer
+			s := &station{station: mockStation, report: &report, now: func() time.Time { return now }}
+			err := s.Plan(context.Background(), config, tt.mode, tt.pgrams)
+			if err != nil {
+				t.Fatalf("station.Plan() error = %v", err)
+			}
+
+			var got []Plan
+			dec := json.NewDecoder(&report)
+			for dec.More() {
+				var plan Plan
+				err := dec.Decode(&plan)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, plan)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("station.Plan() plans mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_quoteCommand(t *testing.T) {
+	got := quoteCommand([]string{"ffmpeg", "-headers", "Referer: https://www.onsen.ag/", "-i", "https://onsen.test/playlist.m3u8"})
+	want := `ffmpeg -headers "Referer: https://www.onsen.ag/" -i https://onsen.test/playlist.m3u8`
+	if got != want {
+		t.Errorf("quoteCommand() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,354 @@
+// データベースを使わない repository.ProgramPersistence
+// プロセスが終了すると消えるので、テストや一時的な利用のためのもの
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+type client struct {
+	mu sync.RWMutex
+	// 登録した順
+	pgrams []program.Program
+	// key は番組の UUID
+	updatedAt map[string]time.Time
+	// key は番組の UUID
+	attempts map[string][]recorder.Attempt
+	// 追加した順
+	recLogs []recorder.RecordingLog
+}
+
+func New() repository.ProgramPersistence {
+	return &client{}
+}
+
+func (c *client) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (c *client) CheckSchema(ctx context.Context) error {
+	return nil
+}
+
+func (c *client) Save(ctx context.Context, pgram program.Program) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 既に番組情報が登録されていれば追加せず、scheduled であれば番組表の変更を反映する
+	for i, stored := range c.pgrams {
+		if stored.Station != pgram.Station || stored.ID != pgram.ID {
+			continue
+		}
+		if stored.Status != program.StatusScheduled ||
+			(stored.Title == pgram.Title && stored.Episode == pgram.Episode && stored.Start.Equal(pgram.Start) && stored.End.Equal(pgram.End)) {
+			return nil
+		}
+		c.pgrams[i].Title = pgram.Title
+		c.pgrams[i].Episode = pgram.Episode
+		c.pgrams[i].Start = pgram.Start
+		c.pgrams[i].End = pgram.End
+		c.touch(stored.UUID)
+		return nil
+	}
+
+	// sqlite と同様に、録画後に設定されるものは登録しない
+	pgram.FilePath = ""
+	pgram.ObjectKey = ""
+	pgram.Protected = false
+	c.pgrams = append(c.pgrams, pgram)
+	c.touch(pgram.UUID)
+	return nil
+}
+
+func (c *client) Upsert(ctx context.Context, pgram program.Program) (program.Program, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, stored := range c.pgrams {
+		if stored.Station == pgram.Station && stored.ID == pgram.ID {
+			pgram.UUID = stored.UUID
+			c.pgrams[i] = pgram
+			c.touch(pgram.UUID)
+			return pgram, false, nil
+		}
+	}
+	if c.index(pgram.UUID) >= 0 {
+		// sqlite の主キー制約と同様
+		return program.Program{}, false, errors.Wrapf(errutil.ErrDatabaseQuery, "duplicate uuid %s", pgram.UUID)
+	}
+	c.pgrams = append(c.pgrams, pgram)
+	c.touch(pgram.UUID)
+	return pgram, true, nil
+}
+
+func (c *client) LoadBroadcastStartIn(ctx context.Context, now time.Time, duration time.Duration) ([]program.Program, error) {
+	after := now.Add(duration)
+	pgrams := c.filter(func(pgram program.Program) bool {
+		return pgram.Status == program.StatusScheduled &&
+			pgram.StreamType == program.StreamTypeBroadcast &&
+			now.Before(pgram.Start) && pgram.Start.Before(after)
+	})
+	if len(pgrams) == 0 {
+		return nil, errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program")
+	}
+	return pgrams, nil
+}
+
+func (c *client) LoadOndemandScheduled(ctx context.Context, limit int) (*[]program.Program, error) {
+	// playlist_url が空であれば何らかの会員限定コンテンツとする
+	pgrams := c.filter(isOndemandScheduled)
+	if len(pgrams) == 0 {
+		return nil, errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program (scheduled ondemand)")
+	}
+	if len(pgrams) > limit {
+		pgrams = pgrams[:limit]
+	}
+	return &pgrams, nil
+}
+
+func (c *client) CountOndemandScheduled(ctx context.Context) (int, error) {
+	return len(c.filter(isOndemandScheduled)), nil
+}
+
+func isOndemandScheduled(pgram program.Program) bool {
+	return pgram.Status == program.StatusScheduled && pgram.PlaylistURL != ""
+}
+
+func (c *client) ChangeStatus(ctx context.Context, pgram program.Program, newStatus program.Status) error {
+	return c.change(pgram.UUID, func(stored *program.Program) {
+		stored.Status = newStatus
+	})
+}
+
+func (c *client) ChangeFilePath(ctx context.Context, pgram program.Program, filePath string) error {
+	return c.change(pgram.UUID, func(stored *program.Program) {
+		stored.FilePath = filePath
+	})
+}
+
+func (c *client) ChangeObjectKey(ctx context.Context, pgram program.Program, objectKey string) error {
+	return c.change(pgram.UUID, func(stored *program.Program) {
+		stored.ObjectKey = objectKey
+	})
+}
+
+func (c *client) ChangeProtected(ctx context.Context, pgram program.Program, protected bool) error {
+	return c.change(pgram.UUID, func(stored *program.Program) {
+		stored.Protected = protected
+	})
+}
+
+func (c *client) LoadDone(ctx context.Context) ([]program.Program, error) {
+	pgrams := c.filter(func(pgram program.Program) bool {
+		return pgram.Status == program.StatusDone
+	})
+	if len(pgrams) == 0 {
+		return nil, errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program (done)")
+	}
+	sortByStart(pgrams)
+	return pgrams, nil
+}
+
+func (c *client) Load(ctx context.Context, filter program.Filter) ([]program.Program, error) {
+	pgrams := c.filter(func(pgram program.Program) bool {
+		if filter.Station != "" && pgram.Station != filter.Station {
+			return false
+		}
+		if filter.Status != "" && pgram.Status != filter.Status {
+			return false
+		}
+		if filter.StreamType != "" && pgram.StreamType != filter.StreamType {
+			return false
+		}
+		if filter.Title != "" && !strings.Contains(pgram.Title, filter.Title) {
+			return false
+		}
+		if !filter.StartFrom.IsZero() && pgram.Start.Before(filter.StartFrom) {
+			return false
+		}
+		if !filter.StartTo.IsZero() && !pgram.Start.Before(filter.StartTo) {
+			return false
+		}
+		return true
+	})
+	if len(pgrams) == 0 {
+		return nil, errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program")
+	}
+
+	sortByStart(pgrams)
+	if filter.Limit > 0 && len(pgrams) > filter.Limit {
+		pgrams = pgrams[:filter.Limit]
+	}
+	return pgrams, nil
+}
+
+func (c *client) LoadByUUID(ctx context.Context, uuid string) (program.Program, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	i := c.index(uuid)
+	if i < 0 {
+		return program.Program{}, errors.Wrapf(errutil.ErrDatabaseNotFoundProgram, "not found program (uuid = %s)", uuid)
+	}
+	return c.pgrams[i], nil
+}
+
+func (c *client) LoadUpdatedAt(ctx context.Context, uuids []string) (map[string]time.Time, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	updatedAt := make(map[string]time.Time, len(uuids))
+	for _, uuid := range uuids {
+		if t, ok := c.updatedAt[uuid]; ok {
+			updatedAt[uuid] = t
+		}
+	}
+	return updatedAt, nil
+}
+
+func (c *client) SaveAttempts(ctx context.Context, pgram program.Program, attempts []recorder.Attempt) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.index(pgram.UUID) < 0 {
+		return errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program")
+	}
+	if c.attempts == nil {
+		c.attempts = map[string][]recorder.Attempt{}
+	}
+	saved := make([]recorder.Attempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		attempt.ProgramUUID = pgram.UUID
+		saved = append(saved, attempt)
+	}
+	sort.SliceStable(saved, func(i, j int) bool {
+		return saved[i].Source < saved[j].Source
+	})
+	c.attempts[pgram.UUID] = saved
+	return nil
+}
+
+func (c *client) LoadAttempts(ctx context.Context, uuid string) ([]recorder.Attempt, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.attempts[uuid]) == 0 {
+		return nil, nil
+	}
+	return append([]recorder.Attempt(nil), c.attempts[uuid]...), nil
+}
+
+func (c *client) SaveRecordingLog(ctx context.Context, pgram program.Program, recLog recorder.RecordingLog) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.index(pgram.UUID) < 0 {
+		return errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program")
+	}
+	recLog.ProgramUUID = pgram.UUID
+	c.recLogs = append(c.recLogs, recLog)
+	return nil
+}
+
+func (c *client) LoadRecordingLogs(ctx context.Context, uuid string) ([]recorder.RecordingLog, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var recLogs []recorder.RecordingLog
+	for _, recLog := range c.recLogs {
+		if recLog.ProgramUUID == uuid {
+			recLogs = append(recLogs, recLog)
+		}
+	}
+	sortByStartedAt(recLogs)
+	return recLogs, nil
+}
+
+func (c *client) DeleteRecordingLogsBefore(ctx context.Context, before time.Time) ([]recorder.RecordingLog, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var kept, deleted []recorder.RecordingLog
+	for _, recLog := range c.recLogs {
+		if recLog.StartedAt.Before(before) {
+			deleted = append(deleted, recLog)
+			continue
+		}
+		kept = append(kept, recLog)
+	}
+	c.recLogs = kept
+	sortByStartedAt(deleted)
+	return deleted, nil
+}
+
+// match する番組のコピーを登録した順に返す
+func (c *client) filter(match func(pgram program.Program) bool) []program.Program {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var pgrams []program.Program
+	for _, pgram := range c.pgrams {
+		if match(pgram) {
+			pgrams = append(pgrams, pgram)
+		}
+	}
+	return pgrams
+}
+
+// uuid の番組を変更する
+// 存在しなければ ErrDatabaseNotFoundProgram
+func (c *client) change(uuid string, change func(stored *program.Program)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.index(uuid)
+	if i < 0 {
+		return errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program")
+	}
+	change(&c.pgrams[i])
+	c.touch(uuid)
+	return nil
+}
+
+// uuid の番組を変更した日時を記録する
+// 呼び出し元で mu をロックしておくこと
+func (c *client) touch(uuid string) {
+	if c.updatedAt == nil {
+		c.updatedAt = map[string]time.Time{}
+	}
+	c.updatedAt[uuid] = time.Now()
+}
+
+// 呼び出し元で mu をロックしておくこと
+// 存在しなければ -1
+func (c *client) index(uuid string) int {
+	for i, pgram := range c.pgrams {
+		if pgram.UUID == uuid {
+			return i
+		}
+	}
+	return -1
+}
+
+// start が同じものは登録した順のまま
+func sortByStart(pgrams []program.Program) {
+	sort.SliceStable(pgrams, func(i, j int) bool {
+		return pgrams[i].Start.Before(pgrams[j].Start)
+	})
+}
+
+func sortByStartedAt(recLogs []recorder.RecordingLog) {
+	sort.SliceStable(recLogs, func(i, j int) bool {
+		return recLogs[i].StartedAt.Before(recLogs[j].StartedAt)
+	})
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/testutil/persistencetest"
+)
+
+func TestConformance(t *testing.T) {
+	persistencetest.Run(t, func(t *testing.T) repository.ProgramPersistence {
+		return New()
+	})
+}
+
+func Test_client_concurrent(t *testing.T) {
+	c := New()
+	ctx := context.Background()
+	start := time.Date(2022, 8, 17, 23, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pgram := program.Program{
+				UUID:        fmt.Sprintf("uuid-%d", i),
+				ID:          i,
+				Station:     program.StationOnsen,
+				Title:       "番組",
+				Start:       start.Add(time.Duration(i) * time.Hour),
+				Status:      program.StatusScheduled,
+				StreamType:  program.StreamTypeOndemand,
+				PlaylistURL: "https://onsen.test/playlist.m3u8",
+			}
+			err := c.Save(ctx, pgram)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = c.ChangeStatus(ctx, pgram, program.StatusDone)
+			if err != nil {
+				t.Error(err)
+			}
+			_, _ = c.LoadOndemandScheduled(ctx, 10)
+		}(i)
+	}
+	wg.Wait()
+
+	got, err := c.LoadDone(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 50 {
+		t.Errorf("LoadDone() returns %d programs, want 50", len(got))
+	}
+}
@@ -4,14 +4,44 @@ import (
 	"net/http"
 
 	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/clock"
 )
 
+type Config struct {
+	// 番組一覧 API の URL
+	ProgramURL string
+
+	// ffmpeg の -loglevel
+	// 空であれば warning
+	FfmpegLoglevel string
+
+	// 配信日（MM/DD）の年を推測するために使う
+	// nil であれば実際の時刻
+	Clock clock.Clock
+}
+
 type client struct {
-	httpClient *http.Client
+	httpClient     *http.Client
+	programURL     string
+	ffmpegLoglevel string
+	clock          clock.Clock
 }
 
-func New() repository.Station {
+func New(config Config) repository.Station {
+	ffmpegLoglevel := config.FfmpegLoglevel
+	if ffmpegLoglevel == "" {
+		ffmpegLoglevel = "warning"
+	}
+
+	clk := config.Clock
+	if clk == nil {
+		clk = clock.New()
+	}
+
 	return &client{
-		httpClient: &http.Client{},
+		httpClient:     &http.Client{},
+		programURL:     config.ProgramURL,
+		ffmpegLoglevel: ffmpegLoglevel,
+		clock:          clk,
 	}
 }
@@ -30,9 +30,10 @@ type onsenProgram struct {
 	// セブン-イレブン presents 佐倉としたい大西
 	Title string `json:"title"`
 
-	// Image             struct {
-	// 	URL string `json:"url"`
-	// } `json:"image"`
+	Image struct {
+		// https://d3bzklg4lms4gh.cloudfront.net/program_info/image/default/production/.../image?v=1661479552
+		URL string `json:"url"`
+	} `json:"image"`
 
 	// New               bool     `json:"new"`
 	// List              bool     `json:"list"`
@@ -42,11 +43,7 @@ type onsenProgram struct {
 	// Copyright         string   `json:"copyright"`
 	// SponsorName       string   `json:"sponsor_name"`
 	// Updated           string   `json:"updated"`
-	// Performers        []struct {
-	// 	ID        int    `json:"id"`
-	// 	Name      string `json:"name"`
-	// 	AllowLike bool   `json:"allow_like"`
-	// } `json:"performers"`
+	Performers []Performer `json:"performers"`
 	// RelatedLinks []struct {
 	// 	LinkURL string `json:"link_url"`
 	// 	Image   string `json:"image"`
@@ -82,6 +79,15 @@ type onsenProgram struct {
 	Contents []Content `json:"contents"`
 }
 
+type Performer struct {
+	// ID        int    `json:"id"`
+
+	// 佐倉綾音
+	Name string `json:"name"`
+
+	// AllowLike bool   `json:"allow_like"`
+}
+
 type Content struct {
 	// 11134
 	ID int `json:"id"`
@@ -140,8 +146,7 @@ type Content struct {
 
 func (c *client) GetPrograms(ctx context.Context, _ date.Date) ([]program.Program, error) {
 	log.Ctx(ctx).Debug().Msg("get onsen program ...")
-	const programURL = "https://www.onsen.ag/web_api/programs"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, programURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.programURL, nil)
 	if err != nil {
 		return nil, errors.Wrap(errutil.ErrInternal, err.Error())
 	}
@@ -161,9 +166,10 @@ func (c *client) GetPrograms(ctx context.Context, _ date.Date) ([]program.Progra
 		return nil, err
 	}
 
+	now := c.clock.Now().In(timeutil.LocationJST())
 	var pgrams []program.Program
 	for _, onsenPgram := range onsenPgrams {
-		pgrams_, err := onsenProgramToPrograms(onsenPgram)
+		pgrams_, err := onsenProgramToPrograms(now, onsenPgram)
 		if err != nil {
 			return nil, err
 		}
@@ -185,8 +191,8 @@ func decodeToOnsenPrograms(input io.Reader) ([]onsenProgram, error) {
 }
 
 // onsenProgram.Contents -> []program.Program
-func onsenProgramToPrograms(onsenPgram onsenProgram) ([]program.Program, error) {
-	now := time.Now().In(timeutil.LocationJST())
+// now は番組表の取得日時で、配信日の年の推測に使う
+func onsenProgramToPrograms(now time.Time, onsenPgram onsenProgram) ([]program.Program, error) {
 	var pgrams []program.Program
 	for _, content := range onsenPgram.Contents {
 		// DeliveryDate が「注目」の意味を表わす空文字（null）になることがある
@@ -203,6 +209,10 @@ func onsenProgramToPrograms(onsenPgram onsenProgram) ([]program.Program, error)
 		}
 
 		pgram := program.NewProgramOndemand(content.ID, program.StationOnsen, onsenPgram.Title, content.Title, contentDate, content.StreamingURL)
+		pgram.ImageURL = onsenPgram.Image.URL
+		for _, performer := range onsenPgram.Performers {
+			pgram.Personalities = append(pgram.Personalities, performer.Name)
+		}
 		pgrams = append(pgrams, pgram)
 	}
 	return pgrams, nil
@@ -226,7 +236,8 @@ func buildDateFromMMDD(now time.Time, mmdd string) (date.Date, error) {
 	sameYearDate := date.New(now.Year(), time.Month(month), day)
 	yearDuration := 365 * 24 * time.Hour
 	diff := now.Sub(time.Time(sameYearDate))
-	if 0 < diff && diff < yearDuration {
+	// 当日の配信回（diff == 0）も同じ年
+	if 0 <= diff && diff < yearDuration {
 		return sameYearDate, nil
 	}
 
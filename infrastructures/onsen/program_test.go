@@ -10,6 +10,7 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/sobadon/anrd/domain/model/date"
 	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/clock"
 	"github.com/sobadon/anrd/internal/timeutil"
 	"gopkg.in/dnaeon/go-vcr.v3/recorder"
 )
@@ -58,6 +59,7 @@ func Test_buildDateFromMMDD(t *testing.T) {
 
 func Test_onsenProgramToPrograms(t *testing.T) {
 	type args struct {
+		now        time.Time
 		onsenPgram onsenProgram
 	}
 	tests := []struct {
@@ -69,9 +71,11 @@ func Test_onsenProgramToPrograms(t *testing.T) {
 		{
 			name: "ok",
 			args: args{
+				now: time.Date(2022, 8, 24, 12, 0, 0, 0, timeutil.LocationJST()),
 				onsenPgram: onsenProgram{
-					ID:    17,
-					Title: "セブン-イレブン presents 佐倉としたい大西",
+					ID:         17,
+					Title:      "セブン-イレブン presents 佐倉としたい大西",
+					Performers: []Performer{{Name: "佐倉綾音"}, {Name: "大西沙織"}},
 					Contents: []Content{
 						{
 							ID:           11134,
@@ -100,26 +104,91 @@ func Test_onsenProgramToPrograms(t *testing.T) {
 			},
 			want: []program.Program{
 				{
-					ID:          11134,
+					ID:            11134,
+					Station:       program.StationOnsen,
+					Title:         "セブン-イレブン presents 佐倉としたい大西",
+					Episode:       "第334回",
+					Start:         time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+					End:           time.Time{},
+					Status:        program.StatusScheduled,
+					StreamType:    program.StreamTypeOndemand,
+					PlaylistURL:   "https://onsen.test/playlist.m3u8",
+					Personalities: []string{"佐倉綾音", "大西沙織"},
+				},
+				{
+					ID:            11054,
+					Station:       program.StationOnsen,
+					Title:         "セブン-イレブン presents 佐倉としたい大西",
+					Episode:       "第333回",
+					Start:         time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+					End:           time.Time{},
+					Status:        program.StatusScheduled,
+					StreamType:    program.StreamTypeOndemand,
+					PlaylistURL:   "",
+					Personalities: []string{"佐倉綾音", "大西沙織"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "年をまたいだ配信回は前年、配信日が空であれば取得日",
+			args: args{
+				now: time.Date(2023, 1, 2, 0, 0, 0, 0, timeutil.LocationJST()),
+				onsenPgram: onsenProgram{
+					ID:    17,
+					Title: "セブン-イレブン presents 佐倉としたい大西",
+					Contents: []Content{
+						{
+							ID:           11200,
+							Title:        "第353回",
+							DeliveryDate: "1/2",
+							StreamingURL: "https://onsen.test/353/playlist.m3u8",
+						},
+						{
+							ID:           11190,
+							Title:        "第352回",
+							DeliveryDate: "12/27",
+							StreamingURL: "https://onsen.test/352/playlist.m3u8",
+						},
+						{
+							ID:           11210,
+							Title:        "特別編",
+							DeliveryDate: "",
+							StreamingURL: "https://onsen.test/special/playlist.m3u8",
+						},
+					},
+				},
+			},
+			want: []program.Program{
+				{
+					ID:          11200,
+					Station:     program.StationOnsen,
+					Title:       "セブン-イレブン presents 佐倉としたい大西",
+					Episode:     "第353回",
+					Start:       time.Date(2023, 1, 2, 0, 0, 0, 0, timeutil.LocationJST()),
+					Status:      program.StatusScheduled,
+					StreamType:  program.StreamTypeOndemand,
+					PlaylistURL: "https://onsen.test/353/playlist.m3u8",
+				},
+				{
+					ID:          11190,
 					Station:     program.StationOnsen,
 					Title:       "セブン-イレブン presents 佐倉としたい大西",
-					Episode:     "第334回",
-					Start:       time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
-					End:         time.Time{},
+					Episode:     "第352回",
+					Start:       time.Date(2022, 12, 27, 0, 0, 0, 0, timeutil.LocationJST()),
 					Status:      program.StatusScheduled,
 					StreamType:  program.StreamTypeOndemand,
-					PlaylistURL: "https://onsen.test/playlist.m3u8",
+					PlaylistURL: "https://onsen.test/352/playlist.m3u8",
 				},
 				{
-					ID:          11054,
+					ID:          11210,
 					Station:     program.StationOnsen,
 					Title:       "セブン-イレブン presents 佐倉としたい大西",
-					Episode:     "第333回",
-					Start:       time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
-					End:         time.Time{},
+					Episode:     "特別編",
+					Start:       time.Date(2023, 1, 2, 0, 0, 0, 0, timeutil.LocationJST()),
 					Status:      program.StatusScheduled,
 					StreamType:  program.StreamTypeOndemand,
-					PlaylistURL: "",
+					PlaylistURL: "https://onsen.test/special/playlist.m3u8",
 				},
 			},
 			wantErr: false,
@@ -127,7 +196,7 @@ func Test_onsenProgramToPrograms(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := onsenProgramToPrograms(tt.args.onsenPgram)
+			got, err := onsenProgramToPrograms(tt.args.now, tt.args.onsenPgram)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("onsenProgramToPrograms() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -163,6 +232,8 @@ func Test_client_GetPrograms(t *testing.T) {
 
 			c := &client{
 				httpClient: r.GetDefaultClient(),
+				programURL: "https://www.onsen.ag/web_api/programs",
+				clock:      clock.NewFake(time.Date(2022, 8, 24, 12, 0, 0, 0, timeutil.LocationJST())),
 			}
 			got, err := c.GetPrograms(context.Background(), date.Date{})
 			if (err != nil) != tt.wantErr {
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+type attemptPostgres struct {
+	ProgramUUID     string         `db:"program_uuid"`
+	Source          int            `db:"source"`
+	FilePath        sql.NullString `db:"file_path"`
+	DurationMs      int64          `db:"duration_ms"`
+	Discontinuities int            `db:"discontinuities"`
+	Error           sql.NullString `db:"error"`
+	Chosen          bool           `db:"chosen"`
+}
+
+func attemptPostgresToModelAttempt(a attemptPostgres) recorder.Attempt {
+	return recorder.Attempt{
+		ProgramUUID: a.ProgramUUID,
+		Source:      a.Source,
+		FilePath:    a.FilePath.String,
+		Probe: recorder.Probe{
+			Duration:        time.Duration(a.DurationMs) * time.Millisecond,
+			Discontinuities: a.Discontinuities,
+		},
+		Error:  a.Error.String,
+		Chosen: a.Chosen,
+	}
+}
+
+func modelAttemptToAttemptPostgres(uuid string, a recorder.Attempt) attemptPostgres {
+	return attemptPostgres{
+		ProgramUUID:     uuid,
+		Source:          a.Source,
+		FilePath:        sql.NullString{String: a.FilePath, Valid: a.FilePath != ""},
+		DurationMs:      a.Probe.Duration.Milliseconds(),
+		Discontinuities: a.Probe.Discontinuities,
+		Error:           sql.NullString{String: a.Error, Valid: a.Error != ""},
+		Chosen:          a.Chosen,
+	}
+}
+
+func (c *client) SaveAttempts(ctx context.Context, pgram program.Program, attempts []recorder.Attempt) error {
+	tx, err := c.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.GetContext(ctx, &count, `select count(*) from programs where uuid = $1`, pgram.UUID)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	if count == 0 {
+		return errors.Wrapf(errutil.ErrDatabaseNotFoundProgram, "not found program (uuid = %s)", pgram.UUID)
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from attempts where program_uuid = $1`, pgram.UUID)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	for _, attempt := range attempts {
+		_, err := tx.NamedExecContext(ctx, `
+			insert into attempts (program_uuid, source, file_path, duration_ms, discontinuities, error, chosen)
+			values (:program_uuid, :source, :file_path, :duration_ms, :discontinuities, :error, :chosen)`,
+			modelAttemptToAttemptPostgres(pgram.UUID, attempt))
+		if err != nil {
+			return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return nil
+}
+
+func (c *client) LoadAttempts(ctx context.Context, uuid string) ([]recorder.Attempt, error) {
+	var attemptsPostgres []attemptPostgres
+	err := c.DB.SelectContext(ctx, &attemptsPostgres, `
+		select program_uuid, source, file_path, duration_ms, discontinuities, error, chosen
+		from attempts where program_uuid = $1 order by source`, uuid)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	var attempts []recorder.Attempt
+	for _, a := range attemptsPostgres {
+		attempts = append(attempts, attemptPostgresToModelAttempt(a))
+	}
+	return attempts, nil
+}
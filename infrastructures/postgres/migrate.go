@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"embed"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/migration"
+)
+
+// マイグレーションは migrations/NNNN_name.sql に置く
+// 一度リリースしたものは変更せず、スキーマを変更するときは新しい番号のファイルを追加する
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// 埋め込まれたマイグレーションを version の昇順で返す
+func Migrations() ([]migration.Migration, error) {
+	return migration.Load(migrationFS, "migrations")
+}
+
+// 埋め込まれたマイグレーションのうち最新の version
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	return migration.Latest(migrations), nil
+}
+
+// 適用済みの version のうち最大のもの
+// 何も適用されていなければ 0
+func CurrentVersion(db *sqlx.DB) (int, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+	return migration.Current(applied), nil
+}
+
+// まだ適用されていないマイグレーション
+// データベースは変更しない
+func PendingMigrations(db *sqlx.DB) ([]migration.Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	return migration.Pending(migrations, applied), nil
+}
+
+// まだ適用されていないマイグレーションを順に適用し、適用したものを返す
+// 各マイグレーションはそれぞれトランザクション内で実行する
+// バックアップはとらないので、必要であれば事前に pg_dump すること
+func Migrate(db *sqlx.DB) ([]migration.Migration, error) {
+	_, err := db.Exec(`create table if not exists schema_version (
+		version integer primary key,
+		name text not null,
+		applied_at timestamptz not null default now()
+	)`)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range pending {
+		err := apply(db, m)
+		if err != nil {
+			return pending[:i], err
+		}
+		log.Info().Msgf("applied migration %s", m)
+	}
+	return pending, nil
+}
+
+func apply(db *sqlx.DB, m migration.Migration) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	defer tx.Rollback()
+
+	// 複数のプロセスが同時にマイグレーションしないよう、適用が終わるまで待たせる
+	_, err = tx.Exec(`lock table schema_version in exclusive mode`)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	var count int
+	err = tx.Get(&count, `select count(*) from schema_version where version = $1`, m.Version)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	if count != 0 {
+		// 他のプロセスが先に適用した
+		return nil
+	}
+
+	_, err = tx.Exec(m.SQL)
+	if err != nil {
+		return errors.Wrapf(errutil.ErrDatabaseQuery, "migration %s: %s", m, err.Error())
+	}
+	_, err = tx.Exec(`insert into schema_version (version, name) values ($1, $2)`, m.Version, m.Name)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return nil
+}
+
+// 適用済みの version
+func appliedVersions(db *sqlx.DB) (map[int]bool, error) {
+	applied := map[int]bool{}
+
+	var exists bool
+	err := db.Get(&exists, `select to_regclass('schema_version') is not null`)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	var versions []int
+	err = db.Select(&versions, `select version from schema_version`)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	for _, version := range versions {
+		applied[version] = true
+	}
+	return applied, nil
+}
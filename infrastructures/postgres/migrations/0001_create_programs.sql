@@ -0,0 +1,30 @@
+-- sqlite の 0001 から 0004 までを適用したものと同じスキーマ
+create table if not exists programs (
+	uuid text primary key,
+	id bigint not null,
+	station text not null,
+	title text not null,
+	episode text,
+	start timestamptz not null,
+	"end" timestamptz not null,
+	status text not null,
+	stream_type text not null,
+	playlist_url text,
+	image_url text,
+	file_path text,
+	protected boolean not null default false,
+	object_key text,
+	created_at timestamptz not null default now(),
+	updated_at timestamptz not null default now(),
+	unique (station, id)
+);
+
+create or replace function set_updated_at() returns trigger as $$
+begin
+	new.updated_at = now();
+	return new;
+end;
+$$ language plpgsql;
+
+create trigger trigger_updated_at before update on programs
+for each row execute function set_updated_at();
@@ -0,0 +1,12 @@
+-- 冗長録画での配信元毎の録画結果
+create table if not exists attempts (
+	program_uuid text not null references programs (uuid) on delete cascade,
+	source integer not null,
+	file_path text,
+	duration_ms bigint not null,
+	discontinuities integer not null,
+	error text,
+	chosen boolean not null default false,
+	created_at timestamptz not null default now(),
+	primary key (program_uuid, source)
+);
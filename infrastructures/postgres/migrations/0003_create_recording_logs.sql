@@ -0,0 +1,8 @@
+-- 録画 1 回分のログファイル
+create table if not exists recording_logs (
+	program_uuid text not null references programs (uuid) on delete cascade,
+	file_path text not null,
+	started_at timestamptz not null,
+	primary key (program_uuid, file_path)
+);
+create index if not exists recording_logs_started_at on recording_logs (started_at);
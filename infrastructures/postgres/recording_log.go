@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+type recordingLogPostgres struct {
+	ProgramUUID string    `db:"program_uuid"`
+	FilePath    string    `db:"file_path"`
+	StartedAt   time.Time `db:"started_at"`
+}
+
+func recordingLogPostgresToModelRecordingLog(l recordingLogPostgres) recorder.RecordingLog {
+	return recorder.RecordingLog{
+		ProgramUUID: l.ProgramUUID,
+		FilePath:    l.FilePath,
+		StartedAt:   l.StartedAt,
+	}
+}
+
+func (c *client) SaveRecordingLog(ctx context.Context, pgram program.Program, recLog recorder.RecordingLog) error {
+	var count int
+	err := c.DB.GetContext(ctx, &count, `select count(*) from programs where uuid = $1`, pgram.UUID)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	if count == 0 {
+		return errors.Wrapf(errutil.ErrDatabaseNotFoundProgram, "not found program (uuid = %s)", pgram.UUID)
+	}
+
+	_, err = c.DB.NamedExecContext(ctx, `
+		insert into recording_logs (program_uuid, file_path, started_at)
+		values (:program_uuid, :file_path, :started_at)`,
+		recordingLogPostgres{
+			ProgramUUID: pgram.UUID,
+			FilePath:    recLog.FilePath,
+			StartedAt:   recLog.StartedAt.UTC(),
+		})
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return nil
+}
+
+func (c *client) LoadRecordingLogs(ctx context.Context, uuid string) ([]recorder.RecordingLog, error) {
+	var logsPostgres []recordingLogPostgres
+	err := c.DB.SelectContext(ctx, &logsPostgres, `
+		select program_uuid, file_path, started_at
+		from recording_logs where program_uuid = $1 order by started_at`, uuid)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	var logs []recorder.RecordingLog
+	for _, l := range logsPostgres {
+		logs = append(logs, recordingLogPostgresToModelRecordingLog(l))
+	}
+	return logs, nil
+}
+
+func (c *client) DeleteRecordingLogsBefore(ctx context.Context, before time.Time) ([]recorder.RecordingLog, error) {
+	tx, err := c.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	defer tx.Rollback()
+
+	var logsPostgres []recordingLogPostgres
+	err = tx.SelectContext(ctx, &logsPostgres, `
+		select program_uuid, file_path, started_at
+		from recording_logs where started_at < $1 order by started_at`, before.UTC())
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	_, err = tx.ExecContext(ctx, `delete from recording_logs where started_at < $1`, before.UTC())
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	var logs []recorder.RecordingLog
+	for _, l := range logsPostgres {
+		logs = append(logs, recordingLogPostgresToModelRecordingLog(l))
+	}
+	return logs, nil
+}
@@ -0,0 +1,144 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/fileutil"
+)
+
+type Config struct {
+	// ホスト名（:ポート）
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+
+	// multipart upload の 1 パートのサイズ（byte）
+	// 0 であれば minio-go におまかせ
+	PartSize uint64
+}
+
+type client struct {
+	minioClient *minio.Client
+	bucket      string
+	partSize    uint64
+}
+
+func New(config Config) (repository.ObjectStorage, error) {
+	minioClient, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: config.UseSSL,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrObjectStorage, err.Error())
+	}
+
+	return &client{
+		minioClient: minioClient,
+		bucket:      config.Bucket,
+		partSize:    config.PartSize,
+	}, nil
+}
+
+func (c *client) Upload(ctx context.Context, localPath string, key string) error {
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	size := stat.Size()
+
+	log.Ctx(ctx).Debug().Msgf("upload start ... (file = %s, key = %s, size = %d)", localPath, key, size)
+	_, err = c.minioClient.FPutObject(ctx, c.bucket, key, localPath, minio.PutObjectOptions{
+		ContentType: fileutil.ContentType(localPath),
+		PartSize:    c.partSize,
+		// 各パートに Content-MD5 を付与し、サーバー側でも破損を検知させる
+		SendContentMd5: true,
+	})
+	if err != nil {
+		return errors.Wrap(errutil.ErrObjectStorage, err.Error())
+	}
+
+	// アップロードされたものがローカルのファイルと一致しているか、サーバーが計算した ETag と比べて確認
+	info, err := c.minioClient.StatObject(ctx, c.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return errors.Wrap(errutil.ErrObjectStorage, err.Error())
+	}
+	if info.Size != size {
+		return errors.Wrapf(errutil.ErrChecksumMismatch, "size mismatch (local = %d, remote = %d)", size, info.Size)
+	}
+	// multipart upload であれば "-パート数" が付く
+	// minio-go は multipart upload できなければ single PUT にフォールバックするため、サーバーの ETag の形式で判断する
+	var partSize int64
+	if strings.Contains(info.ETag, "-") {
+		_, partSize, _, err = minio.OptimalPartInfo(size, c.partSize)
+		if err != nil {
+			return errors.Wrap(errutil.ErrInternal, err.Error())
+		}
+	}
+	etag, err := localETag(localPath, partSize)
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	if info.ETag != etag {
+		return errors.Wrapf(errutil.ErrChecksumMismatch, "etag mismatch (local = %s, remote = %s)", etag, info.ETag)
+	}
+
+	log.Ctx(ctx).Info().Msgf("successfully upload (key = %s)", key)
+	return nil
+}
+
+// S3 がアップロードされたデータから計算する ETag と同じものをローカルのファイルから計算する
+// partSize が 0 であれば single PUT として本体の MD5、
+// そうでなければ multipart upload として各パートの MD5 を連結したものの MD5 に "-パート数" を付けたもの
+// SSE-KMS などで暗号化されるバケットでは ETag が MD5 にならないため一致しない
+func localETag(path string, partSize int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if partSize == 0 {
+		h := md5.New()
+		_, err := io.Copy(h, f)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	var sums []byte
+	parts := 0
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, f, partSize)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if n == 0 {
+			break
+		}
+		sums = append(sums, h.Sum(nil)...)
+		parts++
+		if err == io.EOF {
+			break
+		}
+	}
+	sum := md5.Sum(sums)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), parts), nil
+}
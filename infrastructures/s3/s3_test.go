@@ -0,0 +1,261 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+)
+
+type fakeObject struct {
+	body   []byte
+	header http.Header
+	etag   string
+}
+
+// single PUT、multipart upload と HEAD だけを扱う S3 もどき
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	// key は uploadId、value は partNumber ごとのデータ
+	uploads map[string]map[int][]byte
+	// true であれば Content-MD5 を確認せず、保存時にデータを破損させる
+	corrupt bool
+}
+
+func newFakeS3(corrupt bool) *fakeS3 {
+	return &fakeS3{
+		objects: map[string]fakeObject{},
+		uploads: map[string]map[int][]byte{},
+		corrupt: corrupt,
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		uploadID := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[uploadID] = map[int][]byte{}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			UploadID string   `xml:"UploadId"`
+		}{UploadID: uploadID})
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		partNumber, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, ok := f.readBody(w, r)
+		if !ok {
+			return
+		}
+		parts[partNumber] = body
+		sum := md5.Sum(body)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+			} `xml:"Part"`
+		}
+		err := xml.NewDecoder(r.Body).Decode(&complete)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body, sums []byte
+		for _, part := range complete.Parts {
+			body = append(body, parts[part.PartNumber]...)
+			sum := md5.Sum(parts[part.PartNumber])
+			sums = append(sums, sum[:]...)
+		}
+		sum := md5.Sum(sums)
+		etag := fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(complete.Parts))
+		f.objects[r.URL.Path] = fakeObject{body: body, header: http.Header{}, etag: etag}
+		delete(f.uploads, query.Get("uploadId"))
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: bucket, Key: key, ETag: `"` + etag + `"`})
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		body, ok := f.readBody(w, r)
+		if !ok {
+			return
+		}
+		header := http.Header{}
+		for k, v := range r.Header {
+			if strings.HasPrefix(k, "X-Amz-Meta-") {
+				header[k] = v
+			}
+		}
+		sum := md5.Sum(body)
+		etag := hex.EncodeToString(sum[:])
+		f.objects[r.URL.Path] = fakeObject{body: body, header: header, etag: etag}
+		w.Header().Set("ETag", `"`+etag+`"`)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodHead:
+		obj, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range obj.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.body)))
+		w.Header().Set("ETag", `"`+obj.etag+`"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// 受け取ったデータを Content-MD5 で確認して返す
+// 破損させる場合はサイズを変えずに 1 byte 書き換える
+func (f *fakeS3) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if f.corrupt {
+		body[0] ^= 0xff
+		return body, true
+	}
+	sum := md5.Sum(body)
+	if md5Header := r.Header.Get("Content-Md5"); md5Header != "" && md5Header != base64.StdEncoding.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func Test_client_Upload(t *testing.T) {
+	const partSize = 5 * 1024 * 1024
+
+	tests := []struct {
+		name     string
+		fileSize int
+		corrupt  bool
+		wantErr  error
+	}{
+		{
+			name:     "single PUT でアップロードしたものがローカルと一致する",
+			fileSize: 4096,
+			corrupt:  false,
+			wantErr:  nil,
+		},
+		{
+			name:     "single PUT でアップロードしたものが破損していれば ErrChecksumMismatch",
+			fileSize: 4096,
+			corrupt:  true,
+			wantErr:  errutil.ErrChecksumMismatch,
+		},
+		{
+			name:     "multipart upload でアップロードしたものがローカルと一致する",
+			fileSize: 2*partSize + 1024,
+			corrupt:  false,
+			wantErr:  nil,
+		},
+		{
+			name:     "multipart upload でアップロードしたものが破損していれば ErrChecksumMismatch",
+			fileSize: 2*partSize + 1024,
+			corrupt:  true,
+			wantErr:  errutil.ErrChecksumMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(tt.corrupt)
+			server := httptest.NewTLSServer(fake)
+			defer server.Close()
+
+			serverURL, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			minioClient, err := minio.New(serverURL.Host, &minio.Options{
+				Creds:     credentials.NewStaticV4("access", "secret", ""),
+				Secure:    true,
+				Region:    "us-east-1",
+				Transport: server.Client().Transport,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			localPath := filepath.Join(t.TempDir(), "test.ts")
+			err = os.WriteFile(localPath, []byte(strings.Repeat("anrd", tt.fileSize/4)), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := &client{
+				minioClient: minioClient,
+				bucket:      "archive",
+				partSize:    partSize,
+			}
+			err = c.Upload(context.Background(), localPath, "agqr/2022-08-10/test.ts")
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("client.Upload() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			obj, ok := fake.objects["/archive/agqr/2022-08-10/test.ts"]
+			if !ok {
+				t.Errorf("client.Upload() object not found in fake")
+				return
+			}
+			// PartSize を超えるものは multipart upload される
+			if gotMultipart, wantMultipart := strings.Contains(obj.etag, "-"), tt.fileSize > partSize; gotMultipart != wantMultipart {
+				t.Errorf("client.Upload() multipart = %v, want %v", gotMultipart, wantMultipart)
+			}
+		})
+	}
+}
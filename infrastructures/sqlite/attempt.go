@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+type attemptSqlite struct {
+	ProgramUUID     string         `db:"program_uuid"`
+	Source          int            `db:"source"`
+	FilePath        sql.NullString `db:"file_path"`
+	DurationMs      int64          `db:"duration_ms"`
+	Discontinuities int            `db:"discontinuities"`
+	Error           sql.NullString `db:"error"`
+	Chosen          bool           `db:"chosen"`
+}
+
+func attemptSqliteToModelAttempt(a attemptSqlite) recorder.Attempt {
+	return recorder.Attempt{
+		ProgramUUID: a.ProgramUUID,
+		Source:      a.Source,
+		FilePath:    a.FilePath.String,
+		Probe: recorder.Probe{
+			Duration:        time.Duration(a.DurationMs) * time.Millisecond,
+			Discontinuities: a.Discontinuities,
+		},
+		Error:  a.Error.String,
+		Chosen: a.Chosen,
+	}
+}
+
+func modelAttemptToAttemptSqlite(uuid string, a recorder.Attempt) attemptSqlite {
+	return attemptSqlite{
+		ProgramUUID:     uuid,
+		Source:          a.Source,
+		FilePath:        sql.NullString{String: a.FilePath, Valid: a.FilePath != ""},
+		DurationMs:      a.Probe.Duration.Milliseconds(),
+		Discontinuities: a.Probe.Discontinuities,
+		Error:           sql.NullString{String: a.Error, Valid: a.Error != ""},
+		Chosen:          a.Chosen,
+	}
+}
+
+func (c *client) SaveAttempts(ctx context.Context, pgram program.Program, attempts []recorder.Attempt) error {
+	tx, err := c.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.GetContext(ctx, &count, `select count(*) from programs where uuid = ?`, pgram.UUID)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	if count == 0 {
+		return errors.Wrapf(errutil.ErrDatabaseNotFoundProgram, "not found program (uuid = %s)", pgram.UUID)
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from attempts where program_uuid = ?`, pgram.UUID)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	for _, attempt := range attempts {
+		_, err := tx.NamedExecContext(ctx, `
+			insert into attempts (program_uuid, source, file_path, duration_ms, discontinuities, error, chosen)
+			values (:program_uuid, :source, :file_path, :duration_ms, :discontinuities, :error, :chosen)`,
+			modelAttemptToAttemptSqlite(pgram.UUID, attempt))
+		if err != nil {
+			return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return nil
+}
+
+func (c *client) LoadAttempts(ctx context.Context, uuid string) ([]recorder.Attempt, error) {
+	var attemptsSqlite []attemptSqlite
+	err := c.DB.SelectContext(ctx, &attemptsSqlite, `
+		select program_uuid, source, file_path, duration_ms, discontinuities, error, chosen
+		from attempts where program_uuid = ? order by source`, uuid)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	var attempts []recorder.Attempt
+	for _, a := range attemptsSqlite {
+		attempts = append(attempts, attemptSqliteToModelAttempt(a))
+	}
+	return attempts, nil
+}
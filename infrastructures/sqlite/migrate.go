@@ -0,0 +1,229 @@
+package sqlite
+
+import (
+	"embed"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/migration"
+)
+
+// マイグレーションは migrations/NNNN_name.sql に置く
+// 一度リリースしたものは変更せず、スキーマを変更するときは新しい番号のファイルを追加する
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// schema_version を導入する前の Setup によって作成・変更されたデータベースでは、
+// それぞれのマイグレーションで追加されるカラムが既に存在していれば適用済みとみなす
+var legacyColumns = map[int][]string{
+	2: {"file_path", "protected"},
+	3: {"object_key"},
+	4: {"image_url"},
+}
+
+// 埋め込まれたマイグレーションを version の昇順で返す
+func Migrations() ([]migration.Migration, error) {
+	return migration.Load(migrationFS, "migrations")
+}
+
+// 埋め込まれたマイグレーションのうち最新の version
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	return migration.Latest(migrations), nil
+}
+
+// 適用済みの version のうち最大のもの
+// 何も適用されていなければ 0
+func CurrentVersion(db *sqlx.DB) (int, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+	return migration.Current(applied), nil
+}
+
+// まだ適用されていないマイグレーション
+// データベースは変更しない
+func PendingMigrations(db *sqlx.DB) ([]migration.Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	return migration.Pending(migrations, applied), nil
+}
+
+// まだ適用されていないマイグレーションを順に適用し、適用したものを返す
+// 各マイグレーションはそれぞれトランザクション内で実行する
+func Migrate(db *sqlx.DB) ([]migration.Migration, error) {
+	err := createSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range pending {
+		err := apply(db, m)
+		if err != nil {
+			return pending[:i], err
+		}
+		log.Info().Msgf("applied migration %s", m)
+	}
+	return pending, nil
+}
+
+// db を dest にコピーする
+// dest が既に存在すればエラー
+func Backup(db *sqlx.DB, dest string) error {
+	_, err := db.Exec(`vacuum into ?`, dest)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return nil
+}
+
+func apply(db *sqlx.DB, m migration.Migration) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(m.SQL)
+	if err != nil {
+		return errors.Wrapf(errutil.ErrDatabaseQuery, "migration %s: %s", m, err.Error())
+	}
+	_, err = tx.Exec(`insert into schema_version (version, name) values (?, ?)`, m.Version, m.Name)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return nil
+}
+
+// schema_version を作成する
+// schema_version 導入前のデータベースであれば、既に適用されているとみなせるものを記録する
+func createSchemaVersion(db *sqlx.DB) error {
+	exists, err := tableExists(db, "schema_version")
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`create table schema_version (
+		version integer primary key,
+		name text not null,
+		applied_at timestamp not null default (datetime('now', 'localtime'))
+	);`)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			continue
+		}
+		_, err = tx.Exec(`insert into schema_version (version, name) values (?, ?)`, m.Version, m.Name)
+		if err != nil {
+			return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+		}
+		log.Info().Msgf("migration %s is regarded as applied (legacy database)", m)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return nil
+}
+
+// 適用済みの version
+// schema_version 導入前のデータベースであれば、スキーマから推測する
+func appliedVersions(db *sqlx.DB) (map[int]bool, error) {
+	applied := map[int]bool{}
+
+	exists, err := tableExists(db, "schema_version")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		var versions []int
+		err = db.Select(&versions, `select version from schema_version`)
+		if err != nil {
+			return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+		}
+		for _, version := range versions {
+			applied[version] = true
+		}
+		return applied, nil
+	}
+
+	exists, err = tableExists(db, "programs")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		// まっさらなデータベース
+		return applied, nil
+	}
+
+	applied[1] = true
+	for version, columns := range legacyColumns {
+		all := true
+		for _, column := range columns {
+			var count int
+			err := db.Get(&count, `select count(*) from pragma_table_info('programs') where name = ?`, column)
+			if err != nil {
+				return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+			}
+			if count == 0 {
+				all = false
+			}
+		}
+		applied[version] = all
+	}
+	return applied, nil
+}
+
+func tableExists(db *sqlx.DB, table string) (bool, error) {
+	var count int
+	err := db.Get(&count, `select count(*) from sqlite_master where type = 'table' and name = ?`, table)
+	if err != nil {
+		return false, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return count != 0, nil
+}
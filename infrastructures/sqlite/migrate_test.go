@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+)
+
+// schema_version を導入する前の Setup で作成されていたテーブル
+const legacyCreatePrograms = `create table programs (
+	uuid text primary key,
+	id integer not null,
+	station text not null,
+	title text not null,
+	episode text,
+	start timestamp not null,
+	end timestamp not null,
+	status text not null,
+	stream_type text not null,
+	playlist_url text,
+	created_at timestamp not null default (datetime('now', 'localtime')),
+	updated_at timestamp not null default (datetime('now', 'localtime')),
+	unique (station, id)
+);`
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		prepare     func(db *sqlx.DB) error
+		wantApplied []int
+	}{
+		{
+			name:        "まっさらなデータベースにはすべて適用する",
+			prepare:     func(db *sqlx.DB) error { return nil },
+			wantApplied: []int{1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name: "後から追加したカラムがない既存のテーブルには足りないものだけ適用する",
+			prepare: func(db *sqlx.DB) error {
+				_, err := db.Exec(legacyCreatePrograms)
+				return err
+			},
+			wantApplied: []int{2, 3, 4, 5, 6, 7},
+		},
+		{
+			name: "途中までカラムを追加した既存のテーブルにはその続きから適用する",
+			prepare: func(db *sqlx.DB) error {
+				_, err := db.Exec(legacyCreatePrograms + `
+					alter table programs add column file_path text;
+					alter table programs add column protected integer not null default 0;
+					alter table programs add column object_key text;
+				`)
+				return err
+			},
+			wantApplied: []int{4, 5, 6, 7},
+		},
+		{
+			name: "最新であれば何も適用しない",
+			prepare: func(db *sqlx.DB) error {
+				_, err := Migrate(db)
+				return err
+			},
+			wantApplied: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempFilename := tempFilename(t)
+			defer os.Remove(tempFilename)
+			db, err := sqlx.Open("sqlite3", tempFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			err = tt.prepare(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			applied, err := Migrate(db)
+			if err != nil {
+				t.Fatalf("Migrate() error = %v", err)
+			}
+			var gotApplied []int
+			for _, migration := range applied {
+				gotApplied = append(gotApplied, migration.Version)
+			}
+			if diff := cmp.Diff(tt.wantApplied, gotApplied); diff != "" {
+				t.Errorf("Migrate() applied mismatch (-want +got):\n%s", diff)
+			}
+
+			current, err := CurrentVersion(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			latest, err := LatestVersion()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current != latest {
+				t.Errorf("CurrentVersion() = %d, want %d", current, latest)
+			}
+
+			_, err = db.Exec(`select ` + programColumns + ` from programs`)
+			if err != nil {
+				t.Errorf("Migrate() did not add columns: %v", err)
+			}
+		})
+	}
+}
+
+func TestBackup(t *testing.T) {
+	tempFilename := tempFilename(t)
+	defer os.Remove(tempFilename)
+	db, err := sqlx.Open("sqlite3", tempFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = Migrate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`insert into programs (uuid, id, station, title, start, end, status, stream_type) values
+		("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", 1, "agqr", "鷲崎健のヨルナイト×ヨルナイト", "2022-08-17 23:00:00+09:00", "2022-08-18 00:00:00+09:00", "scheduled", "broadcast")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "backup.sqlite3")
+	err = Backup(db, dest)
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	backup, err := sqlx.Open("sqlite3", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	var count int
+	err = backup.Get(&count, `select count(*) from programs`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Backup() count = %d, want 1", count)
+	}
+}
@@ -0,0 +1,20 @@
+create table if not exists programs (
+	uuid text primary key,
+	id integer not null,
+	station text not null,
+	title text not null,
+	episode text,
+	start timestamp not null,
+	end timestamp not null,
+	status text not null,
+	stream_type text not null,
+	playlist_url text,
+	created_at timestamp not null default (datetime('now', 'localtime')),
+	updated_at timestamp not null default (datetime('now', 'localtime')),
+	unique (station, id)
+);
+
+create trigger if not exists trigger_updated_at after update on programs
+begin
+	update programs set updated_at = datetime('now', 'localtime') where rowid == new.rowid;
+end;
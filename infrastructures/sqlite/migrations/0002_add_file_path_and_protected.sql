@@ -0,0 +1,3 @@
+-- 録画済みファイルのパスと、retention による削除からの保護
+alter table programs add column file_path text;
+alter table programs add column protected integer not null default 0;
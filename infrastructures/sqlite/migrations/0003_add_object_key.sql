@@ -0,0 +1,2 @@
+-- オブジェクトストレージにアップロードした際の object key
+alter table programs add column object_key text;
@@ -0,0 +1,2 @@
+-- 番組のアートワーク
+alter table programs add column image_url text;
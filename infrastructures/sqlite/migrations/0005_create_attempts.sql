@@ -0,0 +1,12 @@
+-- 冗長録画での配信元毎の録画結果
+create table if not exists attempts (
+	program_uuid text not null,
+	source integer not null,
+	file_path text,
+	duration_ms integer not null,
+	discontinuities integer not null,
+	error text,
+	chosen integer not null default 0,
+	created_at timestamp not null default (datetime('now', 'localtime')),
+	primary key (program_uuid, source)
+);
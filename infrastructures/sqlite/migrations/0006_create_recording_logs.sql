@@ -0,0 +1,9 @@
+-- 録画 1 回分のログファイル
+-- started_at は UTC
+create table if not exists recording_logs (
+	program_uuid text not null,
+	file_path text not null,
+	started_at timestamp not null,
+	primary key (program_uuid, file_path)
+);
+create index if not exists recording_logs_started_at on recording_logs (started_at);
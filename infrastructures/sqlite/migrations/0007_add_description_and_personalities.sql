@@ -0,0 +1,4 @@
+-- 番組の説明とパーソナリティ
+-- personalities は JSON の配列
+alter table programs add column description text;
+alter table programs add column personalities text;
@@ -3,6 +3,8 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -25,8 +27,18 @@ type programSqlite struct {
 	Status      string         `db:"status"`
 	StreamType  string         `db:"stream_type"`
 	PlaylistURL sql.NullString `db:"playlist_url"`
+	ImageURL    sql.NullString `db:"image_url"`
+	FilePath    sql.NullString `db:"file_path"`
+	Protected   bool           `db:"protected"`
+	ObjectKey   sql.NullString `db:"object_key"`
+
+	Description   sql.NullString `db:"description"`
+	Personalities sql.NullString `db:"personalities"`
 }
 
+// select で取得するカラム
+const programColumns = `uuid, id, station, title, episode, start, end, status, stream_type, playlist_url, image_url, file_path, protected, object_key, description, personalities`
+
 func programSqliteToModelProgram(pgramSqlite programSqlite) program.Program {
 	return program.Program{
 		UUID:        pgramSqlite.UUID,
@@ -39,6 +51,13 @@ func programSqliteToModelProgram(pgramSqlite programSqlite) program.Program {
 		Status:      program.Status(pgramSqlite.Status),
 		StreamType:  program.StreamType(pgramSqlite.StreamType),
 		PlaylistURL: pgramSqlite.PlaylistURL.String,
+		ImageURL:    pgramSqlite.ImageURL.String,
+		FilePath:    pgramSqlite.FilePath.String,
+		Protected:   pgramSqlite.Protected,
+		ObjectKey:   pgramSqlite.ObjectKey.String,
+
+		Description:   pgramSqlite.Description.String,
+		Personalities: decodePersonalities(pgramSqlite.Personalities),
 	}
 }
 
@@ -70,48 +89,49 @@ func modelProgramToProgramSqlite(pgram program.Program) programSqlite {
 		Status:      pgram.Status.String(),
 		StreamType:  pgram.StreamType.String(),
 		PlaylistURL: playlistURL,
+		ImageURL:    sql.NullString{String: pgram.ImageURL, Valid: pgram.ImageURL != ""},
+		FilePath:    sql.NullString{String: pgram.FilePath, Valid: pgram.FilePath != ""},
+		Protected:   pgram.Protected,
+		ObjectKey:   sql.NullString{String: pgram.ObjectKey, Valid: pgram.ObjectKey != ""},
+
+		Description:   sql.NullString{String: pgram.Description, Valid: pgram.Description != ""},
+		Personalities: encodePersonalities(pgram.Personalities),
 	}
 }
 
-func NewDB(dbPath string) (*sqlx.DB, error) {
-	db, err := sqlx.Open("sqlite3", dbPath)
+// JSON の配列として保存する
+// 空であれば null
+func encodePersonalities(personalities []string) sql.NullString {
+	if len(personalities) == 0 {
+		return sql.NullString{}
+	}
+	b, err := json.Marshal(personalities)
 	if err != nil {
-		return nil, errors.Wrap(errutil.ErrDatabaseOpen, err.Error())
+		// []string は失敗しない
+		return sql.NullString{}
 	}
-	return db, nil
+	return sql.NullString{String: string(b), Valid: true}
 }
 
-// テーブル作成
-func Setup(db *sqlx.DB) error {
-	_, err := db.Exec(`create table if not exists programs (
-		uuid text primary key,
-		id integer not null,
-		station text not null,
-		title text not null,
-		episode text,
-		start timestamp not null,
-		end timestamp not null,
-		status text not null,
-		stream_type text not null,
-		playlist_url text,
-		created_at timestamp not null default (datetime('now', 'localtime')),
-		updated_at timestamp not null default (datetime('now', 'localtime')),
-		unique (station, id)
-	);`)
+// 読めなければ空
+func decodePersonalities(s sql.NullString) []string {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+	var personalities []string
+	err := json.Unmarshal([]byte(s.String), &personalities)
 	if err != nil {
-		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+		return nil
 	}
+	return personalities
+}
 
-	_, err = db.Exec(`CREATE TRIGGER if not exists trigger_updated_at AFTER UPDATE ON programs
-		BEGIN
-			UPDATE programs SET updated_at = DATETIME('now', 'localtime') WHERE rowid == NEW.rowid;
-		END;
-		`)
+func NewDB(dbPath string) (*sqlx.DB, error) {
+	db, err := sqlx.Open("sqlite3", dbPath)
 	if err != nil {
-		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+		return nil, errors.Wrap(errutil.ErrDatabaseOpen, err.Error())
 	}
-
-	return nil
+	return db, nil
 }
 
 type client struct {
@@ -124,31 +144,57 @@ func New(db *sqlx.DB) repository.ProgramPersistence {
 	}
 }
 
-func (c *client) Save(ctx context.Context, pgram program.Program) error {
-	rows, err := c.DB.QueryxContext(ctx, "select count(*) from programs where id = ?", pgram.ID)
+func (c *client) Ping(ctx context.Context) error {
+	err := c.DB.PingContext(ctx)
 	if err != nil {
-		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+		return errors.Wrap(errutil.ErrDatabaseOpen, err.Error())
 	}
+	return nil
+}
 
+func (c *client) CheckSchema(ctx context.Context) error {
+	current, err := CurrentVersion(c.DB)
+	if err != nil {
+		return err
+	}
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	if current != latest {
+		return errors.Wrapf(errutil.ErrDatabaseQuery, "schema version is %d, want %d", current, latest)
+	}
+	return nil
+}
+
+func (c *client) Save(ctx context.Context, pgram program.Program) error {
 	var lineCount int
-	for rows.Next() {
-		err := rows.Scan(&lineCount)
-		if err != nil {
-			return errors.Wrap(errutil.ErrDatabaseScan, err.Error())
-		}
+	err := c.DB.GetContext(ctx, &lineCount, `select count(*) from programs where station = ? and id = ?`, pgram.Station, pgram.ID)
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
 	}
 
-	// 既に番組情報が登録されていれば追加しない
-	// TODO: 番組表の変更に対応できない問題がある
+	pgramSqlite := modelProgramToProgramSqlite(pgram)
+
+	// 既に番組情報が登録されていれば追加せず、番組表の変更を反映する
+	// 録画を始めたものや手動で変更したものはそのまま
+	// 変わっていなければ updated_at を進めないよう更新しない
 	if lineCount != 0 {
+		_, err = c.DB.NamedExecContext(ctx,
+			`update programs set title = :title, episode = :episode, start = :start, end = :end
+			where station = :station and id = :id and status = 'scheduled'
+			and (title != :title or episode is not :episode or start != :start or end != :end)`,
+			pgramSqlite)
+		if err != nil {
+			return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+		}
 		return nil
 	}
 
-	pgramSqlite := modelProgramToProgramSqlite(pgram)
 	_, err = c.DB.NamedExecContext(ctx,
-		`insert into programs (uuid, id, station, title, episode, start, end, status, stream_type, playlist_url)
+		`insert into programs (uuid, id, station, title, episode, start, end, status, stream_type, playlist_url, image_url, description, personalities)
 		values
-		(:uuid, :id, :station, :title, :episode, :start, :end, :status, :stream_type, :playlist_url)`,
+		(:uuid, :id, :station, :title, :episode, :start, :end, :status, :stream_type, :playlist_url, :image_url, :description, :personalities)`,
 		pgramSqlite)
 	if err != nil {
 		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
@@ -157,10 +203,51 @@ func (c *client) Save(ctx context.Context, pgram program.Program) error {
 	return nil
 }
 
+func (c *client) Upsert(ctx context.Context, pgram program.Program) (program.Program, bool, error) {
+	tx, err := c.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return program.Program{}, false, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.GetContext(ctx, &count, `select count(*) from programs where station = ? and id = ?`, pgram.Station, pgram.ID)
+	if err != nil {
+		return program.Program{}, false, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	// 既にあれば uuid は変えない
+	_, err = tx.NamedExecContext(ctx,
+		`insert into programs (uuid, id, station, title, episode, start, end, status, stream_type, playlist_url, image_url, file_path, protected, object_key, description, personalities)
+		values
+		(:uuid, :id, :station, :title, :episode, :start, :end, :status, :stream_type, :playlist_url, :image_url, :file_path, :protected, :object_key, :description, :personalities)
+		on conflict (station, id) do update set
+		title = excluded.title, episode = excluded.episode, start = excluded.start, end = excluded.end,
+		status = excluded.status, stream_type = excluded.stream_type, playlist_url = excluded.playlist_url, image_url = excluded.image_url,
+		file_path = excluded.file_path, protected = excluded.protected, object_key = excluded.object_key,
+		description = excluded.description, personalities = excluded.personalities`,
+		modelProgramToProgramSqlite(pgram))
+	if err != nil {
+		return program.Program{}, false, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	var pgramSqlite programSqlite
+	err = tx.GetContext(ctx, &pgramSqlite, `select `+programColumns+` from programs where station = ? and id = ?`, pgram.Station, pgram.ID)
+	if err != nil {
+		return program.Program{}, false, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return program.Program{}, false, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return programSqliteToModelProgram(pgramSqlite), count == 0, nil
+}
+
 func (c *client) LoadBroadcastStartIn(ctx context.Context, now time.Time, duration time.Duration) ([]program.Program, error) {
 	afterAbsoluteTime := now.Add(duration)
 
-	stmt, err := c.DB.PrepareNamedContext(ctx, `select uuid, id, station, title, episode, start, end, status, stream_type, playlist_url from programs where status = 'scheduled' and stream_type = 'broadcast' and :now < start and start < :after`)
+	stmt, err := c.DB.PrepareNamedContext(ctx, `select `+programColumns+` from programs where status = 'scheduled' and stream_type = 'broadcast' and :now < start and start < :after`)
 	if err != nil {
 		return nil, errors.Wrap(errutil.ErrDatabasePrepare, err.Error())
 	}
@@ -185,24 +272,17 @@ func (c *client) LoadBroadcastStartIn(ctx context.Context, now time.Time, durati
 }
 
 func (c *client) ChangeStatus(ctx context.Context, pgram program.Program, newStatus program.Status) error {
-	var oldStatus string
-	err := c.DB.GetContext(ctx, &oldStatus, `select status from programs where uuid = ?`, pgram.UUID)
-	if err != nil {
-		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
-	}
-
-	_, err = c.DB.NamedExecContext(ctx, `update programs set status = :status where uuid = :uuid`, map[string]interface{}{"status": newStatus, "uuid": pgram.UUID})
+	res, err := c.DB.NamedExecContext(ctx, `update programs set status = :status where uuid = :uuid`, map[string]interface{}{"status": newStatus, "uuid": pgram.UUID})
 	if err != nil {
 		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
 	}
-
-	return nil
+	return checkAffected(res)
 }
 
 func (c *client) LoadOndemandScheduled(ctx context.Context, limit int) (*[]program.Program, error) {
 	var pgramsSqlite []programSqlite
 	// playlist_url が null であれば何らかの会員限定コンテンツとする
-	err := c.DB.SelectContext(ctx, &pgramsSqlite, `select uuid, id, station, title, episode, start, end, status, stream_type, playlist_url from programs where status = 'scheduled' and playlist_url is not null limit ?`, limit)
+	err := c.DB.SelectContext(ctx, &pgramsSqlite, `select `+programColumns+` from programs where status = 'scheduled' and playlist_url is not null limit ?`, limit)
 	if err != nil {
 		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
 	}
@@ -219,3 +299,164 @@ func (c *client) LoadOndemandScheduled(ctx context.Context, limit int) (*[]progr
 	log.Ctx(ctx).Debug().Msg("successfully load program (ondemand scheduled)")
 	return &pgrams, nil
 }
+
+func (c *client) CountOndemandScheduled(ctx context.Context) (int, error) {
+	var count int
+	err := c.DB.GetContext(ctx, &count, `select count(*) from programs where status = 'scheduled' and playlist_url is not null`)
+	if err != nil {
+		return 0, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return count, nil
+}
+
+func (c *client) ChangeFilePath(ctx context.Context, pgram program.Program, filePath string) error {
+	filePathSqlite := sql.NullString{String: filePath, Valid: filePath != ""}
+	res, err := c.DB.NamedExecContext(ctx, `update programs set file_path = :file_path where uuid = :uuid`, map[string]interface{}{"file_path": filePathSqlite, "uuid": pgram.UUID})
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return checkAffected(res)
+}
+
+func (c *client) ChangeObjectKey(ctx context.Context, pgram program.Program, objectKey string) error {
+	objectKeySqlite := sql.NullString{String: objectKey, Valid: objectKey != ""}
+	res, err := c.DB.NamedExecContext(ctx, `update programs set object_key = :object_key where uuid = :uuid`, map[string]interface{}{"object_key": objectKeySqlite, "uuid": pgram.UUID})
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return checkAffected(res)
+}
+
+func (c *client) ChangeProtected(ctx context.Context, pgram program.Program, protected bool) error {
+	res, err := c.DB.NamedExecContext(ctx, `update programs set protected = :protected where uuid = :uuid`, map[string]interface{}{"protected": protected, "uuid": pgram.UUID})
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return checkAffected(res)
+}
+
+func (c *client) LoadDone(ctx context.Context) ([]program.Program, error) {
+	var pgramsSqlite []programSqlite
+	err := c.DB.SelectContext(ctx, &pgramsSqlite, `select `+programColumns+` from programs where status = 'done' order by start`)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	if len(pgramsSqlite) == 0 {
+		return nil, errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program (done)")
+	}
+
+	var pgrams []program.Program
+	for _, pgramSqlite := range pgramsSqlite {
+		pgrams = append(pgrams, programSqliteToModelProgram(pgramSqlite))
+	}
+	return pgrams, nil
+}
+
+func (c *client) Load(ctx context.Context, filter program.Filter) ([]program.Program, error) {
+	var conds []string
+	args := map[string]interface{}{}
+	if filter.Station != "" {
+		conds = append(conds, "station = :station")
+		args["station"] = filter.Station.String()
+	}
+	if filter.Status != "" {
+		conds = append(conds, "status = :status")
+		args["status"] = filter.Status.String()
+	}
+	if filter.StreamType != "" {
+		conds = append(conds, "stream_type = :stream_type")
+		args["stream_type"] = filter.StreamType.String()
+	}
+	if filter.Title != "" {
+		conds = append(conds, "instr(title, :title) > 0")
+		args["title"] = filter.Title
+	}
+	if !filter.StartFrom.IsZero() {
+		conds = append(conds, ":start_from <= start")
+		args["start_from"] = filter.StartFrom
+	}
+	if !filter.StartTo.IsZero() {
+		conds = append(conds, "start < :start_to")
+		args["start_to"] = filter.StartTo
+	}
+
+	query := `select ` + programColumns + ` from programs`
+	if len(conds) != 0 {
+		query += ` where ` + strings.Join(conds, " and ")
+	}
+	query += ` order by start`
+	if filter.Limit > 0 {
+		query += ` limit :limit`
+		args["limit"] = filter.Limit
+	}
+
+	stmt, err := c.DB.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabasePrepare, err.Error())
+	}
+	defer stmt.Close()
+
+	var pgramsSqlite []programSqlite
+	err = stmt.SelectContext(ctx, &pgramsSqlite, args)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+
+	if len(pgramsSqlite) == 0 {
+		return nil, errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program")
+	}
+
+	var pgrams []program.Program
+	for _, pgramSqlite := range pgramsSqlite {
+		pgrams = append(pgrams, programSqliteToModelProgram(pgramSqlite))
+	}
+	return pgrams, nil
+}
+
+func (c *client) LoadByUUID(ctx context.Context, uuid string) (program.Program, error) {
+	var pgramSqlite programSqlite
+	err := c.DB.GetContext(ctx, &pgramSqlite, `select `+programColumns+` from programs where uuid = ?`, uuid)
+	if err == sql.ErrNoRows {
+		return program.Program{}, errors.Wrapf(errutil.ErrDatabaseNotFoundProgram, "not found program (uuid = %s)", uuid)
+	}
+	if err != nil {
+		return program.Program{}, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	return programSqliteToModelProgram(pgramSqlite), nil
+}
+
+func (c *client) LoadUpdatedAt(ctx context.Context, uuids []string) (map[string]time.Time, error) {
+	updatedAt := make(map[string]time.Time, len(uuids))
+	if len(uuids) == 0 {
+		return updatedAt, nil
+	}
+	query, args, err := sqlx.In(`select uuid, updated_at from programs where uuid in (?)`, uuids)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	var rows []struct {
+		UUID      string    `db:"uuid"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	err = c.DB.SelectContext(ctx, &rows, c.DB.Rebind(query), args...)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	for _, row := range rows {
+		updatedAt[row.UUID] = row.UpdatedAt
+	}
+	return updatedAt, nil
+}
+
+// update した行がなければ ErrDatabaseNotFoundProgram
+func checkAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(errutil.ErrDatabaseQuery, err.Error())
+	}
+	if affected == 0 {
+		return errors.Wrap(errutil.ErrDatabaseNotFoundProgram, "not found program")
+	}
+	return nil
+}
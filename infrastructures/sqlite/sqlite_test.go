@@ -12,8 +12,10 @@ import (
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
 	"github.com/sobadon/anrd/internal/errutil"
 	"github.com/sobadon/anrd/internal/testutil"
+	"github.com/sobadon/anrd/internal/testutil/persistencetest"
 	"github.com/sobadon/anrd/internal/timeutil"
 )
 
@@ -26,32 +28,6 @@ func tempFilename(t testing.TB) string {
 	return f.Name()
 }
 
-func TestSetup(t *testing.T) {
-	tests := []struct {
-		name    string
-		wantErr bool
-	}{
-		{
-			name:    "エラーなしで終了する",
-			wantErr: false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tempFilename := tempFilename(t)
-			defer os.Remove(tempFilename)
-			db, err := sqlx.Open("sqlite3", tempFilename)
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			if err := Setup(db); (err != nil) != tt.wantErr {
-				t.Errorf("Setup() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
 func Test_client_Save(t *testing.T) {
 	type args struct {
 		pgram program.Program
@@ -139,7 +115,7 @@ func Test_client_Save(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			err = Setup(db)
+			_, err = Migrate(db)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -264,7 +240,7 @@ func Test_client_LoadBroadcastStartIn(t *testing.T) {
 				DB: db,
 			}
 
-			err = Setup(p.DB)
+			_, err = Migrate(p.DB)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -353,7 +329,7 @@ func Test_client_ChangeStatus(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			err = Setup(db)
+			_, err = Migrate(db)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -476,7 +452,7 @@ func Test_client_LoadOndemandScheduled(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			err = Setup(db)
+			_, err = Migrate(db)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -500,3 +476,304 @@ func Test_client_LoadOndemandScheduled(t *testing.T) {
 		})
 	}
 }
+
+func Test_client_ChangeFilePath(t *testing.T) {
+	type args struct {
+		pgram    program.Program
+		filePath string
+	}
+	tests := []struct {
+		name    string
+		prepare func(db *sqlx.DB) error
+		args    args
+		wantErr error
+	}{
+		{
+			name: "正常にファイルパスを変更できる",
+			prepare: func(db *sqlx.DB) error {
+				_, err := db.Exec(`insert into programs (uuid, id, station, title, episode, start, end, status, stream_type, playlist_url) values (
+					"89350da4-7f3b-4438-b99f-41ae9aa52bf5", "11134", "onsen", "セブン-イレブン presents 佐倉としたい大西", "第334回", "2022-08-23 00:00:00+09:00", "0001-01-01 00:00:00+00:00", "recording", "ondemand", "https://onsen.test/playlist.m3u8"
+				)`)
+				return err
+			},
+			args: args{
+				pgram:    program.Program{UUID: "89350da4-7f3b-4438-b99f-41ae9aa52bf5"},
+				filePath: "/archive/onsen/file.ts",
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "存在しない program であれば ErrDatabaseNotFoundProgram",
+			prepare: func(db *sqlx.DB) error { return nil },
+			args: args{
+				pgram:    program.Program{UUID: "89350da4-7f3b-4438-b99f-41ae9aa52bf5"},
+				filePath: "/archive/onsen/file.ts",
+			},
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempFilename := tempFilename(t)
+			defer os.Remove(tempFilename)
+			db, err := sqlx.Open("sqlite3", tempFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Migrate(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.prepare(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := &client{
+				DB: db,
+			}
+			err = c.ChangeFilePath(context.Background(), tt.args.pgram, tt.args.filePath)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("client.ChangeFilePath() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			var gotFilePath string
+			err = c.DB.Get(&gotFilePath, `select file_path from programs where uuid = ?`, tt.args.pgram.UUID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotFilePath != tt.args.filePath {
+				t.Errorf("client.ChangeFilePath() gotFilePath = %v, wantFilePath %v", gotFilePath, tt.args.filePath)
+			}
+		})
+	}
+}
+
+func Test_client_LoadDone(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(db *sqlx.DB) error
+		want    []program.Program
+		wantErr error
+	}{
+		{
+			name:    "番組が存在しなければ ErrDatabaseNotFoundProgram を返す",
+			prepare: func(db *sqlx.DB) error { return nil },
+			want:    nil,
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+		{
+			name: "done な番組のみを開始日時順に返す",
+			prepare: func(db *sqlx.DB) error {
+				_, err := db.Exec(`insert into programs (uuid, id, station, title, episode, start, end, status, stream_type, playlist_url, file_path, protected) values
+					("89350da4-7f3b-4438-b99f-41ae9aa52bf5", "11134", "onsen", "セブン-イレブン presents 佐倉としたい大西", "第334回", "2022-08-23 00:00:00+09:00", "0001-01-01 00:00:00+00:00", "done", "ondemand", "https://onsen.test/playlist.m3u8", "/archive/334.ts", 1),
+					("4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e", "11133", "onsen", "セブン-イレブン presents 佐倉としたい大西", "第333回", "2022-08-16 00:00:00+09:00", "0001-01-01 00:00:00+00:00", "done", "ondemand", "https://onsen.test/playlist.m3u8", "/archive/333.ts", 0),
+					("e07df7c6-eae8-40f8-8922-6b7ef0497dc8", "11132", "onsen", "セブン-イレブン presents 佐倉としたい大西", "第332回", "2022-08-09 00:00:00+09:00", "0001-01-01 00:00:00+00:00", "purged", "ondemand", "https://onsen.test/playlist.m3u8", "/archive/332.ts", 0)
+				`)
+				return err
+			},
+			want: []program.Program{
+				{
+					UUID:        "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+					ID:          11133,
+					Station:     program.StationOnsen,
+					Title:       "セブン-イレブン presents 佐倉としたい大西",
+					Episode:     "第333回",
+					Start:       time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+					End:         time.Time{},
+					Status:      program.StatusDone,
+					StreamType:  program.StreamTypeOndemand,
+					PlaylistURL: "https://onsen.test/playlist.m3u8",
+					FilePath:    "/archive/333.ts",
+				},
+				{
+					UUID:        "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+					ID:          11134,
+					Station:     program.StationOnsen,
+					Title:       "セブン-イレブン presents 佐倉としたい大西",
+					Episode:     "第334回",
+					Start:       time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+					End:         time.Time{},
+					Status:      program.StatusDone,
+					StreamType:  program.StreamTypeOndemand,
+					PlaylistURL: "https://onsen.test/playlist.m3u8",
+					FilePath:    "/archive/334.ts",
+					Protected:   true,
+				},
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempFilename := tempFilename(t)
+			defer os.Remove(tempFilename)
+			db, err := sqlx.Open("sqlite3", tempFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Migrate(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.prepare(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := &client{
+				DB: db,
+			}
+			got, err := c.LoadDone(context.Background())
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("client.LoadDone() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("client.LoadDone() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_client_Load(t *testing.T) {
+	pgram334 := program.Program{
+		UUID:        "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+		ID:          11134,
+		Station:     program.StationOnsen,
+		Title:       "セブン-イレブン presents 佐倉としたい大西",
+		Episode:     "第334回",
+		Start:       time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:         time.Time{},
+		Status:      program.StatusScheduled,
+		StreamType:  program.StreamTypeOndemand,
+		PlaylistURL: "https://onsen.test/playlist.m3u8",
+	}
+	pgram333 := program.Program{
+		UUID:        "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+		ID:          11133,
+		Station:     program.StationOnsen,
+		Title:       "セブン-イレブン presents 佐倉としたい大西",
+		Episode:     "第333回",
+		Start:       time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:         time.Time{},
+		Status:      program.StatusDone,
+		StreamType:  program.StreamTypeOndemand,
+		PlaylistURL: "https://onsen.test/playlist.m3u8",
+		FilePath:    "/archive/333.ts",
+	}
+	pgramAgqr := program.Program{
+		UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		ID:         1660140000,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusDone,
+		StreamType: program.StreamTypeBroadcast,
+	}
+
+	tests := []struct {
+		name    string
+		filter  program.Filter
+		want    []program.Program
+		wantErr error
+	}{
+		{
+			name:    "条件がなければすべてを開始日時順に返す",
+			filter:  program.Filter{},
+			want:    []program.Program{pgram333, pgramAgqr, pgram334},
+			wantErr: nil,
+		},
+		{
+			name:    "station と status で絞り込む",
+			filter:  program.Filter{Station: program.StationOnsen, Status: program.StatusDone},
+			want:    []program.Program{pgram333},
+			wantErr: nil,
+		},
+		{
+			name:    "タイトルの部分一致と stream type で絞り込む",
+			filter:  program.Filter{Title: "ヨルナイト", StreamType: program.StreamTypeBroadcast},
+			want:    []program.Program{pgramAgqr},
+			wantErr: nil,
+		},
+		{
+			name: "開始日時の範囲で絞り込み、件数を制限する",
+			filter: program.Filter{
+				StartFrom: time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+				StartTo:   time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+				Limit:     1,
+			},
+			want:    []program.Program{pgram333},
+			wantErr: nil,
+		},
+		{
+			name:    "該当する番組がなければ ErrDatabaseNotFoundProgram を返す",
+			filter:  program.Filter{Status: program.StatusFailed},
+			want:    nil,
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempFilename := tempFilename(t)
+			defer os.Remove(tempFilename)
+			db, err := sqlx.Open("sqlite3", tempFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Migrate(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = db.Exec(`insert into programs (uuid, id, station, title, episode, start, end, status, stream_type, playlist_url, file_path) values
+				("89350da4-7f3b-4438-b99f-41ae9aa52bf5", "11134", "onsen", "セブン-イレブン presents 佐倉としたい大西", "第334回", "2022-08-23 00:00:00+09:00", "0001-01-01 00:00:00+00:00", "scheduled", "ondemand", "https://onsen.test/playlist.m3u8", null),
+				("4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e", "11133", "onsen", "セブン-イレブン presents 佐倉としたい大西", "第333回", "2022-08-16 00:00:00+09:00", "0001-01-01 00:00:00+00:00", "done", "ondemand", "https://onsen.test/playlist.m3u8", "/archive/333.ts"),
+				("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", "1660140000", "agqr", "鷲崎健のヨルナイト×ヨルナイト", null, "2022-08-17 23:00:00+09:00", "2022-08-18 00:00:00+09:00", "done", "broadcast", null, null)
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := &client{
+				DB: db,
+			}
+			got, err := c.Load(context.Background(), tt.filter)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("client.Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("client.Load() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	persistencetest.Run(t, func(t *testing.T) repository.ProgramPersistence {
+		tempFilename := tempFilename(t)
+		t.Cleanup(func() { os.Remove(tempFilename) })
+		db, err := NewDB(tempFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		_, err = Migrate(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return New(db)
+	})
+}
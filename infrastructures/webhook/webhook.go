@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/ffmpegutil"
+)
+
+type Format string
+
+const (
+	FormatGeneric = Format("generic")
+	FormatSlack   = Format("slack")
+	FormatDiscord = Format("discord")
+)
+
+type Target struct {
+	URL    string
+	Format Format
+
+	// 通知するイベント
+	// 空であればすべてのイベントを通知する
+	Events []notification.Event
+}
+
+func (t Target) accepts(event notification.Event) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+type client struct {
+	httpClient *http.Client
+	targets    []Target
+
+	// retryMaxCount=3 であれば計 4 回送信を試みる
+	retryMaxCount int
+	// リトライ毎に 2 倍にしていく
+	retryInterval time.Duration
+}
+
+func New(targets []Target, retryMaxCount int) repository.Notifier {
+	return &client{
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		targets:       targets,
+		retryMaxCount: retryMaxCount,
+		retryInterval: 1 * time.Second,
+	}
+}
+
+func (c *client) Notify(ctx context.Context, n notification.Notification) error {
+	var errMsgs []string
+	for _, target := range c.targets {
+		if !target.accepts(n.Event) {
+			continue
+		}
+
+		body, err := buildPayload(target.Format, n)
+		if err != nil {
+			return err
+		}
+
+		err = c.postWithRetry(ctx, target.URL, body)
+		if err != nil {
+			errMsgs = append(errMsgs, err.Error())
+		}
+	}
+
+	if len(errMsgs) != 0 {
+		return errors.Wrap(errutil.ErrNotify, strings.Join(errMsgs, "; "))
+	}
+	return nil
+}
+
+func (c *client) postWithRetry(ctx context.Context, targetURL string, body []byte) error {
+	interval := c.retryInterval
+	var err error
+	for retryCount := 0; retryCount <= c.retryMaxCount; retryCount++ {
+		if retryCount != 0 {
+			log.Ctx(ctx).Warn().Msgf("failed to post webhook (retryCount = %d): %+v", retryCount-1, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+			interval *= 2
+		}
+
+		var retry bool
+		retry, err = c.post(ctx, targetURL, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// 送り直しても結果が変わらなければ retry は false
+// webhook の URL はそれ自体が秘密なので、エラーには伏せたものを含める
+func (c *client) post(ctx context.Context, targetURL string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targetURL, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(errutil.ErrInternal, redactURLError(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, errors.Wrap(errutil.ErrHTTPRequest, redactURLError(err))
+	}
+	defer res.Body.Close()
+	// keep-alive で使い回せるように読み捨てる
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || 300 <= res.StatusCode {
+		// 4xx は URL や payload が間違っているので送り直さない
+		// 429 は時間をおけば受け付けられる
+		retry = res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+		return retry, errors.Wrapf(errutil.ErrHTTPRequest, "http status code is %d (url = %s)", res.StatusCode, ffmpegutil.RedactURLPath(targetURL))
+	}
+	return false, nil
+}
+
+// err に含まれる URL を伏せたエラーメッセージ
+func redactURLError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = ffmpegutil.RedactURLPath(urlErr.URL)
+	}
+	return err.Error()
+}
+
+type genericProgram struct {
+	UUID       string    `json:"uuid"`
+	ID         int       `json:"id"`
+	Station    string    `json:"station"`
+	Title      string    `json:"title"`
+	Episode    string    `json:"episode,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Status     string    `json:"status"`
+	StreamType string    `json:"stream_type"`
+	FilePath   string    `json:"file_path,omitempty"`
+}
+
+type genericPayload struct {
+	Event   string          `json:"event"`
+	Time    time.Time       `json:"time"`
+	Message string          `json:"message"`
+	Program *genericProgram `json:"program,omitempty"`
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+type discordPayload struct {
+	Content string `json:"content"`
+}
+
+func buildPayload(format Format, n notification.Notification) ([]byte, error) {
+	var payload interface{}
+	switch format {
+	case FormatSlack:
+		payload = slackPayload{Text: buildText(n)}
+	case FormatDiscord:
+		payload = discordPayload{Content: buildText(n)}
+	default:
+		p := genericPayload{
+			Event:   n.Event.String(),
+			Time:    n.Time,
+			Message: n.Message,
+		}
+		if n.Program != nil {
+			p.Program = &genericProgram{
+				UUID:       n.Program.UUID,
+				ID:         n.Program.ID,
+				Station:    n.Program.Station.String(),
+				Title:      n.Program.Title,
+				Episode:    n.Program.Episode,
+				Start:      n.Program.Start,
+				End:        n.Program.End,
+				Status:     n.Program.Status.String(),
+				StreamType: n.Program.StreamType.String(),
+				FilePath:   n.Program.FilePath,
+			}
+		}
+		payload = p
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	return body, nil
+}
+
+// Slack, Discord 向けの人が読む文章
+func buildText(n notification.Notification) string {
+	text := fmt.Sprintf("[anrd] %s: %s", n.Event, n.Message)
+	if n.Program != nil {
+		text += fmt.Sprintf("\n%s %s %s (%s)", n.Program.Station, n.Program.Title, n.Program.Episode, n.Program.Start.Format("2006-01-02 15:04"))
+	}
+	return text
+}
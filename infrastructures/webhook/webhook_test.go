@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+func Test_client_Notify(t *testing.T) {
+	pgram := program.Program{
+		UUID:       "b7750840-3407-44a0-b670-2b08cb8e0eb3",
+		ID:         514530,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 10, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 10, 0, 30, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusFailed,
+		StreamType: program.StreamTypeBroadcast,
+	}
+	n := notification.Notification{
+		Event:   notification.EventRecFailed,
+		Time:    time.Date(2022, 8, 10, 0, 31, 0, 0, timeutil.LocationJST()),
+		Program: &pgram,
+		Message: "rec failed after 3 retries",
+	}
+
+	tests := []struct {
+		name string
+		// path -> 失敗させる回数
+		failCount map[string]int
+		// 失敗させるときの status code（0 であれば 500）
+		failStatus int
+		targets    func(baseURL string) []Target
+		// path -> 受け取った body
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "形式毎の payload を送る",
+			targets: func(baseURL string) []Target {
+				return []Target{
+					{URL: baseURL + "/generic", Format: FormatGeneric},
+					{URL: baseURL + "/slack", Format: FormatSlack},
+					{URL: baseURL + "/discord", Format: FormatDiscord},
+				}
+			},
+			want: map[string]string{
+				"/generic": `{"event":"rec_failed","time":"2022-08-10T00:31:00+09:00","message":"rec failed after 3 retries","program":{"uuid":"b7750840-3407-44a0-b670-2b08cb8e0eb3","id":514530,"station":"agqr","title":"鷲崎健のヨルナイト×ヨルナイト","start":"2022-08-10T00:00:00+09:00","end":"2022-08-10T00:30:00+09:00","status":"failed","stream_type":"broadcast"}}`,
+				"/slack":   `{"text":"[anrd] rec_failed: rec failed after 3 retries\nagqr 鷲崎健のヨルナイト×ヨルナイト  (2022-08-10 00:00)"}`,
+				"/discord": `{"content":"[anrd] rec_failed: rec failed after 3 retries\nagqr 鷲崎健のヨルナイト×ヨルナイト  (2022-08-10 00:00)"}`,
+			},
+		},
+		{
+			name: "対象外のイベントは送らない",
+			targets: func(baseURL string) []Target {
+				return []Target{
+					{URL: baseURL + "/started", Format: FormatGeneric, Events: []notification.Event{notification.EventRecStarted}},
+					{URL: baseURL + "/failed", Format: FormatSlack, Events: []notification.Event{notification.EventRecFailed}},
+				}
+			},
+			want: map[string]string{
+				"/failed": `{"text":"[anrd] rec_failed: rec failed after 3 retries\nagqr 鷲崎健のヨルナイト×ヨルナイト  (2022-08-10 00:00)"}`,
+			},
+		},
+		{
+			name:      "失敗してもリトライで送りきる",
+			failCount: map[string]int{"/slack": 2},
+			targets: func(baseURL string) []Target {
+				return []Target{
+					{URL: baseURL + "/slack", Format: FormatSlack},
+				}
+			},
+			want: map[string]string{
+				"/slack": `{"text":"[anrd] rec_failed: rec failed after 3 retries\nagqr 鷲崎健のヨルナイト×ヨルナイト  (2022-08-10 00:00)"}`,
+			},
+		},
+		{
+			name:      "リトライしきっても失敗すればエラー",
+			failCount: map[string]int{"/slack": 10},
+			targets: func(baseURL string) []Target {
+				return []Target{
+					{URL: baseURL + "/slack", Format: FormatSlack},
+				}
+			},
+			want:    map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:       "4xx は送り直さない",
+			failCount:  map[string]int{"/services/secret": 1},
+			failStatus: http.StatusNotFound,
+			targets: func(baseURL string) []Target {
+				return []Target{
+					{URL: baseURL + "/services/secret", Format: FormatSlack},
+				}
+			},
+			want:    map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:       "429 は送り直す",
+			failCount:  map[string]int{"/slack": 1},
+			failStatus: http.StatusTooManyRequests,
+			targets: func(baseURL string) []Target {
+				return []Target{
+					{URL: baseURL + "/slack", Format: FormatSlack},
+				}
+			},
+			want: map[string]string{
+				"/slack": `{"text":"[anrd] rec_failed: rec failed after 3 retries\nagqr 鷲崎健のヨルナイト×ヨルナイト  (2022-08-10 00:00)"}`,
+			},
+		},
+		{
+			name: "接続できなくてもエラーに URL のパスを含めない",
+			targets: func(baseURL string) []Target {
+				return []Target{
+					{URL: "http://127.0.0.1:0/services/secret", Format: FormatSlack},
+				}
+			},
+			want:    map[string]string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			got := map[string]string{}
+			failCount := map[string]int{}
+			for k, v := range tt.failCount {
+				failCount[k] = v
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if failCount[r.URL.Path] > 0 {
+					failCount[r.URL.Path]--
+					status := http.StatusInternalServerError
+					if tt.failStatus != 0 {
+						status = tt.failStatus
+					}
+					w.WriteHeader(status)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				if !json.Valid(body) {
+					t.Errorf("invalid json: %s", body)
+				}
+				got[r.URL.Path] = string(body)
+			}))
+			defer server.Close()
+
+			c := &client{
+				httpClient:    server.Client(),
+				targets:       tt.targets(server.URL),
+				retryMaxCount: 3,
+				retryInterval: 1 * time.Millisecond,
+			}
+			err := c.Notify(context.Background(), n)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("client.Notify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			// webhook の URL はそれ自体が秘密
+			if err != nil && strings.Contains(err.Error(), "secret") {
+				t.Errorf("client.Notify() error = %v, want no webhook url path", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("client.Notify() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
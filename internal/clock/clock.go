@@ -0,0 +1,104 @@
+// 現在日時と時間待ちの抽象
+// テストでは Fake を使い、時間を進めて sleep やタイマーを決定的に動かす
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+
+	// d 経過するか ctx がキャンセルされるまで待つ
+	// キャンセルされれば ctx.Err() を返す
+	// d が 0 以下であればすぐに返る
+	Sleep(ctx context.Context, d time.Duration) error
+
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	// 既に発火または停止していれば false
+	Stop() bool
+	Reset(d time.Duration)
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+// 実際の時刻を使うもの
+func New() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c realClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, c, d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t *realTimer) Reset(d time.Duration) {
+	if !t.timer.Stop() {
+		// 発火済みで読まれていない値を捨てる
+		select {
+		case <-t.timer.C:
+		default:
+		}
+	}
+	t.timer.Reset(d)
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+func sleep(ctx context.Context, c Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Advance などで進めない限り時間が止まっている Clock
+// タイマーやティッカーは、Advance によって発火日時を過ぎたときに発火する
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	// waiters が変化したときに通知する
+	changed chan struct{}
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+type fakeWaiter struct {
+	clock *Fake
+	at    time.Time
+	// 0 であればタイマー
+	period time.Duration
+	c      chan time.Time
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, f, d)
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{clock: f, c: make(chan time.Time, 1)}
+	w.Reset(d)
+	return w
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{clock: f, at: f.now.Add(d), period: d, c: make(chan time.Time, 1)}
+	f.addLocked(w)
+	return fakeTicker{w}
+}
+
+type fakeTicker struct {
+	waiter *fakeWaiter
+}
+
+func (t fakeTicker) C() <-chan time.Time {
+	return t.waiter.c
+}
+
+func (t fakeTicker) Stop() {
+	t.waiter.Stop()
+}
+
+// d だけ時間を進め、その間に発火日時を迎えたタイマーとティッカーを発火日時の順に発火させる
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(f.now.Add(d))
+}
+
+// t まで時間を進める
+// t が現在より前であれば何もしない
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t.Before(f.now) {
+		return
+	}
+	f.setLocked(t)
+}
+
+// 発火を待っているタイマーとティッカー（Sleep 中のものを含む）が n 個以上になるまで待つ
+// 別の goroutine が Sleep し始めたことを確認してから Advance するために使う
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		count := len(f.waiters)
+		changed := f.changed
+		f.mu.Unlock()
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+func (f *Fake) setLocked(t time.Time) {
+	for {
+		sort.SliceStable(f.waiters, func(i, j int) bool {
+			return f.waiters[i].at.Before(f.waiters[j].at)
+		})
+		if len(f.waiters) == 0 || f.waiters[0].at.After(t) {
+			break
+		}
+
+		w := f.waiters[0]
+		f.now = w.at
+		// 読まれていなければ捨てる（time.Ticker と同じ）
+		select {
+		case w.c <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.removeLocked(w)
+		}
+	}
+	f.now = t
+}
+
+func (f *Fake) addLocked(w *fakeWaiter) {
+	f.waiters = append(f.waiters, w)
+	f.notifyLocked()
+}
+
+// 含まれていれば true
+func (f *Fake) removeLocked(w *fakeWaiter) bool {
+	for i, waiter := range f.waiters {
+		if waiter == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.notifyLocked()
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fake) notifyLocked() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.removeLocked(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) {
+	f := w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.removeLocked(w)
+	select {
+	case <-w.c:
+	default:
+	}
+	w.at = f.now.Add(d)
+	if d <= 0 {
+		w.c <- w.at
+		return
+	}
+	f.addLocked(w)
+}
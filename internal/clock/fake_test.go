@@ -0,0 +1,101 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFake_Sleep(t *testing.T) {
+	start := time.Date(2022, 8, 17, 23, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	done := make(chan error)
+	go func() {
+		done <- f.Sleep(context.Background(), 10*time.Minute)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(9 * time.Minute)
+	select {
+	case <-done:
+		t.Fatal("Sleep() returned before the duration elapsed")
+	default:
+	}
+
+	f.Advance(1 * time.Minute)
+	err := <-done
+	if err != nil {
+		t.Errorf("Sleep() error = %v", err)
+	}
+	if got, want := f.Now(), start.Add(10*time.Minute); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+}
+
+func TestFake_Sleep_cancel(t *testing.T) {
+	f := NewFake(time.Date(2022, 8, 17, 23, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- f.Sleep(ctx, 10*time.Minute)
+	}()
+
+	f.BlockUntil(1)
+	cancel()
+	err := <-done
+	if err != context.Canceled {
+		t.Errorf("Sleep() error = %v, want %v", err, context.Canceled)
+	}
+	// キャンセルされたものは待っていない
+	f.BlockUntil(0)
+}
+
+func TestFake_Ticker(t *testing.T) {
+	start := time.Date(2022, 8, 17, 23, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	ticker := f.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	f.Advance(1 * time.Minute)
+	got := <-ticker.C()
+	if want := start.Add(1 * time.Minute); !got.Equal(want) {
+		t.Errorf("tick = %v, want %v", got, want)
+	}
+
+	// 読まれなかった tick は捨てられる
+	f.Advance(3 * time.Minute)
+	got = <-ticker.C()
+	if want := start.Add(2 * time.Minute); !got.Equal(want) {
+		t.Errorf("tick = %v, want %v", got, want)
+	}
+	select {
+	case got := <-ticker.C():
+		t.Errorf("unexpected tick %v", got)
+	default:
+	}
+}
+
+func TestFake_Timer_Reset(t *testing.T) {
+	start := time.Date(2022, 8, 17, 23, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	timer := f.NewTimer(1 * time.Minute)
+	timer.Reset(5 * time.Minute)
+
+	f.Advance(1 * time.Minute)
+	select {
+	case got := <-timer.C():
+		t.Errorf("timer fired at %v before reset duration", got)
+	default:
+	}
+
+	f.Advance(4 * time.Minute)
+	got := <-timer.C()
+	if want := start.Add(5 * time.Minute); !got.Equal(want) {
+		t.Errorf("timer = %v, want %v", got, want)
+	}
+	if timer.Stop() {
+		t.Error("Stop() = true for fired timer, want false")
+	}
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		want    func(c *Config)
+		wantErr error
+	}{
+		{
+			name: "設定ファイルに書かれていない項目はデフォルト値",
+			yaml: `
+archive_dir: /archive
+recorder:
+  margin: 30s
+stations:
+  agqr:
+    ffmpeg_loglevel: error
+webhook:
+  targets:
+    - url: https://hooks.slack.test/services/xxx
+      format: slack
+      events: [rec_failed, disk_low]
+retention:
+  - station: agqr
+    title: ヨルナイト
+    keep_last: 5
+`,
+			want: func(c *Config) {
+				c.ArchiveDir = "/archive"
+				c.Recorder.Margin = 30 * time.Second
+				c.Stations.Agqr.FfmpegLoglevel = "error"
+				c.Webhook.Targets = []WebhookTarget{
+					{URL: "https://hooks.slack.test/services/xxx", Format: "slack", Events: []string{"rec_failed", "disk_low"}},
+				}
+				c.Retention = []RetentionRule{
+					{Station: "agqr", Title: "ヨルナイト", KeepLast: 5},
+				}
+			},
+		},
+		{
+			name: "環境変数が設定ファイルより優先される",
+			yaml: `
+archive_dir: /archive
+recorder:
+  margin: 30s
+s3:
+  endpoint: s3.test
+  bucket: from-file
+`,
+			env: map[string]string{
+				"ATR_MARGIN":                 "2m",
+				"ATR_S3_BUCKET":              "from-env",
+				"ATR_AGQR_STREAM_URL":        "https://stream.test/agqr.m3u8",
+				"ATR_REC_BROADCAST_INTERVAL": "10s",
+			},
+			want: func(c *Config) {
+				c.ArchiveDir = "/archive"
+				c.Recorder.Margin = 2 * time.Minute
+				c.S3.Endpoint = "s3.test"
+				c.S3.Bucket = "from-env"
+				c.Stations.Agqr.StreamURL = "https://stream.test/agqr.m3u8"
+				c.Jobs.RecBroadcastInterval = 10 * time.Second
+			},
+		},
+		{
+			name:    "知らないキーはエラー",
+			yaml:    "recoder:\n  margin: 30s\n",
+			wantErr: errutil.ErrConfig,
+		},
+		{
+			name:    "検証に失敗すればエラー",
+			yaml:    "recorder:\n  ondemand_limit: 0\n",
+			wantErr: errutil.ErrConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			err := os.WriteFile(path, []byte(tt.yaml), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Load(path)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			want := Default()
+			tt.want(&want)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantMsg string
+	}{
+		{
+			name:    "デフォルト値は問題ない",
+			modify:  func(c *Config) {},
+			wantMsg: "",
+		},
+		{
+			name: "問題のある項目をすべて返す",
+			modify: func(c *Config) {
+				c.Recorder.Margin = -1 * time.Minute
+				c.Recorder.ContinuousCapture = true
+				c.Recorder.ContinuousCaptureMax = 0
+				c.Stations.Onsen.FfmpegLoglevel = "loud"
+				c.Webhook.Targets = []WebhookTarget{{URL: "hooks.slack.test", Format: "teams", Events: []string{"rec_done"}}}
+				c.Retention = []RetentionRule{{Station: "radiko"}}
+				c.Redundant = []RedundantRule{{Station: "radiko"}}
+				c.Stations.Agqr.SecondaryStreamURL = "stream.test/agqr.m3u8"
+			},
+			wantMsg: `recorder.margin: must not be negative; ` +
+				`recorder.continuous_capture_max: must be positive when recorder.continuous_capture is true; ` +
+				`stations.onsen.ffmpeg_loglevel: unknown loglevel "loud" (quiet, panic, fatal, error, warning, info, verbose, debug, trace); ` +
+				`webhook.targets[0].url: must be http(s) URL: "hooks.slack.test"; ` +
+				`webhook.targets[0].format: unknown format "teams" (generic, slack, discord); ` +
+				`webhook.targets[0].events: unknown event "rec_done"; ` +
+				`retention[0].station: unknown station "radiko"; ` +
+				`retention[0]: at least one of keep_last, max_age_days, max_total_size_mib must be set; ` +
+				`redundant[0].station: unknown station "radiko"; ` +
+				`stations.agqr.secondary_stream_url: must be http(s) URL: "stream.test/agqr.m3u8": invalid config`,
+		},
+		{
+			name: "player_url があれば stream_url_cache_ttl が必要",
+			modify: func(c *Config) {
+				c.Stations.Agqr.StreamURLCacheTTL = 0
+			},
+			wantMsg: `stations.agqr.stream_url_cache_ttl: must be positive when stations.agqr.player_url is set: invalid config`,
+		},
+		{
+			name: "postgres では postgres_dsn が必要",
+			modify: func(c *Config) {
+				c.DatabaseDriver = DatabaseDriverPostgres
+				c.SqlitePath = ""
+			},
+			wantMsg: `postgres_dsn: must not be empty when database_driver is postgres: invalid config`,
+		},
+		{
+			name: "s3 は endpoint が設定されているときのみ検証する",
+			modify: func(c *Config) {
+				c.S3.Endpoint = "s3.test"
+				c.S3.KeyTemplate = "{{.Station"
+			},
+			wantMsg: `s3.bucket: must not be empty when s3.endpoint is set; ` +
+				`s3.key_template: invalid template: template: :1: unclosed action: invalid config`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.modify(&c)
+			err := c.Validate()
+			gotMsg := ""
+			if err != nil {
+				gotMsg = err.Error()
+			}
+			if gotMsg != tt.wantMsg {
+				t.Errorf("Config.Validate() error = %v, want %v", gotMsg, tt.wantMsg)
+			}
+		})
+	}
+}
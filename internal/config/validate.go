@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+// ffmpeg -loglevel に指定できるもの
+var ffmpegLoglevels = []string{"quiet", "panic", "fatal", "error", "warning", "info", "verbose", "debug", "trace"}
+
+// 問題のある項目をすべてまとめて返す
+// 返されるエラー
+// - errutil.ErrConfig
+func (c Config) Validate() error {
+	var v validator
+
+	switch c.DatabaseDriver {
+	case DatabaseDriverSqlite:
+		v.check(c.SqlitePath != "", "sqlite_path", "must not be empty when database_driver is sqlite")
+	case DatabaseDriverPostgres:
+		v.check(c.PostgresDSN != "", "postgres_dsn", "must not be empty when database_driver is postgres")
+	default:
+		v.add("database_driver", fmt.Sprintf("unknown driver %q (sqlite, postgres)", c.DatabaseDriver))
+	}
+	v.check(c.ArchiveDir != "", "archive_dir", "must not be empty")
+
+	v.check(c.Recorder.PrepareAfter > 0, "recorder.prepare_after", "must be positive")
+	v.check(c.Recorder.Margin >= 0, "recorder.margin", "must not be negative")
+	v.check(c.Recorder.RetryMaxCount >= 0, "recorder.retry_max_count", "must not be negative")
+	v.check(c.Recorder.ResumeBackoff >= 0, "recorder.resume_backoff", "must not be negative")
+	v.check(c.Recorder.MaxGap >= 0, "recorder.max_gap", "must not be negative")
+	v.check(!c.Recorder.ContinuousCapture || c.Recorder.ContinuousCaptureMax > 0, "recorder.continuous_capture_max", "must be positive when recorder.continuous_capture is true")
+	v.check(c.Recorder.OndemandLimit > 0, "recorder.ondemand_limit", "must be positive")
+	v.check(c.Recorder.OndemandInterval >= 0, "recorder.ondemand_interval", "must not be negative")
+	v.check(c.Recorder.DiskCheckInterval >= 0, "recorder.disk_check_interval", "must not be negative")
+	v.check(c.Recorder.RecordingLogRetentionDays >= 0, "recorder.recording_log_retention_days", "must not be negative")
+	v.check(c.Recorder.StallTimeout >= 0, "recorder.stall_timeout", "must not be negative")
+	v.check(c.Recorder.DeadlineGrace >= 0, "recorder.deadline_grace", "must not be negative")
+	v.check(c.Recorder.OndemandTimeout >= 0, "recorder.ondemand_timeout", "must not be negative")
+	v.checkFfmpegLoglevel("recorder.ffmpeg_loglevel", c.Recorder.FfmpegLoglevel, false)
+
+	v.check(c.Jobs.UpdateInterval > 0, "jobs.update_interval", "must be positive")
+	v.check(c.Jobs.RecOndemandInterval > 0, "jobs.rec_ondemand_interval", "must be positive")
+	v.check(c.Jobs.RecBroadcastInterval > 0, "jobs.rec_broadcast_interval", "must be positive")
+	v.check(c.Jobs.RetentionInterval > 0, "jobs.retention_interval", "must be positive")
+	v.check(c.Jobs.StartDelay >= 0, "jobs.start_delay", "must not be negative")
+
+	v.checkURL("stations.agqr.program_url", c.Stations.Agqr.ProgramURL)
+	v.checkURL("stations.agqr.stream_url", c.Stations.Agqr.StreamURL)
+	if c.Stations.Agqr.PlayerURL != "" {
+		v.checkURL("stations.agqr.player_url", c.Stations.Agqr.PlayerURL)
+		v.check(c.Stations.Agqr.StreamURLCacheTTL > 0, "stations.agqr.stream_url_cache_ttl", "must be positive when stations.agqr.player_url is set")
+	}
+	v.checkFfmpegLoglevel("stations.agqr.ffmpeg_loglevel", c.Stations.Agqr.FfmpegLoglevel, true)
+	v.checkURL("stations.onsen.program_url", c.Stations.Onsen.ProgramURL)
+	v.checkFfmpegLoglevel("stations.onsen.ffmpeg_loglevel", c.Stations.Onsen.FfmpegLoglevel, true)
+
+	if c.S3.Endpoint != "" {
+		v.check(c.S3.Bucket != "", "s3.bucket", "must not be empty when s3.endpoint is set")
+		// S3 の multipart upload はパートあたり最小 5MiB
+		v.check(c.S3.PartSizeMiB == 0 || c.S3.PartSizeMiB >= 5, "s3.part_size_mib", "must be 0 or at least 5")
+		_, err := template.New("").Option("missingkey=error").Parse(c.S3.KeyTemplate)
+		v.check(err == nil, "s3.key_template", fmt.Sprintf("invalid template: %v", err))
+	}
+
+	v.check(c.Webhook.RetryMaxCount >= 0, "webhook.retry_max_count", "must not be negative")
+	for i, target := range c.Webhook.Targets {
+		field := fmt.Sprintf("webhook.targets[%d]", i)
+		v.checkURL(field+".url", target.URL)
+		switch target.Format {
+		case "", "generic", "slack", "discord":
+		default:
+			v.add(field+".format", fmt.Sprintf("unknown format %q (generic, slack, discord)", target.Format))
+		}
+		for _, event := range target.Events {
+			v.check(notification.Event(event).Valid(), field+".events", fmt.Sprintf("unknown event %q", event))
+		}
+	}
+
+	for i, rule := range c.Retention {
+		field := fmt.Sprintf("retention[%d]", i)
+		if rule.Station != "" && !program.Station(rule.Station).Valid() {
+			v.add(field+".station", fmt.Sprintf("unknown station %q", rule.Station))
+		}
+		v.check(rule.KeepLast >= 0, field+".keep_last", "must not be negative")
+		v.check(rule.MaxAgeDays >= 0, field+".max_age_days", "must not be negative")
+		v.check(rule.MaxTotalSizeMiB >= 0, field+".max_total_size_mib", "must not be negative")
+		v.check(rule.KeepLast != 0 || rule.MaxAgeDays != 0 || rule.MaxTotalSizeMiB != 0, field, "at least one of keep_last, max_age_days, max_total_size_mib must be set")
+	}
+
+	for i, rule := range c.Redundant {
+		if rule.Station != "" && !program.Station(rule.Station).Valid() {
+			v.add(fmt.Sprintf("redundant[%d].station", i), fmt.Sprintf("unknown station %q", rule.Station))
+		}
+	}
+	if c.Stations.Agqr.SecondaryStreamURL != "" {
+		v.checkURL("stations.agqr.secondary_stream_url", c.Stations.Agqr.SecondaryStreamURL)
+	}
+
+	if c.HTTPAddr != "" {
+		v.checkURL("feed.base_url", c.Feed.BaseURL)
+	}
+	if c.Feed.ObjectBaseURL != "" {
+		v.checkURL("feed.object_base_url", c.Feed.ObjectBaseURL)
+	}
+	v.check(c.Calendar.PastDays >= 0, "calendar.past_days", "must not be negative")
+	v.check(c.XMLTV.PastDays >= 0, "xmltv.past_days", "must not be negative")
+	v.check(c.Health.ReadyUpdateThreshold > 0, "health.ready_update_threshold", "must be positive")
+
+	return v.err()
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(field string, msg string) {
+	v.problems = append(v.problems, fmt.Sprintf("%s: %s", field, msg))
+}
+
+func (v *validator) check(ok bool, field string, msg string) {
+	if !ok {
+		v.add(field, msg)
+	}
+}
+
+func (v *validator) checkURL(field string, rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		v.add(field, err.Error())
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		v.add(field, fmt.Sprintf("must be http(s) URL: %q", rawURL))
+	}
+}
+
+func (v *validator) checkFfmpegLoglevel(field string, loglevel string, allowEmpty bool) {
+	if loglevel == "" && allowEmpty {
+		return
+	}
+	for _, l := range ffmpegLoglevels {
+		if loglevel == l {
+			return
+		}
+	}
+	v.add(field, fmt.Sprintf("unknown loglevel %q (%s)", loglevel, strings.Join(ffmpegLoglevels, ", ")))
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return errors.Wrap(errutil.ErrConfig, strings.Join(v.problems, "; "))
+}
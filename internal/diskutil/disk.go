@@ -0,0 +1,28 @@
+package diskutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// path を含むファイルシステムの空き容量（byte）を返す
+// path がまだ存在しなければ、存在する親ディレクトリまで遡って調べる
+// 返されるエラー
+// - errutil.ErrUnsupported（空き容量を調べられないプラットフォーム）
+func FreeBytes(path string) (uint64, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return freeBytes(dir)
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || dragonfly)
+
+package diskutil
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+func freeBytes(dir string) (uint64, error) {
+	return 0, errors.Wrapf(errutil.ErrUnsupported, "free disk space (GOOS = %s)", runtime.GOOS)
+}
@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package diskutil
+
+import "golang.org/x/sys/unix"
+
+// Statfs_t に Bavail と Bsize があるものだけ
+// openbsd（F_bavail）や netbsd, solaris（Statvfs）はフィールドが異なるので disk_other.go で未対応とする
+func freeBytes(dir string) (uint64, error) {
+	var stat unix.Statfs_t
+	err := unix.Statfs(dir, &stat)
+	if err != nil {
+		return 0, err
+	}
+	// freebsd などでは root 用の予約領域まで使っていると負になる
+	if int64(stat.Bavail) < 0 {
+		return 0, nil
+	}
+	// root 以外が使える容量
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
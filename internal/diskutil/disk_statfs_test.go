@@ -0,0 +1,36 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package diskutil
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFreeBytes(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "存在するディレクトリ",
+			path: dir,
+		},
+		{
+			name: "まだ存在しなければ親ディレクトリで調べる",
+			path: filepath.Join(dir, "agqr", "鷲崎健のヨルナイト×ヨルナイト"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FreeBytes(tt.path)
+			if err != nil {
+				t.Fatalf("FreeBytes() error = %v", err)
+			}
+			if got == 0 {
+				t.Errorf("FreeBytes() = %d, want > 0", got)
+			}
+		})
+	}
+}
@@ -11,7 +11,21 @@ var (
 	ErrDatabasePrepare         = NewInternalError("database prepare error")
 	ErrDatabaseNotFoundProgram = NewInternalError("not found program in database")
 	ErrFfmpeg                  = NewInternalError("ffmpeg error")
+	ErrFfmpegStalled           = NewInternalError("ffmpeg stalled")
+	ErrFfmpegDeadline          = NewInternalError("ffmpeg exceeded deadline")
 	ErrScheduler               = NewInternalError("scheduler error")
+	ErrDiskSpaceShortage       = NewInternalError("disk space shortage")
+	ErrObjectStorage           = NewInternalError("object storage error")
+	ErrChecksumMismatch        = NewInternalError("checksum mismatch")
+	ErrNotify                  = NewInternalError("notify error")
+	ErrConfig                  = NewInternalError("invalid config")
+	ErrInvalidStatus           = NewInternalError("invalid program status")
+	ErrGuideNotFoundProgram    = NewInternalError("not found program in guide")
+	ErrRecFailed               = NewInternalError("rec failed")
+	ErrNotScheduled            = NewInternalError("program is not waiting for rec")
+	ErrStreamNotFound          = NewInternalError("not found stream url")
+	ErrInvalidImport           = NewInternalError("invalid import data")
+	ErrUnsupported             = NewInternalError("unsupported on this platform")
 	// 分類できない系
 	ErrInternal = NewInternalError("internal something error")
 )
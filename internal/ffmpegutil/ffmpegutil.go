@@ -0,0 +1,212 @@
+// ffmpeg を -progress の出力を見ながら動かす
+// 出力が増えなくなった（止まった）ものや、時間内に終わらないものは kill する
+package ffmpegutil
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/metrics"
+)
+
+// 進み具合をログに出す間隔
+const progressLogInterval = 1 * time.Minute
+
+type Options struct {
+	// 出力ファイルがこの間大きくならなければ kill する
+	// 0 であれば確認しない
+	StallTimeout time.Duration
+
+	// 開始からこの間に終わらなければ kill する
+	// 0 であれば制限しない
+	Timeout time.Duration
+
+	// nil であれば実際の時刻
+	Clock clock.Clock
+}
+
+type progressKey struct{}
+
+// ctx で Run した ffmpeg の進み具合を report に渡すようにする
+func WithProgress(ctx context.Context, report func(recorder.Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// WithProgress で渡されたものに p を渡す
+// 渡されていなければ何もしない
+func ReportProgress(ctx context.Context, p recorder.Progress) {
+	report, ok := ctx.Value(progressKey{}).(func(recorder.Progress))
+	if !ok {
+		return
+	}
+	report(p)
+}
+
+// args（args[0] は ffmpeg）を -progress を付けて実行する
+// ctx がキャンセルされても ffmpeg は止めない
+// 返されるエラー
+// - errutil.ErrFfmpeg
+// - errutil.ErrFfmpegStalled
+// - errutil.ErrFfmpegDeadline
+func Run(ctx context.Context, args []string, opts Options) error {
+	clk := opts.Clock
+	if clk == nil {
+		clk = clock.New()
+	}
+
+	cmd := exec.Command(args[0], progressArgs(args)[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.Wrap(errutil.ErrFfmpeg, err.Error())
+	}
+	// https://github.com/rs/zerolog/issues/398
+	// log.Level(zerolog.InfoLevel).With().Logger() などとしても
+	// 出力されるログに loglevel が含まれない
+	cmd.Stderr = log.Ctx(ctx).With().Str("level", zerolog.LevelWarnValue).Logger()
+
+	// 録画ログにも残るので、URL のトークンなどは伏せる
+	log.Ctx(ctx).Info().Msgf("ffmpeg command: %s", strings.Join(RedactArgs(progressArgs(args)), " "))
+	err = cmd.Start()
+	if err != nil {
+		return errors.Wrap(errutil.ErrFfmpeg, err.Error())
+	}
+	startedAt := clk.Now()
+
+	progressCh := make(chan recorder.Progress)
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		readProgress(stdout, func(p recorder.Progress) {
+			progressCh <- p
+		})
+	}()
+
+	var timeout <-chan time.Time
+	if opts.Timeout > 0 {
+		timer := clk.NewTimer(opts.Timeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+	var stallCheck <-chan time.Time
+	if opts.StallTimeout > 0 {
+		ticker := clk.NewTicker(checkInterval(opts.StallTimeout))
+		defer ticker.Stop()
+		stallCheck = ticker.C()
+	}
+
+	var (
+		lastSize   int64
+		lastGrowth = startedAt
+		lastLogged time.Time
+		killErr    error
+	)
+	for killErr == nil {
+		select {
+		case p := <-progressCh:
+			now := clk.Now()
+			p.UpdatedAt = now
+			if p.TotalSize > lastSize {
+				lastSize = p.TotalSize
+				lastGrowth = now
+			}
+			ReportProgress(ctx, p)
+			if now.Sub(lastLogged) >= progressLogInterval {
+				lastLogged = now
+				log.Ctx(ctx).Info().Msgf("ffmpeg progress: time = %s, bitrate = %.1fkbits/s, size = %d", p.OutTime, p.Bitrate, p.TotalSize)
+			}
+			continue
+		case <-readDone:
+		case now := <-stallCheck:
+			if now.Sub(lastGrowth) < opts.StallTimeout {
+				continue
+			}
+			metrics.FfmpegKilledTotal.WithLabelValues("stalled").Inc()
+			killErr = errors.Wrapf(errutil.ErrFfmpegStalled, "output has not grown for %s (size = %d)", now.Sub(lastGrowth).Round(time.Second), lastSize)
+		case <-timeout:
+			metrics.FfmpegKilledTotal.WithLabelValues("deadline").Inc()
+			killErr = errors.Wrapf(errutil.ErrFfmpegDeadline, "not finished in %s", opts.Timeout)
+		}
+		break
+	}
+
+	if killErr != nil {
+		log.Ctx(ctx).Error().Msgf("kill ffmpeg: %+v", killErr)
+		_ = cmd.Process.Kill()
+		// 読み終わるまで progressCh を捨てる
+		go func() {
+			for range progressCh {
+			}
+		}()
+		<-readDone
+		close(progressCh)
+		_ = cmd.Wait()
+		return killErr
+	}
+
+	err = cmd.Wait()
+	if err != nil {
+		return errors.Wrap(errutil.ErrFfmpeg, err.Error())
+	}
+	return nil
+}
+
+// args[0] の直後に -progress を差し込む
+// 毎秒の統計（stderr）は -progress と重複するので出さない
+func progressArgs(args []string) []string {
+	withProgress := []string{args[0], "-progress", "pipe:1", "-nostats"}
+	return append(withProgress, args[1:]...)
+}
+
+// StallTimeout より十分細かく、1 秒より粗くはしない
+func checkInterval(stallTimeout time.Duration) time.Duration {
+	interval := stallTimeout / 4
+	if interval > 1*time.Second {
+		return 1 * time.Second
+	}
+	if interval <= 0 {
+		return stallTimeout
+	}
+	return interval
+}
+
+// -progress の key=value 行を読み、progress=continue（または end）ごとに report を呼ぶ
+// 値が N/A のものはゼロ値のまま
+func readProgress(r io.Reader, report func(recorder.Progress)) {
+	var p recorder.Progress
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "out_time_us":
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil {
+				p.OutTime = time.Duration(us) * time.Microsecond
+			}
+		case "total_size":
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil {
+				p.TotalSize = size
+			}
+		case "bitrate":
+			if bitrate, err := strconv.ParseFloat(strings.TrimSuffix(value, "kbits/s"), 64); err == nil {
+				p.Bitrate = bitrate
+			}
+		case "progress":
+			report(p)
+			p = recorder.Progress{}
+		}
+	}
+}
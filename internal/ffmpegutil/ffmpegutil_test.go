@@ -0,0 +1,144 @@
+package ffmpegutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+func Test_readProgress(t *testing.T) {
+	input := `frame=0
+fps=0.00
+stream_0_0_q=-1.0
+bitrate=N/A
+total_size=N/A
+out_time_us=N/A
+out_time=N/A
+speed=N/A
+progress=continue
+frame=0
+bitrate= 129.4kbits/s
+total_size=1048576
+out_time_us=64800000
+out_time_ms=64800000
+out_time=00:01:04.800000
+speed=1.01x
+progress=end
+`
+	var got []recorder.Progress
+	readProgress(strings.NewReader(input), func(p recorder.Progress) {
+		got = append(got, p)
+	})
+	want := []recorder.Progress{
+		{},
+		{OutTime: 64800 * time.Millisecond, Bitrate: 129.4, TotalSize: 1048576},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readProgress() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// ffmpeg の代わりに、引数を無視して script を実行する
+func fakeFfmpeg(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		opts   Options
+		// nil であれば成功
+		wantErr  error
+		wantLast recorder.Progress
+	}{
+		{
+			name: "終了するまで進み具合を報告する",
+			script: `echo total_size=1024; echo out_time_us=1000000; echo progress=continue
+echo total_size=2048; echo out_time_us=2000000; echo bitrate=128.0kbits/s; echo progress=end
+`,
+			opts:     Options{StallTimeout: 5 * time.Second, Timeout: 5 * time.Second},
+			wantLast: recorder.Progress{OutTime: 2 * time.Second, Bitrate: 128, TotalSize: 2048},
+		},
+		{
+			name: "出力が増えなくなれば kill する",
+			script: `echo total_size=1024; echo progress=continue
+exec sleep 10
+`,
+			opts:     Options{StallTimeout: 200 * time.Millisecond, Timeout: 5 * time.Second},
+			wantErr:  errutil.ErrFfmpegStalled,
+			wantLast: recorder.Progress{TotalSize: 1024},
+		},
+		{
+			name: "時間内に終わらなければ kill する",
+			script: `size=0
+while true; do size=$((size + 1024)); echo total_size=$size; echo progress=continue; sleep 0.05; done
+`,
+			opts:    Options{StallTimeout: 5 * time.Second, Timeout: 300 * time.Millisecond},
+			wantErr: errutil.ErrFfmpegDeadline,
+		},
+		{
+			name:    "異常終了すればエラー",
+			script:  "exit 1\n",
+			wantErr: errutil.ErrFfmpeg,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				last recorder.Progress
+			)
+			ctx := WithProgress(context.Background(), func(p recorder.Progress) {
+				mu.Lock()
+				defer mu.Unlock()
+				last = p
+			})
+
+			err := Run(ctx, []string{fakeFfmpeg(t, tt.script), "-i", "in.m3u8", "out.ts"}, tt.opts)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.wantErr == errutil.ErrFfmpegDeadline {
+				// kill されるまで報告されている
+				if last.TotalSize == 0 {
+					t.Errorf("progress is not reported")
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.wantLast, last, cmpopts.IgnoreFields(recorder.Progress{}, "UpdatedAt")); diff != "" {
+				t.Errorf("last progress mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_progressArgs(t *testing.T) {
+	got := progressArgs([]string{"ffmpeg", "-y", "-i", "in.m3u8", "out.ts"})
+	want := []string{"ffmpeg", "-progress", "pipe:1", "-nostats", "-y", "-i", "in.m3u8", "out.ts"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("progressArgs() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,72 @@
+package ffmpegutil
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// 値を伏せる HTTP ヘッダ（小文字）
+var secretHeaders = map[string]bool{
+	"authorization":       true,
+	"cookie":              true,
+	"proxy-authorization": true,
+}
+
+// ログに出すために、ffmpeg の引数から秘密になりうるものを伏せる
+// - URL のクエリの値とパスワード
+// - -headers の Authorization や Cookie などの値
+func RedactArgs(args []string) []string {
+	redactedArgs := make([]string, len(args))
+	for i, arg := range args {
+		if i > 0 && args[i-1] == "-headers" {
+			redactedArgs[i] = redactHeaders(arg)
+			continue
+		}
+		redactedArgs[i] = redactURL(arg)
+	}
+	return redactedArgs
+}
+
+func redactURL(arg string) string {
+	u, err := url.Parse(arg)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return arg
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{redacted}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// ログに出すために、URL の host より後ろをすべて伏せる
+// webhook の URL のように、パスそのものが秘密になるもの向け
+func RedactURLPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// ffmpeg の -headers は CRLF 区切り
+func redactHeaders(arg string) string {
+	lines := strings.Split(arg, "\r\n")
+	for i, line := range lines {
+		name, _, ok := strings.Cut(line, ":")
+		if ok && secretHeaders[strings.ToLower(strings.TrimSpace(name))] {
+			lines[i] = name + ": " + redacted
+		}
+	}
+	return strings.Join(lines, "\r\n")
+}
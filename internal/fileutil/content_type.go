@@ -0,0 +1,26 @@
+package fileutil
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// mime.TypeByExtension に任せられない拡張子
+// .ts は環境によって text/vnd.trolltech.linguist（Qt の翻訳ファイル）になってしまう
+var contentTypes = map[string]string{
+	".ts": "video/mp2t",
+}
+
+// path の拡張子から Content-Type を決める
+// わからなければ application/octet-stream
+func ContentType(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,39 @@
+package fileutil
+
+import "testing"
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "ts は MPEG-TS",
+			path: "/archive/agqr/鷲崎健のヨルナイト×ヨルナイト.ts",
+			want: "video/mp2t",
+		},
+		{
+			name: "大文字の拡張子",
+			path: "/archive/agqr/file.TS",
+			want: "video/mp2t",
+		},
+		{
+			name: "それ以外は mime.TypeByExtension",
+			path: "/archive/onsen/file.mp4",
+			want: "video/mp4",
+		},
+		{
+			name: "拡張子がなければ application/octet-stream",
+			path: "/archive/onsen/file",
+			want: "application/octet-stream",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContentType(tt.path); got != tt.want {
+				t.Errorf("ContentType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
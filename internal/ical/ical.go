@@ -0,0 +1,127 @@
+// RFC 5545 の iCalendar を書き出す
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const prodID = "-//sobadon//anrd//JA"
+
+type EventStatus string
+
+const (
+	EventStatusConfirmed = EventStatus("CONFIRMED")
+	EventStatusCancelled = EventStatus("CANCELLED")
+)
+
+type Calendar struct {
+	// X-WR-CALNAME
+	Name string
+	// 書き出した日時（DTSTAMP）
+	Stamp  time.Time
+	Events []Event
+}
+
+type Event struct {
+	// 更新されても変わらないもの
+	UID         string
+	Summary     string
+	Description string
+	// 空であれば出力しない
+	URL string
+
+	// AllDay であれば Start の日付の終日の予定とし、End は使わない
+	Start  time.Time
+	End    time.Time
+	AllDay bool
+
+	Status EventStatus
+	// 変更される毎に増やす
+	Sequence int
+}
+
+func Encode(w io.Writer, cal Calendar) error {
+	var b strings.Builder
+	line := func(name string, value string) {
+		writeFolded(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escapeText(cal.Name))
+	}
+	for _, ev := range cal.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escapeText(ev.UID))
+		line("DTSTAMP", formatDateTime(cal.Stamp))
+		if ev.AllDay {
+			line("DTSTART;VALUE=DATE", formatDate(ev.Start))
+			line("DTEND;VALUE=DATE", formatDate(ev.Start.AddDate(0, 0, 1)))
+		} else {
+			line("DTSTART", formatDateTime(ev.Start))
+			line("DTEND", formatDateTime(ev.End))
+		}
+		line("SUMMARY", escapeText(ev.Summary))
+		if ev.Description != "" {
+			line("DESCRIPTION", escapeText(ev.Description))
+		}
+		if ev.URL != "" {
+			line("URL", ev.URL)
+		}
+		if ev.Status != "" {
+			line("STATUS", string(ev.Status))
+		}
+		line("SEQUENCE", fmt.Sprint(ev.Sequence))
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// UTC で 20060102T150405Z
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// t の location での日付
+func formatDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+// TEXT の値として \ ; , と改行を escape する
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// 1 行を 75 octet 以下に折り返し、CRLF で終える
+// マルチバイト文字の途中では折り返さない
+func writeFolded(b *strings.Builder, s string) {
+	const limit = 75
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			// 先頭の空白も数える
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	b.WriteString("\r\n")
+}
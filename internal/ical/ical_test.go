@@ -0,0 +1,93 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+func TestEncode(t *testing.T) {
+	cal := Calendar{
+		Name:  "anrd",
+		Stamp: time.Date(2022, 8, 17, 12, 0, 0, 0, timeutil.LocationJST()),
+		Events: []Event{
+			{
+				UID:         "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a@anrd",
+				Summary:     "鷲崎健のヨルナイト×ヨルナイト",
+				Description: "station: agqr\nstatus: scheduled",
+				Start:       time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+				End:         time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+				Status:      EventStatusConfirmed,
+			},
+			{
+				UID:      "e07df7c6-eae8-40f8-8922-6b7ef0497dc8@anrd",
+				Summary:  "セブンスパイス, 第1回; 配信",
+				Start:    time.Date(2022, 8, 9, 0, 0, 0, 0, timeutil.LocationJST()),
+				AllDay:   true,
+				Status:   EventStatusCancelled,
+				Sequence: 1,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Encode(&buf, cal)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:anrd\r\n",
+		"DTSTAMP:20220817T030000Z\r\n",
+		"DTSTART:20220817T140000Z\r\nDTEND:20220817T150000Z\r\n",
+		`DESCRIPTION:station: agqr\nstatus: scheduled` + "\r\n",
+		"STATUS:CONFIRMED\r\nSEQUENCE:0\r\n",
+		"DTSTART;VALUE=DATE:20220809\r\nDTEND;VALUE=DATE:20220810\r\n",
+		`SUMMARY:セブンスパイス\, 第1回\; 配信` + "\r\n",
+		"STATUS:CANCELLED\r\nSEQUENCE:1\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Encode() does not contain %q\n%s", want, got)
+		}
+	}
+}
+
+func Test_writeFolded(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "75 octet 以下はそのまま",
+			s:    "SUMMARY:" + strings.Repeat("a", 67),
+			want: "SUMMARY:" + strings.Repeat("a", 67) + "\r\n",
+		},
+		{
+			name: "75 octet を超えれば折り返す",
+			s:    "SUMMARY:" + strings.Repeat("a", 70),
+			want: "SUMMARY:" + strings.Repeat("a", 67) + "\r\n aaa\r\n",
+		},
+		{
+			name: "マルチバイト文字の途中では折り返さない",
+			// 8 + 3*22 = 74 octet の後に 3 octet の文字
+			s:    "SUMMARY:" + strings.Repeat("あ", 23),
+			want: "SUMMARY:" + strings.Repeat("あ", 22) + "\r\n あ\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeFolded(&b, tt.s)
+			if got := b.String(); got != tt.want {
+				t.Errorf("writeFolded() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "anrd"
+
+var (
+	// 番組表の取得
+	GuideFetchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "guide_fetches_total",
+		Help:      "Number of guide fetches per station and result.",
+	}, []string{"station", "result"})
+	GuideFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "guide_fetch_duration_seconds",
+		Help:      "Latency of guide fetches per station.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"station"})
+	GuidePrograms = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "guide_programs",
+		Help:      "Number of programs returned by the last successful guide fetch per station.",
+	}, []string{"station"})
+
+	// 録画
+	RecordingsStartedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "recordings_started_total",
+		Help:      "Number of recordings started per station and stream type.",
+	}, []string{"station", "stream_type"})
+	RecordingsSucceededTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "recordings_succeeded_total",
+		Help:      "Number of recordings succeeded per station and stream type.",
+	}, []string{"station", "stream_type"})
+	RecordingsFailedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "recordings_failed_total",
+		Help:      "Number of recordings failed (after retries) per station and stream type.",
+	}, []string{"station", "stream_type"})
+	FfmpegActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_active",
+		Help:      "Number of running ffmpeg processes.",
+	})
+	FfmpegKilledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_killed_total",
+		Help:      "Number of ffmpeg processes killed by anrd per reason (stalled, deadline).",
+	}, []string{"reason"})
+	RecordedBytesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "recorded_bytes_total",
+		Help:      "Bytes written by finished recordings per station.",
+	}, []string{"station"})
+	OndemandQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ondemand_queue_depth",
+		Help:      "Number of scheduled ondemand programs waiting to be recorded.",
+	})
+
+	// gocron の job
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of scheduler job runs.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300},
+	}, []string{"job"})
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
@@ -0,0 +1,96 @@
+// データベースのスキーマを変更する、番号つきのマイグレーション
+package migration
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// dir 以下の NNNN_name.sql を version の昇順で返す
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, errors.Wrapf(errutil.ErrInternal, "invalid migration file name: %s", entry.Name())
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, errors.Wrapf(errutil.ErrInternal, "invalid migration file name: %s", entry.Name())
+		}
+
+		sql, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrap(errutil.ErrInternal, err.Error())
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(sql)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i-1].Version == migrations[i].Version {
+			return nil, errors.Wrapf(errutil.ErrInternal, "duplicate migration version: %d", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// 最新の version
+// migrations は version の昇順であること
+func Latest(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// applied に含まれないもの
+func Pending(migrations []Migration, applied map[int]bool) []Migration {
+	var pending []Migration
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// 適用済みの version のうち最大のもの
+// 何も適用されていなければ 0
+func Current(applied map[int]bool) int {
+	current := 0
+	for version, ok := range applied {
+		if ok && current < version {
+			current = version
+		}
+	}
+	return current
+}
@@ -0,0 +1,79 @@
+package migration
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr error
+	}{
+		{
+			name: "version の昇順で返し、.sql 以外は無視する",
+			fsys: fstest.MapFS{
+				"migrations/0002_add_column.sql":      {Data: []byte("alter table t add column c text;")},
+				"migrations/0001_create_table.sql":    {Data: []byte("create table t (id integer);")},
+				"migrations/README.md":                {Data: []byte("readme")},
+				"migrations/0010_add_another_one.sql": {Data: []byte("alter table t add column d text;")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "create_table", SQL: "create table t (id integer);"},
+				{Version: 2, Name: "add_column", SQL: "alter table t add column c text;"},
+				{Version: 10, Name: "add_another_one", SQL: "alter table t add column d text;"},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "番号のないファイル名はエラー",
+			fsys: fstest.MapFS{
+				"migrations/create_table.sql": {Data: []byte("create table t (id integer);")},
+			},
+			want:    nil,
+			wantErr: errutil.ErrInternal,
+		},
+		{
+			name: "version が重複していればエラー",
+			fsys: fstest.MapFS{
+				"migrations/0001_create_table.sql": {Data: []byte("create table t (id integer);")},
+				"migrations/1_create_another.sql":  {Data: []byte("create table u (id integer);")},
+			},
+			want:    nil,
+			wantErr: errutil.ErrInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.fsys, "migrations")
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPendingAndCurrent(t *testing.T) {
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+	applied := map[int]bool{1: true, 2: false, 3: true}
+
+	if diff := cmp.Diff([]Migration{{Version: 2}}, Pending(migrations, applied)); diff != "" {
+		t.Errorf("Pending() mismatch (-want +got):\n%s", diff)
+	}
+	if got := Current(applied); got != 3 {
+		t.Errorf("Current() = %d, want 3", got)
+	}
+	if got := Latest(migrations); got != 3 {
+		t.Errorf("Latest() = %d, want 3", got)
+	}
+}
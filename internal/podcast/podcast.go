@@ -0,0 +1,127 @@
+package podcast
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+const itunesNamespace = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// RSS 2.0 + iTunes 拡張
+type rss struct {
+	XMLName     xml.Name `xml:"rss"`
+	Version     string   `xml:"version,attr"`
+	XMLNSItunes string   `xml:"xmlns:itunes,attr"`
+	Channel     channel  `xml:"channel"`
+}
+
+type channel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Language    string       `xml:"language"`
+	Image       *itunesImage `xml:"itunes:image,omitempty"`
+	Items       []item       `xml:"item"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type item struct {
+	Title     string       `xml:"title"`
+	GUID      guid         `xml:"guid"`
+	PubDate   string       `xml:"pubDate"`
+	Enclosure enclosure    `xml:"enclosure"`
+	Duration  string       `xml:"itunes:duration,omitempty"`
+	Image     *itunesImage `xml:"itunes:image,omitempty"`
+}
+
+type guid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type Channel struct {
+	Title       string
+	Link        string
+	Description string
+	ImageURL    string
+	Items       []Item
+}
+
+type Item struct {
+	// 一意であればよい
+	GUID    string
+	Title   string
+	PubDate time.Time
+	// 0 であれば出力しない
+	Duration time.Duration
+	ImageURL string
+
+	EnclosureURL    string
+	EnclosureLength int64
+	EnclosureType   string
+}
+
+func Encode(w io.Writer, ch Channel) error {
+	feed := rss{
+		Version:     "2.0",
+		XMLNSItunes: itunesNamespace,
+		Channel: channel{
+			Title:       ch.Title,
+			Link:        ch.Link,
+			Description: ch.Description,
+			Language:    "ja",
+		},
+	}
+	if ch.ImageURL != "" {
+		feed.Channel.Image = &itunesImage{Href: ch.ImageURL}
+	}
+
+	for _, it := range ch.Items {
+		i := item{
+			Title:   it.Title,
+			GUID:    guid{IsPermaLink: false, Value: it.GUID},
+			PubDate: it.PubDate.Format(time.RFC1123Z),
+			Enclosure: enclosure{
+				URL:    it.EnclosureURL,
+				Length: it.EnclosureLength,
+				Type:   it.EnclosureType,
+			},
+		}
+		if it.Duration > 0 {
+			i.Duration = formatDuration(it.Duration)
+		}
+		if it.ImageURL != "" {
+			i.Image = &itunesImage{Href: it.ImageURL}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, i)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(feed)
+}
+
+// hh:mm:ss
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	return fmt.Sprintf("%02d:%02d:%02d", h, m, d/time.Second)
+}
@@ -0,0 +1,50 @@
+package podcast
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+func TestEncode(t *testing.T) {
+	ch := Channel{
+		Title:       "鷲崎健のヨルナイト×ヨルナイト",
+		Link:        "http://anrd.test/feeds/agqr.xml",
+		Description: "agqr",
+		ImageURL:    "http://anrd.test/image.png",
+		Items: []Item{
+			{
+				GUID:            "b7750840-3407-44a0-b670-2b08cb8e0eb3",
+				Title:           "鷲崎健のヨルナイト×ヨルナイト",
+				PubDate:         time.Date(2022, 8, 10, 0, 0, 0, 0, timeutil.LocationJST()),
+				Duration:        30 * time.Minute,
+				EnclosureURL:    "http://anrd.test/archive/agqr/a.ts",
+				EnclosureLength: 1024,
+				EnclosureType:   "video/mp2t",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Encode(&buf, ch)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		`<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">`,
+		`<itunes:image href="http://anrd.test/image.png"></itunes:image>`,
+		`<guid isPermaLink="false">b7750840-3407-44a0-b670-2b08cb8e0eb3</guid>`,
+		`<pubDate>Wed, 10 Aug 2022 00:00:00 +0900</pubDate>`,
+		`<enclosure url="http://anrd.test/archive/agqr/a.ts" length="1024" type="video/mp2t"></enclosure>`,
+		`<itunes:duration>00:30:00</itunes:duration>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Encode() does not contain %s\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+// 番組データベースの中身を JSON Lines や CSV として書き出し、読み込む
+// 別のマシンや database_driver への移行、テスト用データベースの用意、表計算ソフトでの一括編集のためのもの
+package programio
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+type Format string
+
+const (
+	FormatJSONL = Format("jsonl")
+	FormatCSV   = Format("csv")
+)
+
+func (f Format) Valid() bool {
+	switch f {
+	case FormatJSONL, FormatCSV:
+		return true
+	}
+	return false
+}
+
+// 番組 1 つ分
+type Record struct {
+	Program program.Program
+	// 冗長録画していなければ空
+	Attempts []recorder.Attempt
+}
+
+type record struct {
+	// 空であれば読み込む際に新しく振る
+	UUID    string `json:"uuid"`
+	ID      int    `json:"id"`
+	Station string `json:"station"`
+	Title   string `json:"title"`
+	Episode string `json:"episode,omitempty"`
+	// RFC 3339
+	Start string `json:"start"`
+	// ondemand な番組は空
+	End         string `json:"end,omitempty"`
+	Status      string `json:"status"`
+	StreamType  string `json:"stream_type"`
+	PlaylistURL string `json:"playlist_url,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	// CSV では ", " 区切り
+	Personalities []string  `json:"personalities,omitempty"`
+	FilePath      string    `json:"file_path,omitempty"`
+	ObjectKey     string    `json:"object_key,omitempty"`
+	Protected     bool      `json:"protected"`
+	Attempts      []attempt `json:"attempts,omitempty"`
+
+	// 読み込んだ行（1 始まり）
+	line int
+}
+
+type attempt struct {
+	Source   int    `json:"source"`
+	FilePath string `json:"file_path"`
+	// 秒
+	Duration        float64 `json:"duration"`
+	Discontinuities int     `json:"discontinuities"`
+	Error           string  `json:"error,omitempty"`
+	Chosen          bool    `json:"chosen"`
+}
+
+// CSV の列
+// attempts は JSON の配列
+var csvHeader = []string{"uuid", "id", "station", "title", "episode", "start", "end", "status", "stream_type", "playlist_url", "image_url", "description", "personalities", "file_path", "object_key", "protected", "attempts"}
+
+// CSV の personalities の区切り
+const personalitiesSeparator = ", "
+
+func Encode(w io.Writer, format Format, records []Record) error {
+	switch format {
+	case FormatJSONL:
+		return encodeJSONL(w, records)
+	case FormatCSV:
+		return encodeCSV(w, records)
+	}
+	return errors.Wrapf(errutil.ErrConfig, "unknown format %q (jsonl, csv)", format)
+}
+
+func encodeJSONL(w io.Writer, records []Record) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, r := range records {
+		err := encoder.Encode(newRecord(r))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		rec := newRecord(r)
+		var attempts string
+		if len(rec.Attempts) > 0 {
+			b, err := json.Marshal(rec.Attempts)
+			if err != nil {
+				return err
+			}
+			attempts = string(b)
+		}
+		err := cw.Write([]string{
+			rec.UUID, strconv.Itoa(rec.ID), rec.Station, rec.Title, rec.Episode, rec.Start, rec.End, rec.Status, rec.StreamType,
+			rec.PlaylistURL, rec.ImageURL, rec.Description, strings.Join(rec.Personalities, personalitiesSeparator), rec.FilePath, rec.ObjectKey, strconv.FormatBool(rec.Protected), attempts,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func newRecord(r Record) record {
+	pgram := r.Program
+	rec := record{
+		UUID:          pgram.UUID,
+		ID:            pgram.ID,
+		Station:       pgram.Station.String(),
+		Title:         pgram.Title,
+		Episode:       pgram.Episode,
+		Start:         pgram.Start.Format(time.RFC3339),
+		Status:        pgram.Status.String(),
+		StreamType:    pgram.StreamType.String(),
+		PlaylistURL:   pgram.PlaylistURL,
+		ImageURL:      pgram.ImageURL,
+		Description:   pgram.Description,
+		Personalities: pgram.Personalities,
+		FilePath:      pgram.FilePath,
+		ObjectKey:     pgram.ObjectKey,
+		Protected:     pgram.Protected,
+	}
+	if !pgram.End.IsZero() {
+		rec.End = pgram.End.Format(time.RFC3339)
+	}
+	for _, a := range r.Attempts {
+		rec.Attempts = append(rec.Attempts, attempt{
+			Source:          a.Source,
+			FilePath:        a.FilePath,
+			Duration:        a.Probe.Duration.Seconds(),
+			Discontinuities: a.Probe.Discontinuities,
+			Error:           a.Error,
+			Chosen:          a.Chosen,
+		})
+	}
+	return rec
+}
+
+// すべて読み込んで検証する
+// 問題があればどの行であるかを含めて返し、一部だけを返すことはしない
+// 返されるエラー
+// - errutil.ErrInvalidImport
+func Decode(r io.Reader, format Format) ([]Record, error) {
+	var recs []record
+	var err error
+	switch format {
+	case FormatJSONL:
+		recs, err = decodeJSONL(r)
+	case FormatCSV:
+		recs, err = decodeCSV(r)
+	default:
+		return nil, errors.Wrapf(errutil.ErrConfig, "unknown format %q (jsonl, csv)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	// key は station/id
+	seen := make(map[string]int)
+	for _, rec := range recs {
+		line := rec.line
+		record, err := rec.toRecord()
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		key := fmt.Sprintf("%s/%d", record.Program.Station, record.Program.ID)
+		if prev, ok := seen[key]; ok {
+			return nil, errors.Wrapf(errutil.ErrInvalidImport, "line %d: duplicated station and id with line %d", line, prev)
+		}
+		seen[key] = line
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func decodeJSONL(r io.Reader) ([]record, error) {
+	var recs []record
+	scanner := bufio.NewScanner(r)
+	// 冗長録画の結果を含むと長くなりうる
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		rec := record{line: line}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&rec)
+		if err != nil {
+			return nil, errors.Wrapf(errutil.ErrInvalidImport, "line %d: %s", line, err.Error())
+		}
+		recs = append(recs, rec)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrInvalidImport, err.Error())
+	}
+	return recs, nil
+}
+
+// 列の順序は問わないが、すべての列が必要
+// 空欄の項目は空として扱うので、一部の列を省略して一部の項目だけ変更することはできない
+func decodeCSV(r io.Reader) ([]record, error) {
+	cr := csv.NewReader(r)
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(errutil.ErrInvalidImport, err.Error())
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	// key は列名
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range csvHeader {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.Wrapf(errutil.ErrInvalidImport, "line 1: missing column %q", name)
+		}
+	}
+	if len(columns) != len(csvHeader) {
+		return nil, errors.Wrapf(errutil.ErrInvalidImport, "line 1: unknown columns (want %s)", strings.Join(csvHeader, ", "))
+	}
+
+	var recs []record
+	for i, row := range rows[1:] {
+		line := i + 2
+		get := func(name string) string {
+			return strings.TrimSpace(row[columns[name]])
+		}
+
+		rec := record{
+			line:        line,
+			UUID:        get("uuid"),
+			Station:     get("station"),
+			Title:       get("title"),
+			Episode:     get("episode"),
+			Start:       get("start"),
+			End:         get("end"),
+			Status:      get("status"),
+			StreamType:  get("stream_type"),
+			PlaylistURL: get("playlist_url"),
+			ImageURL:    get("image_url"),
+			Description: get("description"),
+			FilePath:    get("file_path"),
+			ObjectKey:   get("object_key"),
+		}
+		rec.ID, err = strconv.Atoi(get("id"))
+		if err != nil {
+			return nil, errors.Wrapf(errutil.ErrInvalidImport, "line %d: invalid id %q", line, get("id"))
+		}
+		for _, name := range strings.Split(get("personalities"), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				rec.Personalities = append(rec.Personalities, name)
+			}
+		}
+		if protected := get("protected"); protected != "" {
+			rec.Protected, err = strconv.ParseBool(protected)
+			if err != nil {
+				return nil, errors.Wrapf(errutil.ErrInvalidImport, "line %d: invalid protected %q", line, protected)
+			}
+		}
+		if attempts := get("attempts"); attempts != "" {
+			decoder := json.NewDecoder(strings.NewReader(attempts))
+			decoder.DisallowUnknownFields()
+			err = decoder.Decode(&rec.Attempts)
+			if err != nil {
+				return nil, errors.Wrapf(errutil.ErrInvalidImport, "line %d: invalid attempts: %s", line, err.Error())
+			}
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// 返されるエラー
+// - errutil.ErrInvalidImport
+func (rec record) toRecord() (Record, error) {
+	pgram := program.Program{
+		UUID:          rec.UUID,
+		ID:            rec.ID,
+		Station:       program.Station(rec.Station),
+		Title:         rec.Title,
+		Episode:       rec.Episode,
+		Status:        program.Status(rec.Status),
+		StreamType:    program.StreamType(rec.StreamType),
+		PlaylistURL:   rec.PlaylistURL,
+		ImageURL:      rec.ImageURL,
+		Description:   rec.Description,
+		Personalities: rec.Personalities,
+		FilePath:      rec.FilePath,
+		ObjectKey:     rec.ObjectKey,
+		Protected:     rec.Protected,
+	}
+
+	if pgram.UUID != "" {
+		_, err := uuid.Parse(pgram.UUID)
+		if err != nil {
+			return Record{}, errors.Wrapf(errutil.ErrInvalidImport, "invalid uuid %q", pgram.UUID)
+		}
+	}
+	// 手動で録画する番組は負
+	if pgram.ID == 0 {
+		return Record{}, errors.Wrap(errutil.ErrInvalidImport, "id must not be 0")
+	}
+	if !pgram.Station.Valid() {
+		return Record{}, errors.Wrapf(errutil.ErrInvalidImport, "unknown station %q", rec.Station)
+	}
+	if pgram.Title == "" {
+		return Record{}, errors.Wrap(errutil.ErrInvalidImport, "title must not be empty")
+	}
+	if !pgram.Status.Valid() {
+		return Record{}, errors.Wrapf(errutil.ErrInvalidImport, "unknown status %q (scheduled, recording, done, failed, purged, skipped)", rec.Status)
+	}
+	if !pgram.StreamType.Valid() {
+		return Record{}, errors.Wrapf(errutil.ErrInvalidImport, "unknown stream type %q (broadcast, ondemand)", rec.StreamType)
+	}
+
+	var err error
+	pgram.Start, err = parseTime(rec.Start)
+	if err != nil {
+		return Record{}, errors.Wrapf(errutil.ErrInvalidImport, "invalid start %q", rec.Start)
+	}
+	if rec.End != "" {
+		pgram.End, err = parseTime(rec.End)
+		if err != nil {
+			return Record{}, errors.Wrapf(errutil.ErrInvalidImport, "invalid end %q", rec.End)
+		}
+	}
+	if pgram.StreamType == program.StreamTypeBroadcast && !pgram.Start.Before(pgram.End) {
+		return Record{}, errors.Wrap(errutil.ErrInvalidImport, "end of broadcast program must be after start")
+	}
+
+	var attempts []recorder.Attempt
+	for _, a := range rec.Attempts {
+		if a.Source < 0 {
+			return Record{}, errors.Wrapf(errutil.ErrInvalidImport, "invalid attempt source %d", a.Source)
+		}
+		attempts = append(attempts, recorder.Attempt{
+			ProgramUUID: pgram.UUID,
+			Source:      a.Source,
+			FilePath:    a.FilePath,
+			Probe: recorder.Probe{
+				Duration:        time.Duration(a.Duration * float64(time.Second)),
+				Discontinuities: a.Discontinuities,
+			},
+			Error:  a.Error,
+			Chosen: a.Chosen,
+		})
+	}
+	return Record{Program: pgram, Attempts: attempts}, nil
+}
+
+// ファイル名などが既存の番組と変わらないよう JST にそろえる
+func parseTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(timeutil.LocationJST()), nil
+}
@@ -0,0 +1,199 @@
+package programio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+func testRecords() []Record {
+	return []Record{
+		{
+			Program: program.Program{
+				UUID:        "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+				ID:          1650291,
+				Station:     program.StationAgqr,
+				Title:       "鷲崎健のヨルナイト×ヨルナイト",
+				Start:       time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+				End:         time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+				Status:      program.StatusDone,
+				StreamType:  program.StreamTypeBroadcast,
+				FilePath:    "/archive/agqr/yorunight.ts",
+				Protected:   true,
+				Description: "鷲崎健による生放送。\n月曜日～木曜日",
+			},
+			Attempts: []recorder.Attempt{
+				{
+					ProgramUUID: "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+					Source:      0,
+					FilePath:    "/archive/agqr/yorunight.ts",
+					Probe:       recorder.Probe{Duration: 3720 * time.Second},
+					Chosen:      true,
+				},
+				{
+					ProgramUUID: "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+					Source:      1,
+					FilePath:    "/archive/agqr/yorunight.source1.ts",
+					Probe:       recorder.Probe{Duration: 3600500 * time.Millisecond, Discontinuities: 2},
+					Error:       "ffmpeg error: exit status 1",
+				},
+			},
+		},
+		{
+			Program: program.Program{
+				UUID:          "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+				ID:            11134,
+				Station:       program.StationOnsen,
+				Title:         "セブン-イレブン presents 佐倉としたい大西",
+				Episode:       "第334回, \"特別編\"",
+				Start:         time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+				Status:        program.StatusScheduled,
+				StreamType:    program.StreamTypeOndemand,
+				PlaylistURL:   "https://onsen.test/334/playlist.m3u8?a=1&b=2",
+				ImageURL:      "https://onsen.test/image.jpg",
+				Personalities: []string{"佐倉綾音", "大西沙織"},
+			},
+		},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, format := range []Format{FormatJSONL, FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Encode(&buf, format, testRecords())
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			got, err := Decode(&buf, format)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if diff := cmp.Diff(testRecords(), got); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEncode_csv(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, FormatCSV, testRecords()[1:])
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	want := `uuid,id,station,title,episode,start,end,status,stream_type,playlist_url,image_url,description,personalities,file_path,object_key,protected,attempts
+89350da4-7f3b-4438-b99f-41ae9aa52bf5,11134,onsen,セブン-イレブン presents 佐倉としたい大西,"第334回, ""特別編""",2022-08-23T00:00:00+09:00,,scheduled,ondemand,https://onsen.test/334/playlist.m3u8?a=1&b=2,https://onsen.test/image.jpg,,"佐倉綾音, 大西沙織",,,false,
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	const header = "uuid,id,station,title,episode,start,end,status,stream_type,playlist_url,image_url,description,personalities,file_path,object_key,protected,attempts\n"
+
+	tests := []struct {
+		name    string
+		format  Format
+		input   string
+		want    []Record
+		wantErr string
+	}{
+		{
+			name:   "uuid は省略でき、列の順序は問わない",
+			format: FormatCSV,
+			input: "station,id,title,start,end,status,stream_type,uuid,episode,playlist_url,image_url,description,personalities,file_path,object_key,protected,attempts\n" +
+				"agqr,-1660744800,手動録画,2022-08-17T14:00:00Z,2022-08-17T15:00:00Z,skipped,broadcast,,,,,,,,,,\n",
+			want: []Record{{
+				Program: program.Program{
+					ID:         -1660744800,
+					Station:    program.StationAgqr,
+					Title:      "手動録画",
+					Start:      time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+					End:        time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+					Status:     program.StatusSkipped,
+					StreamType: program.StreamTypeBroadcast,
+				},
+			}},
+		},
+		{
+			name:   "空行は読み飛ばす",
+			format: FormatJSONL,
+			input:  "\n" + `{"id":11134,"station":"onsen","title":"佐倉としたい大西","start":"2022-08-23T00:00:00+09:00","status":"done","stream_type":"ondemand","protected":false}` + "\n\n",
+			want: []Record{{
+				Program: program.Program{
+					ID:         11134,
+					Station:    program.StationOnsen,
+					Title:      "佐倉としたい大西",
+					Start:      time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+					Status:     program.StatusDone,
+					StreamType: program.StreamTypeOndemand,
+				},
+			}},
+		},
+		{
+			name:    "知らない status",
+			format:  FormatCSV,
+			input:   header + ",11134,onsen,佐倉としたい大西,,2022-08-23T00:00:00+09:00,,finished,ondemand,,,,,,,false,\n",
+			wantErr: `line 2: unknown status "finished"`,
+		},
+		{
+			name:    "知らない stream_type",
+			format:  FormatJSONL,
+			input:   `{"id":11134,"station":"onsen","title":"佐倉としたい大西","start":"2022-08-23T00:00:00+09:00","status":"done","stream_type":"live","protected":false}`,
+			wantErr: `line 1: unknown stream type "live"`,
+		},
+		{
+			name:    "知らない項目",
+			format:  FormatJSONL,
+			input:   `{"id":11134,"station":"onsen","title":"佐倉としたい大西","start":"2022-08-23T00:00:00+09:00","status":"done","stream_type":"ondemand","state":"done"}`,
+			wantErr: `line 1: json: unknown field "state"`,
+		},
+		{
+			name:    "broadcast は end が必要",
+			format:  FormatCSV,
+			input:   header + ",1650291,agqr,ヨルナイト,,2022-08-17T23:00:00+09:00,,scheduled,broadcast,,,,,,,false,\n",
+			wantErr: "line 2: end of broadcast program must be after start",
+		},
+		{
+			name:    "列が足りない",
+			format:  FormatCSV,
+			input:   "id,station,title\n11134,onsen,佐倉としたい大西\n",
+			wantErr: `line 1: missing column "uuid"`,
+		},
+		{
+			name:   "station と id の重複",
+			format: FormatJSONL,
+			input: `{"id":11134,"station":"onsen","title":"佐倉としたい大西","start":"2022-08-23T00:00:00+09:00","status":"done","stream_type":"ondemand"}` + "\n\n" +
+				`{"id":11134,"station":"onsen","title":"佐倉としたい大西","start":"2022-08-23T00:00:00+09:00","status":"failed","stream_type":"ondemand"}`,
+			wantErr: "line 3: duplicated station and id with line 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(strings.NewReader(tt.input), tt.format)
+			if tt.wantErr != "" {
+				if !testutil.ErrorsAs(err, errutil.ErrInvalidImport) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,695 @@
+// repository.ProgramPersistence の実装が共通して満たすべき振る舞いのテスト
+// 各実装のテストから Run を呼び出す
+package persistencetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+// newPersistence はサブテストごとに呼び出され、空のデータベースにつながったものを返すこと
+func Run(t *testing.T, newPersistence func(t *testing.T) repository.ProgramPersistence) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, p repository.ProgramPersistence)
+	}{
+		{name: "Ping と CheckSchema", test: testPingAndCheckSchema},
+		{name: "Save と LoadByUUID", test: testSave},
+		{name: "Save は同じ station と ID の番組があれば追加せず、scheduled なら番組表の変更を反映する", test: testSaveDuplicate},
+		{name: "Upsert は station と id が同じ番組を UUID 以外置き換える", test: testUpsert},
+		{name: "LoadByUUID は存在しなければ ErrDatabaseNotFoundProgram", test: testLoadByUUIDNotFound},
+		{name: "LoadBroadcastStartIn", test: testLoadBroadcastStartIn},
+		{name: "LoadOndemandScheduled と CountOndemandScheduled", test: testLoadOndemandScheduled},
+		{name: "ChangeStatus はどの status にも変更でき、他の項目は変えない", test: testChangeStatus},
+		{name: "Change 系", test: testChange},
+		{name: "Change 系は存在しなければ ErrDatabaseNotFoundProgram", test: testChangeNotFound},
+		{name: "LoadDone", test: testLoadDone},
+		{name: "Load", test: testLoad},
+		{name: "LoadUpdatedAt", test: testLoadUpdatedAt},
+		{name: "SaveAttempts と LoadAttempts", test: testAttempts},
+		{name: "録画ログ", test: testRecordingLogs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newPersistence(t))
+		})
+	}
+}
+
+func pgramOndemand334() program.Program {
+	return program.Program{
+		UUID:          "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+		ID:            11134,
+		Station:       program.StationOnsen,
+		Title:         "セブン-イレブン presents 佐倉としたい大西",
+		Episode:       "第334回",
+		Start:         time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:           time.Time{},
+		Status:        program.StatusScheduled,
+		StreamType:    program.StreamTypeOndemand,
+		PlaylistURL:   "https://onsen.test/334/playlist.m3u8",
+		ImageURL:      "https://onsen.test/image.jpg",
+		Personalities: []string{"佐倉綾音", "大西沙織"},
+	}
+}
+
+func pgramOndemand333() program.Program {
+	return program.Program{
+		UUID:        "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+		ID:          11133,
+		Station:     program.StationOnsen,
+		Title:       "セブン-イレブン presents 佐倉としたい大西",
+		Episode:     "第333回",
+		Start:       time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:         time.Time{},
+		Status:      program.StatusScheduled,
+		StreamType:  program.StreamTypeOndemand,
+		PlaylistURL: "https://onsen.test/333/playlist.m3u8",
+	}
+}
+
+// 会員限定で playlist_url がないもの
+func pgramOndemandPremium() program.Program {
+	return program.Program{
+		UUID:       "0c3f5c0e-5d3c-4d4a-8f6c-3b1e6c7a9d21",
+		ID:         11200,
+		Station:    program.StationOnsen,
+		Title:      "会員限定番組",
+		Episode:    "第1回",
+		Start:      time.Date(2022, 8, 20, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Time{},
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeOndemand,
+	}
+}
+
+func pgramBroadcast() program.Program {
+	return program.Program{
+		UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		ID:         1660140000,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeBroadcast,
+		// 改行や引用符もそのまま保存する
+		Description:   "鷲崎健による生放送。\n\"ヨルナイト\"",
+		Personalities: []string{"鷲崎健"},
+	}
+}
+
+func save(t *testing.T, p repository.ProgramPersistence, pgrams ...program.Program) {
+	t.Helper()
+	for _, pgram := range pgrams {
+		err := p.Save(context.Background(), pgram)
+		if err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+}
+
+func changeStatus(t *testing.T, p repository.ProgramPersistence, pgram program.Program, status program.Status) program.Program {
+	t.Helper()
+	err := p.ChangeStatus(context.Background(), pgram, status)
+	if err != nil {
+		t.Fatalf("ChangeStatus() error = %v", err)
+	}
+	pgram.Status = status
+	return pgram
+}
+
+func loadByUUID(t *testing.T, p repository.ProgramPersistence, uuid string) program.Program {
+	t.Helper()
+	got, err := p.LoadByUUID(context.Background(), uuid)
+	if err != nil {
+		t.Fatalf("LoadByUUID() error = %v", err)
+	}
+	return got
+}
+
+func testPingAndCheckSchema(t *testing.T, p repository.ProgramPersistence) {
+	err := p.Ping(context.Background())
+	if err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+	err = p.CheckSchema(context.Background())
+	if err != nil {
+		t.Errorf("CheckSchema() error = %v", err)
+	}
+}
+
+func testSave(t *testing.T, p repository.ProgramPersistence) {
+	for _, want := range []program.Program{pgramOndemand334(), pgramOndemandPremium(), pgramBroadcast()} {
+		save(t, p, want)
+		got := loadByUUID(t, p, want.UUID)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("LoadByUUID() mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func testSaveDuplicate(t *testing.T, p repository.ProgramPersistence) {
+	stored := pgramBroadcast()
+	save(t, p, stored)
+
+	// 番組表で放送時間などが変わったもの
+	moved := pgramBroadcast()
+	moved.UUID = "e5d8b8a4-2f0c-4a8c-9d3e-7b6a5c4d3e2f"
+	moved.Title = "鷲崎健のヨルナイト×ヨルナイト（特番）"
+	moved.Start = stored.Start.Add(30 * time.Minute)
+	moved.End = stored.End.Add(30 * time.Minute)
+	moved.Status = program.StatusSkipped
+	moved.Description = "変更後の説明"
+	save(t, p, moved)
+
+	// UUID, status などはそのまま
+	want := stored
+	want.Title = moved.Title
+	want.Start = moved.Start
+	want.End = moved.End
+	got, err := p.Load(context.Background(), program.Filter{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if diff := cmp.Diff([]program.Program{want}, got); diff != "" {
+		t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+	}
+
+	// scheduled でなければ変更しない
+	skipped := changeStatus(t, p, want, program.StatusSkipped)
+	movedAgain := moved
+	movedAgain.Start = moved.Start.Add(1 * time.Hour)
+	movedAgain.End = moved.End.Add(1 * time.Hour)
+	save(t, p, movedAgain)
+	if diff := cmp.Diff(skipped, loadByUUID(t, p, stored.UUID)); diff != "" {
+		t.Errorf("LoadByUUID() mismatch (-want +got):\n%s", diff)
+	}
+
+	// station が異なれば別の番組
+	other := pgramBroadcast()
+	other.UUID = "4a6f1d2e-8a6b-4f0e-bf3c-1f2d3e4a5b6c"
+	other.Station = program.StationOnsen
+	save(t, p, other)
+	loadByUUID(t, p, other.UUID)
+}
+
+func testUpsert(t *testing.T, p repository.ProgramPersistence) {
+	ctx := context.Background()
+
+	// 録画後に設定されるものも保存する
+	want := pgramOndemand334()
+	want.Status = program.StatusDone
+	want.FilePath = "/archive/onsen/334.ts"
+	want.ObjectKey = "onsen/334.ts"
+	want.Protected = true
+	got, created, err := p.Upsert(ctx, want)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if !created {
+		t.Errorf("Upsert() created = false, want true")
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Upsert() mismatch (-want +got):\n%s", diff)
+	}
+
+	changed := want
+	changed.UUID = "e5d8b8a4-2f0c-4a8c-9d3e-7b6a5c4d3e2f"
+	changed.Episode = "第334回（変更後）"
+	changed.Status = program.StatusFailed
+	changed.FilePath = ""
+	changed.Protected = false
+	got, created, err = p.Upsert(ctx, changed)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if created {
+		t.Errorf("Upsert() created = true, want false")
+	}
+	changed.UUID = want.UUID
+	if diff := cmp.Diff(changed, got); diff != "" {
+		t.Errorf("Upsert() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(changed, loadByUUID(t, p, want.UUID)); diff != "" {
+		t.Errorf("LoadByUUID() mismatch (-want +got):\n%s", diff)
+	}
+
+	// station が異なれば別の番組
+	other := pgramOndemand334()
+	other.UUID = "4a6f1d2e-8a6b-4f0e-bf3c-1f2d3e4a5b6c"
+	other.Station = program.StationAgqr
+	_, created, err = p.Upsert(ctx, other)
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if !created {
+		t.Errorf("Upsert() created = false, want true")
+	}
+}
+
+func testLoadByUUIDNotFound(t *testing.T, p repository.ProgramPersistence) {
+	_, err := p.LoadByUUID(context.Background(), "00000000-0000-0000-0000-000000000000")
+	if !testutil.ErrorsAs(err, errutil.ErrDatabaseNotFoundProgram) {
+		t.Errorf("LoadByUUID() error = %v, wantErr %v", err, errutil.ErrDatabaseNotFoundProgram)
+	}
+}
+
+func testLoadBroadcastStartIn(t *testing.T, p repository.ProgramPersistence) {
+	broadcast := pgramBroadcast()
+	save(t, p, broadcast, pgramOndemand334())
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		duration time.Duration
+		want     []program.Program
+		wantErr  error
+	}{
+		{
+			name:     "duration 後までに始まる scheduled な broadcast を返す",
+			now:      broadcast.Start.Add(-5 * time.Minute),
+			duration: 10 * time.Minute,
+			want:     []program.Program{broadcast},
+			wantErr:  nil,
+		},
+		{
+			name:     "既に始まっていれば返さない",
+			now:      broadcast.Start.Add(1 * time.Minute),
+			duration: 10 * time.Minute,
+			want:     nil,
+			wantErr:  errutil.ErrDatabaseNotFoundProgram,
+		},
+		{
+			name:     "duration 後までに始まらなければ返さない",
+			now:      broadcast.Start.Add(-15 * time.Minute),
+			duration: 10 * time.Minute,
+			want:     nil,
+			wantErr:  errutil.ErrDatabaseNotFoundProgram,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.LoadBroadcastStartIn(context.Background(), tt.now, tt.duration)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("LoadBroadcastStartIn() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("LoadBroadcastStartIn() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	changeStatus(t, p, broadcast, program.StatusRecording)
+	_, err := p.LoadBroadcastStartIn(context.Background(), broadcast.Start.Add(-5*time.Minute), 10*time.Minute)
+	if !testutil.ErrorsAs(err, errutil.ErrDatabaseNotFoundProgram) {
+		t.Errorf("LoadBroadcastStartIn() error = %v, wantErr %v (not scheduled)", err, errutil.ErrDatabaseNotFoundProgram)
+	}
+}
+
+func testLoadOndemandScheduled(t *testing.T, p repository.ProgramPersistence) {
+	_, err := p.LoadOndemandScheduled(context.Background(), 10)
+	if !testutil.ErrorsAs(err, errutil.ErrDatabaseNotFoundProgram) {
+		t.Errorf("LoadOndemandScheduled() error = %v, wantErr %v (empty)", err, errutil.ErrDatabaseNotFoundProgram)
+	}
+
+	save(t, p, pgramOndemand334(), pgramOndemand333(), pgramOndemandPremium(), pgramBroadcast())
+	changeStatus(t, p, pgramOndemand333(), program.StatusDone)
+
+	count, err := p.CountOndemandScheduled(context.Background())
+	if err != nil {
+		t.Fatalf("CountOndemandScheduled() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("CountOndemandScheduled() = %d, want 1", count)
+	}
+
+	got, err := p.LoadOndemandScheduled(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("LoadOndemandScheduled() error = %v", err)
+	}
+	if diff := cmp.Diff([]program.Program{pgramOndemand334()}, *got); diff != "" {
+		t.Errorf("LoadOndemandScheduled() mismatch (-want +got):\n%s", diff)
+	}
+
+	changeStatus(t, p, pgramOndemand333(), program.StatusScheduled)
+	got, err = p.LoadOndemandScheduled(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("LoadOndemandScheduled() error = %v", err)
+	}
+	if len(*got) != 1 {
+		t.Errorf("LoadOndemandScheduled() returns %d programs, want 1 (limit)", len(*got))
+	}
+}
+
+func testChangeStatus(t *testing.T, p repository.ProgramPersistence) {
+	want := pgramBroadcast()
+	save(t, p, want)
+
+	statuses := []program.Status{
+		program.StatusRecording,
+		program.StatusFailed,
+		program.StatusScheduled,
+		program.StatusRecording,
+		program.StatusDone,
+		program.StatusPurged,
+		program.StatusSkipped,
+		program.StatusDeleted,
+	}
+	for _, status := range statuses {
+		want = changeStatus(t, p, want, status)
+		got := loadByUUID(t, p, want.UUID)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("LoadByUUID() after ChangeStatus(%s) mismatch (-want +got):\n%s", status, diff)
+		}
+	}
+}
+
+func testChange(t *testing.T, p repository.ProgramPersistence) {
+	want := pgramOndemand334()
+	save(t, p, want)
+	ctx := context.Background()
+
+	want = changeStatus(t, p, want, program.StatusDone)
+	err := p.ChangeFilePath(ctx, want, "/archive/334.ts")
+	if err != nil {
+		t.Fatalf("ChangeFilePath() error = %v", err)
+	}
+	want.FilePath = "/archive/334.ts"
+	err = p.ChangeObjectKey(ctx, want, "onsen/2022-08-23/334.ts")
+	if err != nil {
+		t.Fatalf("ChangeObjectKey() error = %v", err)
+	}
+	want.ObjectKey = "onsen/2022-08-23/334.ts"
+	err = p.ChangeProtected(ctx, want, true)
+	if err != nil {
+		t.Fatalf("ChangeProtected() error = %v", err)
+	}
+	want.Protected = true
+
+	got := loadByUUID(t, p, want.UUID)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LoadByUUID() mismatch (-want +got):\n%s", diff)
+	}
+
+	// 空文字に戻せる
+	err = p.ChangeFilePath(ctx, want, "")
+	if err != nil {
+		t.Fatalf("ChangeFilePath() error = %v", err)
+	}
+	want.FilePath = ""
+	err = p.ChangeObjectKey(ctx, want, "")
+	if err != nil {
+		t.Fatalf("ChangeObjectKey() error = %v", err)
+	}
+	want.ObjectKey = ""
+	err = p.ChangeProtected(ctx, want, false)
+	if err != nil {
+		t.Fatalf("ChangeProtected() error = %v", err)
+	}
+	want.Protected = false
+
+	got = loadByUUID(t, p, want.UUID)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LoadByUUID() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testChangeNotFound(t *testing.T, p repository.ProgramPersistence) {
+	save(t, p, pgramOndemand334())
+	ctx := context.Background()
+	missing := pgramOndemand333()
+
+	changes := map[string]func() error{
+		"ChangeStatus":    func() error { return p.ChangeStatus(ctx, missing, program.StatusDone) },
+		"ChangeFilePath":  func() error { return p.ChangeFilePath(ctx, missing, "/archive/333.ts") },
+		"ChangeObjectKey": func() error { return p.ChangeObjectKey(ctx, missing, "onsen/333.ts") },
+		"ChangeProtected": func() error { return p.ChangeProtected(ctx, missing, true) },
+	}
+	for name, change := range changes {
+		err := change()
+		if !testutil.ErrorsAs(err, errutil.ErrDatabaseNotFoundProgram) {
+			t.Errorf("%s() error = %v, wantErr %v", name, err, errutil.ErrDatabaseNotFoundProgram)
+		}
+	}
+}
+
+func testLoadDone(t *testing.T, p repository.ProgramPersistence) {
+	_, err := p.LoadDone(context.Background())
+	if !testutil.ErrorsAs(err, errutil.ErrDatabaseNotFoundProgram) {
+		t.Errorf("LoadDone() error = %v, wantErr %v (empty)", err, errutil.ErrDatabaseNotFoundProgram)
+	}
+
+	save(t, p, pgramOndemand334(), pgramOndemand333(), pgramBroadcast())
+	done334 := changeStatus(t, p, pgramOndemand334(), program.StatusDone)
+	done333 := changeStatus(t, p, pgramOndemand333(), program.StatusDone)
+
+	got, err := p.LoadDone(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDone() error = %v", err)
+	}
+	// start の昇順
+	if diff := cmp.Diff([]program.Program{done333, done334}, got); diff != "" {
+		t.Errorf("LoadDone() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testLoad(t *testing.T, p repository.ProgramPersistence) {
+	pgram334 := pgramOndemand334()
+	broadcast := pgramBroadcast()
+	save(t, p, pgram334, pgramOndemand333(), broadcast)
+	pgram333 := changeStatus(t, p, pgramOndemand333(), program.StatusDone)
+
+	tests := []struct {
+		name    string
+		filter  program.Filter
+		want    []program.Program
+		wantErr error
+	}{
+		{
+			name:    "条件がなければすべてを開始日時順に返す",
+			filter:  program.Filter{},
+			want:    []program.Program{pgram333, broadcast, pgram334},
+			wantErr: nil,
+		},
+		{
+			name:    "station と status で絞り込む",
+			filter:  program.Filter{Station: program.StationOnsen, Status: program.StatusDone},
+			want:    []program.Program{pgram333},
+			wantErr: nil,
+		},
+		{
+			name:    "タイトルの部分一致と stream type で絞り込む",
+			filter:  program.Filter{Title: "ヨルナイト", StreamType: program.StreamTypeBroadcast},
+			want:    []program.Program{broadcast},
+			wantErr: nil,
+		},
+		{
+			name:    "タイトルの % や _ はそのまま部分一致させる",
+			filter:  program.Filter{Title: "%"},
+			want:    nil,
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+		{
+			name: "開始日時の範囲で絞り込み、件数を制限する",
+			filter: program.Filter{
+				StartFrom: time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+				StartTo:   time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+				Limit:     1,
+			},
+			want:    []program.Program{pgram333},
+			wantErr: nil,
+		},
+		{
+			name:    "該当する番組がなければ ErrDatabaseNotFoundProgram を返す",
+			filter:  program.Filter{Status: program.StatusFailed},
+			want:    nil,
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Load(context.Background(), tt.filter)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func testLoadUpdatedAt(t *testing.T, p repository.ProgramPersistence) {
+	ctx := context.Background()
+	pgram := pgramOndemand334()
+	save(t, p, pgram)
+	unknown := pgramOndemand333().UUID
+
+	got, err := p.LoadUpdatedAt(ctx, []string{pgram.UUID, unknown})
+	if err != nil {
+		t.Fatalf("LoadUpdatedAt() error = %v", err)
+	}
+	saved, ok := got[pgram.UUID]
+	if !ok || saved.IsZero() {
+		t.Fatalf("LoadUpdatedAt() = %v, want non-zero time for %s", got, pgram.UUID)
+	}
+	if _, ok := got[unknown]; ok {
+		t.Errorf("LoadUpdatedAt() = %v, want no entry for %s", got, unknown)
+	}
+
+	// 変更しても前の日時より戻らない
+	changeStatus(t, p, pgram, program.StatusSkipped)
+	got, err = p.LoadUpdatedAt(ctx, []string{pgram.UUID})
+	if err != nil {
+		t.Fatalf("LoadUpdatedAt() error = %v", err)
+	}
+	if got[pgram.UUID].Before(saved) {
+		t.Errorf("LoadUpdatedAt() = %s, want not before %s", got[pgram.UUID], saved)
+	}
+
+	got, err = p.LoadUpdatedAt(ctx, nil)
+	if err != nil {
+		t.Fatalf("LoadUpdatedAt() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("LoadUpdatedAt(nil) = %v, want empty", got)
+	}
+}
+
+func testAttempts(t *testing.T, p repository.ProgramPersistence) {
+	ctx := context.Background()
+	pgram := pgramBroadcast()
+	save(t, p, pgram)
+
+	got, err := p.LoadAttempts(ctx, pgram.UUID)
+	if err != nil || len(got) != 0 {
+		t.Errorf("LoadAttempts() = %v, %v, want empty (not saved yet)", got, err)
+	}
+
+	attempts := []recorder.Attempt{
+		{
+			Source:   1,
+			FilePath: "/archive/agqr/file.source1.ts",
+			Probe:    recorder.Probe{Duration: 31*time.Minute + 500*time.Millisecond, Discontinuities: 2},
+			Error:    "ffmpeg error: exit status 1",
+		},
+		{
+			Source:   0,
+			FilePath: "/archive/agqr/file.ts",
+			Probe:    recorder.Probe{Duration: 32 * time.Minute},
+			Chosen:   true,
+		},
+	}
+	err = p.SaveAttempts(ctx, pgram, attempts)
+	if err != nil {
+		t.Fatalf("SaveAttempts() error = %v", err)
+	}
+	want := []recorder.Attempt{attempts[1], attempts[0]}
+	for i := range want {
+		want[i].ProgramUUID = pgram.UUID
+	}
+	got, err = p.LoadAttempts(ctx, pgram.UUID)
+	if err != nil {
+		t.Fatalf("LoadAttempts() error = %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LoadAttempts() mismatch (-want +got):\n%s", diff)
+	}
+
+	// 保存しなおせば置き換わる
+	err = p.SaveAttempts(ctx, pgram, attempts[1:])
+	if err != nil {
+		t.Fatalf("SaveAttempts() error = %v", err)
+	}
+	got, err = p.LoadAttempts(ctx, pgram.UUID)
+	if err != nil {
+		t.Fatalf("LoadAttempts() error = %v", err)
+	}
+	if diff := cmp.Diff(want[:1], got); diff != "" {
+		t.Errorf("LoadAttempts() mismatch (-want +got):\n%s", diff)
+	}
+
+	err = p.SaveAttempts(ctx, pgramOndemand334(), attempts)
+	if !testutil.ErrorsAs(err, errutil.ErrDatabaseNotFoundProgram) {
+		t.Errorf("SaveAttempts() error = %v, wantErr %v", err, errutil.ErrDatabaseNotFoundProgram)
+	}
+}
+
+func testRecordingLogs(t *testing.T, p repository.ProgramPersistence) {
+	ctx := context.Background()
+	pgram := pgramBroadcast()
+	other := pgramOndemand333()
+	save(t, p, pgram, other)
+
+	got, err := p.LoadRecordingLogs(ctx, pgram.UUID)
+	if err != nil || len(got) != 0 {
+		t.Errorf("LoadRecordingLogs() = %v, %v, want empty (not saved yet)", got, err)
+	}
+
+	startedAt := time.Date(2022, 8, 17, 22, 59, 0, 0, timeutil.LocationJST())
+	second := recorder.RecordingLog{FilePath: "/archive/agqr/file_20220817-231000.log", StartedAt: startedAt.Add(11 * time.Minute)}
+	first := recorder.RecordingLog{FilePath: "/archive/agqr/file_20220817-225900.log", StartedAt: startedAt}
+	otherLog := recorder.RecordingLog{FilePath: "/archive/onsen/file_20220818-120000.log", StartedAt: startedAt.Add(13 * time.Hour)}
+	for _, l := range []struct {
+		pgram  program.Program
+		recLog recorder.RecordingLog
+	}{{pgram, second}, {pgram, first}, {other, otherLog}} {
+		err := p.SaveRecordingLog(ctx, l.pgram, l.recLog)
+		if err != nil {
+			t.Fatalf("SaveRecordingLog() error = %v", err)
+		}
+	}
+	first.ProgramUUID = pgram.UUID
+	second.ProgramUUID = pgram.UUID
+	otherLog.ProgramUUID = other.UUID
+
+	got, err = p.LoadRecordingLogs(ctx, pgram.UUID)
+	if err != nil {
+		t.Fatalf("LoadRecordingLogs() error = %v", err)
+	}
+	if diff := cmp.Diff([]recorder.RecordingLog{first, second}, got); diff != "" {
+		t.Errorf("LoadRecordingLogs() mismatch (-want +got):\n%s", diff)
+	}
+
+	err = p.SaveRecordingLog(ctx, pgramOndemand334(), first)
+	if !testutil.ErrorsAs(err, errutil.ErrDatabaseNotFoundProgram) {
+		t.Errorf("SaveRecordingLog() error = %v, wantErr %v", err, errutil.ErrDatabaseNotFoundProgram)
+	}
+
+	// before ちょうどのものは残す
+	deleted, err := p.DeleteRecordingLogsBefore(ctx, second.StartedAt)
+	if err != nil {
+		t.Fatalf("DeleteRecordingLogsBefore() error = %v", err)
+	}
+	if diff := cmp.Diff([]recorder.RecordingLog{first}, deleted); diff != "" {
+		t.Errorf("DeleteRecordingLogsBefore() mismatch (-want +got):\n%s", diff)
+	}
+	got, err = p.LoadRecordingLogs(ctx, pgram.UUID)
+	if err != nil {
+		t.Fatalf("LoadRecordingLogs() error = %v", err)
+	}
+	if diff := cmp.Diff([]recorder.RecordingLog{second}, got); diff != "" {
+		t.Errorf("LoadRecordingLogs() mismatch (-want +got):\n%s", diff)
+	}
+
+	// 他の番組の録画ログは別
+	got, err = p.LoadRecordingLogs(ctx, other.UUID)
+	if err != nil {
+		t.Fatalf("LoadRecordingLogs() error = %v", err)
+	}
+	if diff := cmp.Diff([]recorder.RecordingLog{otherLog}, got); diff != "" {
+		t.Errorf("LoadRecordingLogs() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,127 @@
+// XMLTV（https://github.com/XMLTV/xmltv/blob/master/xmltv.dtd）の番組表を書き出す
+package xmltv
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const (
+	generatorName = "anrd"
+	lang          = "ja"
+	// 20060102150405 -0700
+	timeLayout = "20060102150405 -0700"
+)
+
+type tv struct {
+	XMLName           xml.Name    `xml:"tv"`
+	GeneratorInfoName string      `xml:"generator-info-name,attr"`
+	Channels          []channel   `xml:"channel"`
+	Programmes        []programme `xml:"programme"`
+}
+
+type channel struct {
+	ID          string     `xml:"id,attr"`
+	DisplayName langString `xml:"display-name"`
+}
+
+type programme struct {
+	Start    string      `xml:"start,attr"`
+	Stop     string      `xml:"stop,attr,omitempty"`
+	Channel  string      `xml:"channel,attr"`
+	Title    langString  `xml:"title"`
+	SubTitle *langString `xml:"sub-title,omitempty"`
+	Desc     *langString `xml:"desc,omitempty"`
+	Credits  *credits    `xml:"credits,omitempty"`
+	Icon     *icon       `xml:"icon,omitempty"`
+}
+
+type langString struct {
+	Lang  string `xml:"lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+type credits struct {
+	Presenters []string `xml:"presenter"`
+}
+
+type icon struct {
+	Src string `xml:"src,attr"`
+}
+
+type Guide struct {
+	Channels   []Channel
+	Programmes []Programme
+}
+
+type Channel struct {
+	// Programme.Channel から参照する
+	ID          string
+	DisplayName string
+}
+
+type Programme struct {
+	Channel string
+	Start   time.Time
+	// ゼロ値であれば出力しない
+	Stop time.Time
+
+	Title string
+	// 以下は空であれば出力しない
+	SubTitle    string
+	Description string
+	Presenters  []string
+	IconURL     string
+}
+
+func Encode(w io.Writer, guide Guide) error {
+	doc := tv{
+		GeneratorInfoName: generatorName,
+	}
+	for _, ch := range guide.Channels {
+		doc.Channels = append(doc.Channels, channel{
+			ID:          ch.ID,
+			DisplayName: langString{Lang: lang, Value: ch.DisplayName},
+		})
+	}
+	for _, pg := range guide.Programmes {
+		p := programme{
+			Start:    pg.Start.Format(timeLayout),
+			Channel:  pg.Channel,
+			Title:    langString{Lang: lang, Value: pg.Title},
+			SubTitle: optionalLangString(pg.SubTitle),
+			Desc:     optionalLangString(pg.Description),
+		}
+		if !pg.Stop.IsZero() {
+			p.Stop = pg.Stop.Format(timeLayout)
+		}
+		if len(pg.Presenters) > 0 {
+			p.Credits = &credits{Presenters: pg.Presenters}
+		}
+		if pg.IconURL != "" {
+			p.Icon = &icon{Src: pg.IconURL}
+		}
+		doc.Programmes = append(doc.Programmes, p)
+	}
+
+	_, err := io.WriteString(w, xml.Header+`<!DOCTYPE tv SYSTEM "xmltv.dtd">`+"\n")
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func optionalLangString(value string) *langString {
+	if value == "" {
+		return nil
+	}
+	return &langString{Lang: lang, Value: value}
+}
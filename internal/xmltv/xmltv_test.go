@@ -0,0 +1,60 @@
+package xmltv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+func TestEncode(t *testing.T) {
+	guide := Guide{
+		Channels: []Channel{
+			{ID: "agqr.anrd", DisplayName: "超！A&G+"},
+		},
+		Programmes: []Programme{
+			{
+				Channel:     "agqr.anrd",
+				Start:       time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+				Stop:        time.Date(2022, 8, 18, 1, 0, 0, 0, timeutil.LocationJST()),
+				Title:       "鷲崎健のヨルナイト×ヨルナイト",
+				Description: "鷲崎健による生放送。",
+				Presenters:  []string{"鷲崎健", "青木佑磨"},
+				IconURL:     "https://agqr.test/image.jpg",
+			},
+			{
+				Channel:  "agqr.anrd",
+				Start:    time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+				Title:    "セブン-イレブン presents 佐倉としたい大西",
+				SubTitle: "第334回",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Encode(&buf, guide)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		`<!DOCTYPE tv SYSTEM "xmltv.dtd">`,
+		`<tv generator-info-name="anrd">`,
+		`<channel id="agqr.anrd">`,
+		`<display-name lang="ja">超！A&amp;G+</display-name>`,
+		`<programme start="20220818000000 +0900" stop="20220818010000 +0900" channel="agqr.anrd">`,
+		`<desc lang="ja">鷲崎健による生放送。</desc>`,
+		"<credits>\n      <presenter>鷲崎健</presenter>\n      <presenter>青木佑磨</presenter>\n    </credits>",
+		`<icon src="https://agqr.test/image.jpg"></icon>`,
+		// stop がなければ出力しない
+		`<programme start="20220823000000 +0900" channel="agqr.anrd">`,
+		`<sub-title lang="ja">第334回</sub-title>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Encode() does not contain %s\n%s", want, got)
+		}
+	}
+}
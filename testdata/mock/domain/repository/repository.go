@@ -11,8 +11,10 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	date "github.com/sobadon/anrd/domain/model/date"
+	notification "github.com/sobadon/anrd/domain/model/notification"
 	program "github.com/sobadon/anrd/domain/model/program"
 	recorder "github.com/sobadon/anrd/domain/model/recorder"
+	repository "github.com/sobadon/anrd/domain/repository"
 )
 
 // MockStation is a mock of Station interface.
@@ -38,6 +40,20 @@ func (m *MockStation) EXPECT() *MockStationMockRecorder {
 	return m.recorder
 }
 
+// ArchiveFilePath mocks base method.
+func (m *MockStation) ArchiveFilePath(config recorder.Config, pgram program.Program) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveFilePath", config, pgram)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ArchiveFilePath indicates an expected call of ArchiveFilePath.
+func (mr *MockStationMockRecorder) ArchiveFilePath(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveFilePath", reflect.TypeOf((*MockStation)(nil).ArchiveFilePath), config, pgram)
+}
+
 // GetPrograms mocks base method.
 func (m *MockStation) GetPrograms(ctx context.Context, date date.Date) ([]program.Program, error) {
 	m.ctrl.T.Helper()
@@ -67,6 +83,468 @@ func (mr *MockStationMockRecorder) Rec(ctx, config, targetPgram interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rec", reflect.TypeOf((*MockStation)(nil).Rec), ctx, config, targetPgram)
 }
 
+// RecCommand mocks base method.
+func (m *MockStation) RecCommand(config recorder.Config, pgram program.Program) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecCommand", config, pgram)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// RecCommand indicates an expected call of RecCommand.
+func (mr *MockStationMockRecorder) RecCommand(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecCommand", reflect.TypeOf((*MockStation)(nil).RecCommand), config, pgram)
+}
+
+// MockContinuousStation is a mock of ContinuousStation interface.
+type MockContinuousStation struct {
+	ctrl     *gomock.Controller
+	recorder *MockContinuousStationMockRecorder
+}
+
+// MockContinuousStationMockRecorder is the mock recorder for MockContinuousStation.
+type MockContinuousStationMockRecorder struct {
+	mock *MockContinuousStation
+}
+
+// NewMockContinuousStation creates a new mock instance.
+func NewMockContinuousStation(ctrl *gomock.Controller) *MockContinuousStation {
+	mock := &MockContinuousStation{ctrl: ctrl}
+	mock.recorder = &MockContinuousStationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockContinuousStation) EXPECT() *MockContinuousStationMockRecorder {
+	return m.recorder
+}
+
+// ArchiveFilePath mocks base method.
+func (m *MockContinuousStation) ArchiveFilePath(config recorder.Config, pgram program.Program) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveFilePath", config, pgram)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ArchiveFilePath indicates an expected call of ArchiveFilePath.
+func (mr *MockContinuousStationMockRecorder) ArchiveFilePath(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveFilePath", reflect.TypeOf((*MockContinuousStation)(nil).ArchiveFilePath), config, pgram)
+}
+
+// GetPrograms mocks base method.
+func (m *MockContinuousStation) GetPrograms(ctx context.Context, date date.Date) ([]program.Program, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrograms", ctx, date)
+	ret0, _ := ret[0].([]program.Program)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrograms indicates an expected call of GetPrograms.
+func (mr *MockContinuousStationMockRecorder) GetPrograms(ctx, date interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrograms", reflect.TypeOf((*MockContinuousStation)(nil).GetPrograms), ctx, date)
+}
+
+// Rec mocks base method.
+func (m *MockContinuousStation) Rec(ctx context.Context, config recorder.Config, targetPgram program.Program) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rec", ctx, config, targetPgram)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rec indicates an expected call of Rec.
+func (mr *MockContinuousStationMockRecorder) Rec(ctx, config, targetPgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rec", reflect.TypeOf((*MockContinuousStation)(nil).Rec), ctx, config, targetPgram)
+}
+
+// RecCommand mocks base method.
+func (m *MockContinuousStation) RecCommand(config recorder.Config, pgram program.Program) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecCommand", config, pgram)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// RecCommand indicates an expected call of RecCommand.
+func (mr *MockContinuousStationMockRecorder) RecCommand(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecCommand", reflect.TypeOf((*MockContinuousStation)(nil).RecCommand), config, pgram)
+}
+
+// RecContinuous mocks base method.
+func (m *MockContinuousStation) RecContinuous(ctx context.Context, config recorder.Config, pgrams []program.Program) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecContinuous", ctx, config, pgrams)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecContinuous indicates an expected call of RecContinuous.
+func (mr *MockContinuousStationMockRecorder) RecContinuous(ctx, config, pgrams interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecContinuous", reflect.TypeOf((*MockContinuousStation)(nil).RecContinuous), ctx, config, pgrams)
+}
+
+// MockResumableStation is a mock of ResumableStation interface.
+type MockResumableStation struct {
+	ctrl     *gomock.Controller
+	recorder *MockResumableStationMockRecorder
+}
+
+// MockResumableStationMockRecorder is the mock recorder for MockResumableStation.
+type MockResumableStationMockRecorder struct {
+	mock *MockResumableStation
+}
+
+// NewMockResumableStation creates a new mock instance.
+func NewMockResumableStation(ctrl *gomock.Controller) *MockResumableStation {
+	mock := &MockResumableStation{ctrl: ctrl}
+	mock.recorder = &MockResumableStationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResumableStation) EXPECT() *MockResumableStationMockRecorder {
+	return m.recorder
+}
+
+// ArchiveFilePath mocks base method.
+func (m *MockResumableStation) ArchiveFilePath(config recorder.Config, pgram program.Program) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveFilePath", config, pgram)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ArchiveFilePath indicates an expected call of ArchiveFilePath.
+func (mr *MockResumableStationMockRecorder) ArchiveFilePath(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveFilePath", reflect.TypeOf((*MockResumableStation)(nil).ArchiveFilePath), config, pgram)
+}
+
+// ConcatParts mocks base method.
+func (m *MockResumableStation) ConcatParts(ctx context.Context, config recorder.Config, pgram program.Program, parts []recorder.Part) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConcatParts", ctx, config, pgram, parts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConcatParts indicates an expected call of ConcatParts.
+func (mr *MockResumableStationMockRecorder) ConcatParts(ctx, config, pgram, parts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConcatParts", reflect.TypeOf((*MockResumableStation)(nil).ConcatParts), ctx, config, pgram, parts)
+}
+
+// GetPrograms mocks base method.
+func (m *MockResumableStation) GetPrograms(ctx context.Context, date date.Date) ([]program.Program, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrograms", ctx, date)
+	ret0, _ := ret[0].([]program.Program)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrograms indicates an expected call of GetPrograms.
+func (mr *MockResumableStationMockRecorder) GetPrograms(ctx, date interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrograms", reflect.TypeOf((*MockResumableStation)(nil).GetPrograms), ctx, date)
+}
+
+// Rec mocks base method.
+func (m *MockResumableStation) Rec(ctx context.Context, config recorder.Config, targetPgram program.Program) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rec", ctx, config, targetPgram)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rec indicates an expected call of Rec.
+func (mr *MockResumableStationMockRecorder) Rec(ctx, config, targetPgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rec", reflect.TypeOf((*MockResumableStation)(nil).Rec), ctx, config, targetPgram)
+}
+
+// RecCommand mocks base method.
+func (m *MockResumableStation) RecCommand(config recorder.Config, pgram program.Program) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecCommand", config, pgram)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// RecCommand indicates an expected call of RecCommand.
+func (mr *MockResumableStationMockRecorder) RecCommand(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecCommand", reflect.TypeOf((*MockResumableStation)(nil).RecCommand), config, pgram)
+}
+
+// RecPart mocks base method.
+func (m *MockResumableStation) RecPart(ctx context.Context, config recorder.Config, pgram program.Program, index int, duration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecPart", ctx, config, pgram, index, duration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecPart indicates an expected call of RecPart.
+func (mr *MockResumableStationMockRecorder) RecPart(ctx, config, pgram, index, duration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecPart", reflect.TypeOf((*MockResumableStation)(nil).RecPart), ctx, config, pgram, index, duration)
+}
+
+// MockRedundantStation is a mock of RedundantStation interface.
+type MockRedundantStation struct {
+	ctrl     *gomock.Controller
+	recorder *MockRedundantStationMockRecorder
+}
+
+// MockRedundantStationMockRecorder is the mock recorder for MockRedundantStation.
+type MockRedundantStationMockRecorder struct {
+	mock *MockRedundantStation
+}
+
+// NewMockRedundantStation creates a new mock instance.
+func NewMockRedundantStation(ctrl *gomock.Controller) *MockRedundantStation {
+	mock := &MockRedundantStation{ctrl: ctrl}
+	mock.recorder = &MockRedundantStationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRedundantStation) EXPECT() *MockRedundantStationMockRecorder {
+	return m.recorder
+}
+
+// ArchiveFilePath mocks base method.
+func (m *MockRedundantStation) ArchiveFilePath(config recorder.Config, pgram program.Program) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveFilePath", config, pgram)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ArchiveFilePath indicates an expected call of ArchiveFilePath.
+func (mr *MockRedundantStationMockRecorder) ArchiveFilePath(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveFilePath", reflect.TypeOf((*MockRedundantStation)(nil).ArchiveFilePath), config, pgram)
+}
+
+// GetPrograms mocks base method.
+func (m *MockRedundantStation) GetPrograms(ctx context.Context, date date.Date) ([]program.Program, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrograms", ctx, date)
+	ret0, _ := ret[0].([]program.Program)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrograms indicates an expected call of GetPrograms.
+func (mr *MockRedundantStationMockRecorder) GetPrograms(ctx, date interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrograms", reflect.TypeOf((*MockRedundantStation)(nil).GetPrograms), ctx, date)
+}
+
+// Probe mocks base method.
+func (m *MockRedundantStation) Probe(ctx context.Context, file string) (recorder.Probe, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Probe", ctx, file)
+	ret0, _ := ret[0].(recorder.Probe)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Probe indicates an expected call of Probe.
+func (mr *MockRedundantStationMockRecorder) Probe(ctx, file interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Probe", reflect.TypeOf((*MockRedundantStation)(nil).Probe), ctx, file)
+}
+
+// Rec mocks base method.
+func (m *MockRedundantStation) Rec(ctx context.Context, config recorder.Config, targetPgram program.Program) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rec", ctx, config, targetPgram)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rec indicates an expected call of Rec.
+func (mr *MockRedundantStationMockRecorder) Rec(ctx, config, targetPgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rec", reflect.TypeOf((*MockRedundantStation)(nil).Rec), ctx, config, targetPgram)
+}
+
+// RecCommand mocks base method.
+func (m *MockRedundantStation) RecCommand(config recorder.Config, pgram program.Program) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecCommand", config, pgram)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// RecCommand indicates an expected call of RecCommand.
+func (mr *MockRedundantStationMockRecorder) RecCommand(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecCommand", reflect.TypeOf((*MockRedundantStation)(nil).RecCommand), config, pgram)
+}
+
+// RecSource mocks base method.
+func (m *MockRedundantStation) RecSource(ctx context.Context, config recorder.Config, pgram program.Program, source int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecSource", ctx, config, pgram, source)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecSource indicates an expected call of RecSource.
+func (mr *MockRedundantStationMockRecorder) RecSource(ctx, config, pgram, source interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecSource", reflect.TypeOf((*MockRedundantStation)(nil).RecSource), ctx, config, pgram, source)
+}
+
+// SourceFilePath mocks base method.
+func (m *MockRedundantStation) SourceFilePath(config recorder.Config, pgram program.Program, source int) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SourceFilePath", config, pgram, source)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// SourceFilePath indicates an expected call of SourceFilePath.
+func (mr *MockRedundantStationMockRecorder) SourceFilePath(config, pgram, source interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SourceFilePath", reflect.TypeOf((*MockRedundantStation)(nil).SourceFilePath), config, pgram, source)
+}
+
+// Sources mocks base method.
+func (m *MockRedundantStation) Sources() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sources")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Sources indicates an expected call of Sources.
+func (mr *MockRedundantStationMockRecorder) Sources() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sources", reflect.TypeOf((*MockRedundantStation)(nil).Sources))
+}
+
+// MockPlanStation is a mock of PlanStation interface.
+type MockPlanStation struct {
+	ctrl     *gomock.Controller
+	recorder *MockPlanStationMockRecorder
+}
+
+// MockPlanStationMockRecorder is the mock recorder for MockPlanStation.
+type MockPlanStationMockRecorder struct {
+	mock *MockPlanStation
+}
+
+// NewMockPlanStation creates a new mock instance.
+func NewMockPlanStation(ctrl *gomock.Controller) *MockPlanStation {
+	mock := &MockPlanStation{ctrl: ctrl}
+	mock.recorder = &MockPlanStationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPlanStation) EXPECT() *MockPlanStationMockRecorder {
+	return m.recorder
+}
+
+// ArchiveFilePath mocks base method.
+func (m *MockPlanStation) ArchiveFilePath(config recorder.Config, pgram program.Program) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveFilePath", config, pgram)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ArchiveFilePath indicates an expected call of ArchiveFilePath.
+func (mr *MockPlanStationMockRecorder) ArchiveFilePath(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveFilePath", reflect.TypeOf((*MockPlanStation)(nil).ArchiveFilePath), config, pgram)
+}
+
+// GetPrograms mocks base method.
+func (m *MockPlanStation) GetPrograms(ctx context.Context, date date.Date) ([]program.Program, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrograms", ctx, date)
+	ret0, _ := ret[0].([]program.Program)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrograms indicates an expected call of GetPrograms.
+func (mr *MockPlanStationMockRecorder) GetPrograms(ctx, date interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrograms", reflect.TypeOf((*MockPlanStation)(nil).GetPrograms), ctx, date)
+}
+
+// Plan mocks base method.
+func (m *MockPlanStation) Plan(ctx context.Context, config recorder.Config, mode recorder.Mode, pgrams []program.Program) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Plan", ctx, config, mode, pgrams)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Plan indicates an expected call of Plan.
+func (mr *MockPlanStationMockRecorder) Plan(ctx, config, mode, pgrams interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Plan", reflect.TypeOf((*MockPlanStation)(nil).Plan), ctx, config, mode, pgrams)
+}
+
+// Rec mocks base method.
+func (m *MockPlanStation) Rec(ctx context.Context, config recorder.Config, targetPgram program.Program) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rec", ctx, config, targetPgram)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rec indicates an expected call of Rec.
+func (mr *MockPlanStationMockRecorder) Rec(ctx, config, targetPgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rec", reflect.TypeOf((*MockPlanStation)(nil).Rec), ctx, config, targetPgram)
+}
+
+// RecCommand mocks base method.
+func (m *MockPlanStation) RecCommand(config recorder.Config, pgram program.Program) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecCommand", config, pgram)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// RecCommand indicates an expected call of RecCommand.
+func (mr *MockPlanStationMockRecorder) RecCommand(config, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecCommand", reflect.TypeOf((*MockPlanStation)(nil).RecCommand), config, pgram)
+}
+
+// Unwrap mocks base method.
+func (m *MockPlanStation) Unwrap() repository.Station {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unwrap")
+	ret0, _ := ret[0].(repository.Station)
+	return ret0
+}
+
+// Unwrap indicates an expected call of Unwrap.
+func (mr *MockPlanStationMockRecorder) Unwrap() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unwrap", reflect.TypeOf((*MockPlanStation)(nil).Unwrap))
+}
+
 // MockProgramPersistence is a mock of ProgramPersistence interface.
 type MockProgramPersistence struct {
 	ctrl     *gomock.Controller
@@ -90,6 +568,48 @@ func (m *MockProgramPersistence) EXPECT() *MockProgramPersistenceMockRecorder {
 	return m.recorder
 }
 
+// ChangeFilePath mocks base method.
+func (m *MockProgramPersistence) ChangeFilePath(ctx context.Context, pgram program.Program, filePath string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeFilePath", ctx, pgram, filePath)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeFilePath indicates an expected call of ChangeFilePath.
+func (mr *MockProgramPersistenceMockRecorder) ChangeFilePath(ctx, pgram, filePath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeFilePath", reflect.TypeOf((*MockProgramPersistence)(nil).ChangeFilePath), ctx, pgram, filePath)
+}
+
+// ChangeObjectKey mocks base method.
+func (m *MockProgramPersistence) ChangeObjectKey(ctx context.Context, pgram program.Program, objectKey string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeObjectKey", ctx, pgram, objectKey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeObjectKey indicates an expected call of ChangeObjectKey.
+func (mr *MockProgramPersistenceMockRecorder) ChangeObjectKey(ctx, pgram, objectKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeObjectKey", reflect.TypeOf((*MockProgramPersistence)(nil).ChangeObjectKey), ctx, pgram, objectKey)
+}
+
+// ChangeProtected mocks base method.
+func (m *MockProgramPersistence) ChangeProtected(ctx context.Context, pgram program.Program, protected bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeProtected", ctx, pgram, protected)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeProtected indicates an expected call of ChangeProtected.
+func (mr *MockProgramPersistenceMockRecorder) ChangeProtected(ctx, pgram, protected interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeProtected", reflect.TypeOf((*MockProgramPersistence)(nil).ChangeProtected), ctx, pgram, protected)
+}
+
 // ChangeStatus mocks base method.
 func (m *MockProgramPersistence) ChangeStatus(ctx context.Context, pgram program.Program, newStatus program.Status) error {
 	m.ctrl.T.Helper()
@@ -104,6 +624,80 @@ func (mr *MockProgramPersistenceMockRecorder) ChangeStatus(ctx, pgram, newStatus
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeStatus", reflect.TypeOf((*MockProgramPersistence)(nil).ChangeStatus), ctx, pgram, newStatus)
 }
 
+// CheckSchema mocks base method.
+func (m *MockProgramPersistence) CheckSchema(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSchema", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSchema indicates an expected call of CheckSchema.
+func (mr *MockProgramPersistenceMockRecorder) CheckSchema(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSchema", reflect.TypeOf((*MockProgramPersistence)(nil).CheckSchema), ctx)
+}
+
+// CountOndemandScheduled mocks base method.
+func (m *MockProgramPersistence) CountOndemandScheduled(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountOndemandScheduled", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountOndemandScheduled indicates an expected call of CountOndemandScheduled.
+func (mr *MockProgramPersistenceMockRecorder) CountOndemandScheduled(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountOndemandScheduled", reflect.TypeOf((*MockProgramPersistence)(nil).CountOndemandScheduled), ctx)
+}
+
+// DeleteRecordingLogsBefore mocks base method.
+func (m *MockProgramPersistence) DeleteRecordingLogsBefore(ctx context.Context, before time.Time) ([]recorder.RecordingLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecordingLogsBefore", ctx, before)
+	ret0, _ := ret[0].([]recorder.RecordingLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteRecordingLogsBefore indicates an expected call of DeleteRecordingLogsBefore.
+func (mr *MockProgramPersistenceMockRecorder) DeleteRecordingLogsBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecordingLogsBefore", reflect.TypeOf((*MockProgramPersistence)(nil).DeleteRecordingLogsBefore), ctx, before)
+}
+
+// Load mocks base method.
+func (m *MockProgramPersistence) Load(ctx context.Context, filter program.Filter) ([]program.Program, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load", ctx, filter)
+	ret0, _ := ret[0].([]program.Program)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Load indicates an expected call of Load.
+func (mr *MockProgramPersistenceMockRecorder) Load(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockProgramPersistence)(nil).Load), ctx, filter)
+}
+
+// LoadAttempts mocks base method.
+func (m *MockProgramPersistence) LoadAttempts(ctx context.Context, uuid string) ([]recorder.Attempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadAttempts", ctx, uuid)
+	ret0, _ := ret[0].([]recorder.Attempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadAttempts indicates an expected call of LoadAttempts.
+func (mr *MockProgramPersistenceMockRecorder) LoadAttempts(ctx, uuid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadAttempts", reflect.TypeOf((*MockProgramPersistence)(nil).LoadAttempts), ctx, uuid)
+}
+
 // LoadBroadcastStartIn mocks base method.
 func (m *MockProgramPersistence) LoadBroadcastStartIn(ctx context.Context, now time.Time, duration time.Duration) ([]program.Program, error) {
 	m.ctrl.T.Helper()
@@ -119,6 +713,36 @@ func (mr *MockProgramPersistenceMockRecorder) LoadBroadcastStartIn(ctx, now, dur
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadBroadcastStartIn", reflect.TypeOf((*MockProgramPersistence)(nil).LoadBroadcastStartIn), ctx, now, duration)
 }
 
+// LoadByUUID mocks base method.
+func (m *MockProgramPersistence) LoadByUUID(ctx context.Context, uuid string) (program.Program, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadByUUID", ctx, uuid)
+	ret0, _ := ret[0].(program.Program)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadByUUID indicates an expected call of LoadByUUID.
+func (mr *MockProgramPersistenceMockRecorder) LoadByUUID(ctx, uuid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadByUUID", reflect.TypeOf((*MockProgramPersistence)(nil).LoadByUUID), ctx, uuid)
+}
+
+// LoadDone mocks base method.
+func (m *MockProgramPersistence) LoadDone(ctx context.Context) ([]program.Program, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadDone", ctx)
+	ret0, _ := ret[0].([]program.Program)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadDone indicates an expected call of LoadDone.
+func (mr *MockProgramPersistenceMockRecorder) LoadDone(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadDone", reflect.TypeOf((*MockProgramPersistence)(nil).LoadDone), ctx)
+}
+
 // LoadOndemandScheduled mocks base method.
 func (m *MockProgramPersistence) LoadOndemandScheduled(ctx context.Context, limit int) (*[]program.Program, error) {
 	m.ctrl.T.Helper()
@@ -134,6 +758,50 @@ func (mr *MockProgramPersistenceMockRecorder) LoadOndemandScheduled(ctx, limit i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadOndemandScheduled", reflect.TypeOf((*MockProgramPersistence)(nil).LoadOndemandScheduled), ctx, limit)
 }
 
+// LoadRecordingLogs mocks base method.
+func (m *MockProgramPersistence) LoadRecordingLogs(ctx context.Context, uuid string) ([]recorder.RecordingLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadRecordingLogs", ctx, uuid)
+	ret0, _ := ret[0].([]recorder.RecordingLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadRecordingLogs indicates an expected call of LoadRecordingLogs.
+func (mr *MockProgramPersistenceMockRecorder) LoadRecordingLogs(ctx, uuid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadRecordingLogs", reflect.TypeOf((*MockProgramPersistence)(nil).LoadRecordingLogs), ctx, uuid)
+}
+
+// LoadUpdatedAt mocks base method.
+func (m *MockProgramPersistence) LoadUpdatedAt(ctx context.Context, uuids []string) (map[string]time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadUpdatedAt", ctx, uuids)
+	ret0, _ := ret[0].(map[string]time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadUpdatedAt indicates an expected call of LoadUpdatedAt.
+func (mr *MockProgramPersistenceMockRecorder) LoadUpdatedAt(ctx, uuids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadUpdatedAt", reflect.TypeOf((*MockProgramPersistence)(nil).LoadUpdatedAt), ctx, uuids)
+}
+
+// Ping mocks base method.
+func (m *MockProgramPersistence) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockProgramPersistenceMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockProgramPersistence)(nil).Ping), ctx)
+}
+
 // Save mocks base method.
 func (m *MockProgramPersistence) Save(ctx context.Context, pgram program.Program) error {
 	m.ctrl.T.Helper()
@@ -147,3 +815,121 @@ func (mr *MockProgramPersistenceMockRecorder) Save(ctx, pgram interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockProgramPersistence)(nil).Save), ctx, pgram)
 }
+
+// SaveAttempts mocks base method.
+func (m *MockProgramPersistence) SaveAttempts(ctx context.Context, pgram program.Program, attempts []recorder.Attempt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAttempts", ctx, pgram, attempts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAttempts indicates an expected call of SaveAttempts.
+func (mr *MockProgramPersistenceMockRecorder) SaveAttempts(ctx, pgram, attempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAttempts", reflect.TypeOf((*MockProgramPersistence)(nil).SaveAttempts), ctx, pgram, attempts)
+}
+
+// SaveRecordingLog mocks base method.
+func (m *MockProgramPersistence) SaveRecordingLog(ctx context.Context, pgram program.Program, recLog recorder.RecordingLog) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRecordingLog", ctx, pgram, recLog)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRecordingLog indicates an expected call of SaveRecordingLog.
+func (mr *MockProgramPersistenceMockRecorder) SaveRecordingLog(ctx, pgram, recLog interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRecordingLog", reflect.TypeOf((*MockProgramPersistence)(nil).SaveRecordingLog), ctx, pgram, recLog)
+}
+
+// Upsert mocks base method.
+func (m *MockProgramPersistence) Upsert(ctx context.Context, pgram program.Program) (program.Program, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upsert", ctx, pgram)
+	ret0, _ := ret[0].(program.Program)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Upsert indicates an expected call of Upsert.
+func (mr *MockProgramPersistenceMockRecorder) Upsert(ctx, pgram interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upsert", reflect.TypeOf((*MockProgramPersistence)(nil).Upsert), ctx, pgram)
+}
+
+// MockObjectStorage is a mock of ObjectStorage interface.
+type MockObjectStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockObjectStorageMockRecorder
+}
+
+// MockObjectStorageMockRecorder is the mock recorder for MockObjectStorage.
+type MockObjectStorageMockRecorder struct {
+	mock *MockObjectStorage
+}
+
+// NewMockObjectStorage creates a new mock instance.
+func NewMockObjectStorage(ctrl *gomock.Controller) *MockObjectStorage {
+	mock := &MockObjectStorage{ctrl: ctrl}
+	mock.recorder = &MockObjectStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockObjectStorage) EXPECT() *MockObjectStorageMockRecorder {
+	return m.recorder
+}
+
+// Upload mocks base method.
+func (m *MockObjectStorage) Upload(ctx context.Context, localPath, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upload", ctx, localPath, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Upload indicates an expected call of Upload.
+func (mr *MockObjectStorageMockRecorder) Upload(ctx, localPath, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockObjectStorage)(nil).Upload), ctx, localPath, key)
+}
+
+// MockNotifier is a mock of Notifier interface.
+type MockNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotifierMockRecorder
+}
+
+// MockNotifierMockRecorder is the mock recorder for MockNotifier.
+type MockNotifierMockRecorder struct {
+	mock *MockNotifier
+}
+
+// NewMockNotifier creates a new mock instance.
+func NewMockNotifier(ctrl *gomock.Controller) *MockNotifier {
+	mock := &MockNotifier{ctrl: ctrl}
+	mock.recorder = &MockNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotifier) EXPECT() *MockNotifierMockRecorder {
+	return m.recorder
+}
+
+// Notify mocks base method.
+func (m *MockNotifier) Notify(ctx context.Context, n notification.Notification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Notify", ctx, n)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Notify indicates an expected call of Notify.
+func (mr *MockNotifierMockRecorder) Notify(ctx, n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockNotifier)(nil).Notify), ctx, n)
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/ical"
+)
+
+type ucCalendar struct {
+	programPersistence repository.ProgramPersistence
+	clock              clock.Clock
+
+	// 今よりこれだけ前に始まった番組まで含める
+	past time.Duration
+}
+
+func NewCalendar(programPersistence repository.ProgramPersistence, clk clock.Clock, past time.Duration) *ucCalendar {
+	return &ucCalendar{
+		programPersistence: programPersistence,
+		clock:              clk,
+		past:               past,
+	}
+}
+
+// 録画する（した）番組の予定
+// station が空であればすべての station
+// - broadcast な番組は放送時間の予定
+// - ondemand な番組は scheduled, recording なものを配信日の終日の予定
+// skipped, deleted な番組は STATUS:CANCELLED として残し、カレンダーから消えたことがわかるようにする
+func (c *ucCalendar) Calendar(ctx context.Context, station program.Station) (ical.Calendar, error) {
+	now := c.clock.Now()
+	from := now.Add(-c.past)
+
+	var pgrams []program.Program
+	for _, filter := range []program.Filter{
+		{Station: station, StreamType: program.StreamTypeBroadcast, StartFrom: from},
+		{Station: station, StreamType: program.StreamTypeOndemand, Status: program.StatusScheduled},
+		{Station: station, StreamType: program.StreamTypeOndemand, Status: program.StatusRecording},
+		{Station: station, StreamType: program.StreamTypeOndemand, Status: program.StatusSkipped, StartFrom: from},
+		{Station: station, StreamType: program.StreamTypeOndemand, Status: program.StatusDeleted, StartFrom: from},
+	} {
+		loaded, err := c.programPersistence.Load(ctx, filter)
+		if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+			continue
+		}
+		if err != nil {
+			return ical.Calendar{}, err
+		}
+		pgrams = append(pgrams, loaded...)
+	}
+	sort.SliceStable(pgrams, func(i, j int) bool {
+		return pgrams[i].Start.Before(pgrams[j].Start)
+	})
+
+	uuids := make([]string, 0, len(pgrams))
+	for _, pgram := range pgrams {
+		uuids = append(uuids, pgram.UUID)
+	}
+	updatedAt, err := c.programPersistence.LoadUpdatedAt(ctx, uuids)
+	if err != nil {
+		return ical.Calendar{}, err
+	}
+
+	name := "anrd"
+	if station != "" {
+		name = "anrd " + station.String()
+	}
+	cal := ical.Calendar{
+		Name:  name,
+		Stamp: now,
+	}
+	for _, pgram := range pgrams {
+		cal.Events = append(cal.Events, calendarEvent(pgram, updatedAt[pgram.UUID]))
+	}
+	return cal, nil
+}
+
+// SEQUENCE の起点
+// iCalendar の INTEGER は 32 bit なので、Unix time ではなくこれからの秒数にする
+var sequenceEpoch = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// updatedAt は番組が最後に変更された日時
+// 変更されるたびに増えるので SEQUENCE に使い、放送時間の変更や取り消しを既存の予定より新しいものとして扱わせる
+func calendarEvent(pgram program.Program, updatedAt time.Time) ical.Event {
+	summary := pgram.Title
+	if pgram.Episode != "" {
+		summary = pgram.Title + " " + pgram.Episode
+	}
+	ev := ical.Event{
+		// 番組表の更新で放送時間などが変わっても同じ予定として扱われる
+		UID:         pgram.UUID + "@anrd",
+		Summary:     summary,
+		Description: "station: " + pgram.Station.String() + "\nstatus: " + pgram.Status.String(),
+		Start:       pgram.Start,
+		End:         pgram.End,
+		AllDay:      pgram.StreamType == program.StreamTypeOndemand,
+		Status:      ical.EventStatusConfirmed,
+	}
+	if updatedAt.After(sequenceEpoch) {
+		ev.Sequence = int(updatedAt.Sub(sequenceEpoch) / time.Second)
+	}
+	if pgram.Status == program.StatusSkipped || pgram.Status == program.StatusDeleted {
+		ev.Status = ical.EventStatusCancelled
+	}
+	return ev
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/ical"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+// LoadUpdatedAt が決まった日時を返す
+type fixedUpdatedAtPersistence struct {
+	repository.ProgramPersistence
+	updatedAt map[string]time.Time
+}
+
+func (p fixedUpdatedAtPersistence) LoadUpdatedAt(ctx context.Context, uuids []string) (map[string]time.Time, error) {
+	updatedAt := make(map[string]time.Time)
+	for _, uuid := range uuids {
+		if t, ok := p.updatedAt[uuid]; ok {
+			updatedAt[uuid] = t
+		}
+	}
+	return updatedAt, nil
+}
+
+func Test_ucCalendar_Calendar(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, timeutil.LocationJST())
+
+	yorunight := program.Program{
+		UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		ID:         1,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeBroadcast,
+	}
+	skipped := program.Program{
+		UUID:       "0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10",
+		ID:         2,
+		Station:    program.StationAgqr,
+		Title:      "A&G NEXT BREAKS",
+		Start:      time.Date(2022, 8, 17, 22, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 17, 22, 30, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusSkipped,
+		StreamType: program.StreamTypeBroadcast,
+	}
+	// past を過ぎているので含めない
+	old := program.Program{
+		UUID:       "8a1d0d55-4f0f-4f8e-9d4b-3c9c4d0f3a11",
+		ID:         3,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 3, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 4, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusDone,
+		StreamType: program.StreamTypeBroadcast,
+	}
+	ondemand := program.Program{
+		UUID:       "e07df7c6-eae8-40f8-8922-6b7ef0497dc8",
+		ID:         11133,
+		Station:    program.StationOnsen,
+		Title:      "セブン-イレブン presents 佐倉としたい大西",
+		Episode:    "第333回",
+		Start:      time.Date(2022, 8, 1, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeOndemand,
+	}
+	// 録画済みの ondemand は含めない
+	ondemandDone := program.Program{
+		UUID:       "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+		ID:         11132,
+		Station:    program.StationOnsen,
+		Title:      "セブン-イレブン presents 佐倉としたい大西",
+		Episode:    "第332回",
+		Start:      time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusDone,
+		StreamType: program.StreamTypeOndemand,
+	}
+
+	programPersistence := memory.New()
+	for _, pgram := range []program.Program{yorunight, skipped, old, ondemand, ondemandDone} {
+		err := programPersistence.Save(ctx, pgram)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// SEQUENCE は sequenceEpoch から最後に変更された日時までの秒数
+	persistence := fixedUpdatedAtPersistence{
+		ProgramPersistence: programPersistence,
+		updatedAt: map[string]time.Time{
+			yorunight.UUID: sequenceEpoch.Add(100 * time.Second),
+			skipped.UUID:   sequenceEpoch.Add(200 * time.Second),
+			ondemand.UUID:  sequenceEpoch.Add(300 * time.Second),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		station program.Station
+		want    ical.Calendar
+	}{
+		{
+			name: "すべての station",
+			want: ical.Calendar{
+				Name:  "anrd",
+				Stamp: now,
+				Events: []ical.Event{
+					{
+						UID:         "e07df7c6-eae8-40f8-8922-6b7ef0497dc8@anrd",
+						Summary:     "セブン-イレブン presents 佐倉としたい大西 第333回",
+						Description: "station: onsen\nstatus: scheduled",
+						Start:       ondemand.Start,
+						AllDay:      true,
+						Status:      ical.EventStatusConfirmed,
+						Sequence:    300,
+					},
+					{
+						UID:         "0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10@anrd",
+						Summary:     "A&G NEXT BREAKS",
+						Description: "station: agqr\nstatus: skipped",
+						Start:       skipped.Start,
+						End:         skipped.End,
+						Status:      ical.EventStatusCancelled,
+						Sequence:    200,
+					},
+					{
+						UID:         "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a@anrd",
+						Summary:     "鷲崎健のヨルナイト×ヨルナイト",
+						Description: "station: agqr\nstatus: scheduled",
+						Start:       yorunight.Start,
+						End:         yorunight.End,
+						Status:      ical.EventStatusConfirmed,
+						Sequence:    100,
+					},
+				},
+			},
+		},
+		{
+			name:    "station を指定",
+			station: program.StationOnsen,
+			want: ical.Calendar{
+				Name:  "anrd onsen",
+				Stamp: now,
+				Events: []ical.Event{
+					{
+						UID:         "e07df7c6-eae8-40f8-8922-6b7ef0497dc8@anrd",
+						Summary:     "セブン-イレブン presents 佐倉としたい大西 第333回",
+						Description: "station: onsen\nstatus: scheduled",
+						Start:       ondemand.Start,
+						AllDay:      true,
+						Status:      ical.EventStatusConfirmed,
+						Sequence:    300,
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCalendar(persistence, clock.NewFake(now), 7*24*time.Hour)
+			got, err := c.Calendar(ctx, tt.station)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Calendar() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/metrics"
+)
+
+// scheduler から呼び出される
+// ContinuousCapture であれば、続けて放送される番組をまとめて録画する
+func (r *ucRecorder) recBroadcast(ctx context.Context, config recorder.Config, startAt time.Time, targetPgram program.Program) {
+	station := r.station(targetPgram.Station)
+	_, ok := capableStation(station).(repository.ContinuousStation)
+	// 手動で開始日時を変更したものは、分割する位置がずれるのでまとめない
+	if !config.ContinuousCapture || !ok || !startAt.Equal(targetPgram.Start.Add(-config.Margin)) {
+		r.rec(ctx, config, startAt, targetPgram)
+		return
+	}
+
+	pgrams, err := r.followingPrograms(ctx, config, targetPgram)
+	if err != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to load following programs, rec alone (program = %+v): %+v", targetPgram, err)
+		r.rec(ctx, config, startAt, targetPgram)
+		return
+	}
+	if len(pgrams) == 1 {
+		r.rec(ctx, config, startAt, targetPgram)
+		return
+	}
+	r.recContinuous(ctx, config, startAt, station, pgrams)
+}
+
+// pgram と、それに続けて放送される scheduled な番組を Start の昇順で返す
+// 最初の番組の Start から最後の番組の End までが ContinuousCaptureMax を超えないところまで
+func (r *ucRecorder) followingPrograms(ctx context.Context, config recorder.Config, pgram program.Program) ([]program.Program, error) {
+	pgrams := []program.Program{pgram}
+	candidates, err := r.programPersistence.Load(ctx, program.Filter{
+		Station:    pgram.Station,
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeBroadcast,
+		StartFrom:  pgram.End,
+		StartTo:    pgram.Start.Add(config.ContinuousCaptureMax),
+	})
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		return pgrams, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		last := pgrams[len(pgrams)-1]
+		if candidate.Start.Before(last.End) {
+			// 重なっている番組は別に録画する
+			continue
+		}
+		if !candidate.Start.Equal(last.End) {
+			// 間が空いている
+			break
+		}
+		if candidate.End.Sub(pgram.Start) > config.ContinuousCaptureMax {
+			break
+		}
+		pgrams = append(pgrams, candidate)
+	}
+	return pgrams, nil
+}
+
+// pgrams をまとめて録画する
+// 空き容量の確認や録画後の処理は rec と同じ
+// 失敗すれば、録画できたところまでの番組は done にし、残りのうちまだ終わっていない番組を rec で個別に録画する
+func (r *ucRecorder) recContinuous(ctx context.Context, config recorder.Config, startAt time.Time, station repository.Station, pgrams []program.Program) {
+	// 空き容量は全体で 1 番組として見積もる
+	span := pgrams[0]
+	span.End = pgrams[len(pgrams)-1].End
+	err := r.checkDiskSpace(ctx, config, span)
+	if errors.Is(err, errutil.ErrDiskSpaceShortage) {
+		log.Ctx(ctx).Error().Msgf("refuse continuous rec because of disk space shortage (programs = %d): %+v", len(pgrams), err)
+		for _, pgram := range pgrams {
+			r.recFailed(ctx, pgram, "refuse rec because of disk space shortage: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to check disk space: %+v", err)
+	}
+
+	for i, pgram := range pgrams {
+		err := r.programPersistence.ChangeStatus(ctx, pgram, program.StatusRecording)
+		if err != nil {
+			log.Ctx(ctx).Error().Msgf("%+v", err)
+			// recording にできたところまでをまとめて録画する
+			pgrams = pgrams[:i]
+			break
+		}
+	}
+	if len(pgrams) == 0 {
+		return
+	}
+	// recording にしてから取り除くことで、RecBroadcastPrepare で再び登録されることはない
+	for _, pgram := range pgrams[1:] {
+		r.scheduler.cancel(pgram.UUID)
+	}
+	log.Ctx(ctx).Info().Msgf("continuous rec (first = %+v, programs = %d)", pgrams[0], len(pgrams))
+
+	err = r.clock.Sleep(ctx, startAt.Sub(r.clock.Now()))
+	if err != nil {
+		log.Ctx(ctx).Error().Msgf("canceled while waiting for program to start (first = %+v): %+v", pgrams[0], err)
+		for _, pgram := range pgrams {
+			err := r.programPersistence.ChangeStatus(log.Ctx(ctx).WithContext(context.Background()), pgram, program.StatusFailed)
+			if err != nil {
+				log.Ctx(ctx).Error().Msgf("%+v", err)
+			}
+		}
+		return
+	}
+
+	monitorCtx, cancelMonitor := context.WithCancel(ctx)
+	defer cancelMonitor()
+	go r.monitorDiskSpace(monitorCtx, config)
+
+	// 個別に録画しなおすものは別の録画ログに書き込む
+	logCtx, closeRecLog := r.openRecLog(ctx, config, station, pgrams...)
+	defer closeRecLog()
+
+	for i := range pgrams {
+		r.notify(logCtx, notification.EventRecStarted, &pgrams[i], "rec started (continuous %d/%d)", i+1, len(pgrams))
+		metrics.RecordingsStartedTotal.WithLabelValues(pgrams[i].Station.String(), pgrams[i].StreamType.String()).Inc()
+	}
+
+	if config.DryRun {
+		r.recDryRun(logCtx, config, station, recorder.ModeContinuous, pgrams...)
+		return
+	}
+	continuous, ok := station.(repository.ContinuousStation)
+	if !ok {
+		// recBroadcast で確認しているので、DryRun でないのに dry-run 用の Station を使わない限り起きない
+		for _, pgram := range pgrams {
+			r.recFailed(logCtx, pgram, "station does not support continuous rec")
+		}
+		return
+	}
+
+	recCtx, untrack := r.trackRecording(logCtx, pgrams...)
+	metrics.FfmpegActive.Inc()
+	recorded, err := continuous.RecContinuous(recCtx, config, pgrams)
+	metrics.FfmpegActive.Dec()
+	untrack()
+	if recorded < 0 || len(pgrams) < recorded {
+		recorded = 0
+	}
+	for _, pgram := range pgrams[:recorded] {
+		r.recSucceeded(logCtx, config, station, pgram)
+	}
+	if err == nil {
+		log.Ctx(logCtx).Info().Msgf("successfully continuous rec (programs = %d)", len(pgrams))
+		return
+	}
+
+	// まとめて録画しなおすと分割する位置がずれるので、まだ終わっていない番組を個別に録画する
+	log.Ctx(logCtx).Warn().Msgf("failed to continuous rec, fall back to rec each program (recorded = %d/%d, first = %+v): %+v", recorded, len(pgrams), pgrams[0], err)
+	now := r.clock.Now()
+	for _, pgram := range pgrams[recorded:] {
+		if !now.Before(pgram.End) {
+			log.Ctx(logCtx).Error().Msgf("program already ended (program = %+v)", pgram)
+			r.recFailed(logCtx, pgram, "continuous rec failed: %v", err)
+			continue
+		}
+		go r.rec(ctx, config, pgram.Start.Add(-config.Margin), pgram)
+	}
+}
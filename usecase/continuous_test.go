@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func newAgqrBroadcast(uuid string, title string, start time.Time, duration time.Duration) program.Program {
+	return program.Program{
+		UUID:       uuid,
+		ID:         int(start.Unix()),
+		Station:    program.StationAgqr,
+		Title:      title,
+		Start:      start,
+		End:        start.Add(duration),
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeBroadcast,
+	}
+}
+
+func Test_ucRecorder_recBroadcast_continuous(t *testing.T) {
+	start := time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST())
+	pgramA := newAgqrBroadcast("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", "鷲崎健のヨルナイト×ヨルナイト", start, 30*time.Minute)
+	pgramB := newAgqrBroadcast("0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10", "ハチャメチャ☆ライブ", start.Add(30*time.Minute), 30*time.Minute)
+	// B との間が空いている
+	pgramC := newAgqrBroadcast("5c3a1f0e-2a7e-4b59-9d0c-6f7e2b8d1a44", "あしたのラジオ", start.Add(90*time.Minute), 30*time.Minute)
+
+	configCommon := recorder.Config{
+		ArchiveDir:           "/archive",
+		Margin:               1 * time.Minute,
+		ContinuousCapture:    true,
+		ContinuousCaptureMax: 6 * time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		config     func(c *recorder.Config)
+		prepare    func(m *mock_repository.MockContinuousStation, config recorder.Config)
+		wantStatus map[string]program.Status
+	}{
+		{
+			name:   "続けて放送される番組をまとめて録画し、間が空いた番組はまとめない",
+			config: func(c *recorder.Config) {},
+			prepare: func(m *mock_repository.MockContinuousStation, config recorder.Config) {
+				m.EXPECT().RecContinuous(gomock.Any(), config, []program.Program{pgramA, pgramB}).Return(2, nil)
+				m.EXPECT().ArchiveFilePath(config, pgramA).Return("/archive/agqr/a.ts")
+				m.EXPECT().ArchiveFilePath(config, pgramB).Return("/archive/agqr/b.ts")
+			},
+			wantStatus: map[string]program.Status{
+				pgramA.UUID: program.StatusDone,
+				pgramB.UUID: program.StatusDone,
+				pgramC.UUID: program.StatusScheduled,
+			},
+		},
+		{
+			name: "上限を超えるならまとめない",
+			config: func(c *recorder.Config) {
+				c.ContinuousCaptureMax = 45 * time.Minute
+			},
+			prepare: func(m *mock_repository.MockContinuousStation, config recorder.Config) {
+				m.EXPECT().Rec(gomock.Any(), config, pgramA).Return(nil)
+				m.EXPECT().ArchiveFilePath(config, pgramA).Return("/archive/agqr/a.ts")
+			},
+			wantStatus: map[string]program.Status{
+				pgramA.UUID: program.StatusDone,
+				pgramB.UUID: program.StatusScheduled,
+			},
+		},
+		{
+			name: "無効であればまとめない",
+			config: func(c *recorder.Config) {
+				c.ContinuousCapture = false
+			},
+			prepare: func(m *mock_repository.MockContinuousStation, config recorder.Config) {
+				m.EXPECT().Rec(gomock.Any(), config, pgramA).Return(nil)
+				m.EXPECT().ArchiveFilePath(config, pgramA).Return("/archive/agqr/a.ts")
+			},
+			wantStatus: map[string]program.Status{
+				pgramA.UUID: program.StatusDone,
+				pgramB.UUID: program.StatusScheduled,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			config := configCommon
+			tt.config(&config)
+
+			programPersistence := memory.New()
+			for _, pgram := range []program.Program{pgramA, pgramB, pgramC} {
+				err := programPersistence.Save(ctx, pgram)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			mockAgqr := mock_repository.NewMockContinuousStation(ctrl)
+			tt.prepare(mockAgqr, config)
+
+			fakeClock := clock.NewFake(pgramA.Start.Add(-config.Margin))
+			r := &ucRecorder{
+				programPersistence: programPersistence,
+				agqr:               mockAgqr,
+				clock:              fakeClock,
+			}
+			r.scheduler = newRecScheduler(fakeClock, r.recBroadcast)
+
+			r.recBroadcast(ctx, config, pgramA.Start.Add(-config.Margin), pgramA)
+
+			for uuid, want := range tt.wantStatus {
+				got, err := programPersistence.LoadByUUID(ctx, uuid)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Status != want {
+					t.Errorf("status of %s = %s, want %s", got.Title, got.Status, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_ucRecorder_recBroadcast_continuousFallback(t *testing.T) {
+	start := time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST())
+	pgramA := newAgqrBroadcast("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", "鷲崎健のヨルナイト×ヨルナイト", start, 30*time.Minute)
+	pgramB := newAgqrBroadcast("0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10", "ハチャメチャ☆ライブ", start.Add(30*time.Minute), 30*time.Minute)
+	config := recorder.Config{
+		ArchiveDir:           "/archive",
+		Margin:               1 * time.Minute,
+		ContinuousCapture:    true,
+		ContinuousCaptureMax: 6 * time.Hour,
+	}
+
+	tests := []struct {
+		name string
+		// RecContinuous が分割して保存できた番組数
+		recorded    int
+		wantStatusA program.Status
+	}{
+		{
+			name:        "録画できていた A は done にし、終わっていない B だけを個別に録画しなおす",
+			recorded:    1,
+			wantStatusA: program.StatusDone,
+		},
+		{
+			name:        "A も録画できていなければ、終わっている A は failed",
+			recorded:    0,
+			wantStatusA: program.StatusFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			programPersistence := memory.New()
+			for _, pgram := range []program.Program{pgramA, pgramB} {
+				err := programPersistence.Save(ctx, pgram)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fakeClock := clock.NewFake(pgramA.Start.Add(-config.Margin))
+			mockAgqr := mock_repository.NewMockContinuousStation(ctrl)
+			// A が終わった後に失敗した
+			mockAgqr.EXPECT().
+				RecContinuous(gomock.Any(), config, []program.Program{pgramA, pgramB}).
+				DoAndReturn(func(ctx context.Context, config recorder.Config, pgrams []program.Program) (int, error) {
+					fakeClock.Set(pgramA.End.Add(5 * time.Minute))
+					return tt.recorded, errors.Wrap(errutil.ErrFfmpeg, "connection reset")
+				})
+			if tt.recorded > 0 {
+				mockAgqr.EXPECT().ArchiveFilePath(config, pgramA).Return("/archive/agqr/a.ts")
+			}
+			mockAgqr.EXPECT().Rec(gomock.Any(), config, pgramB).Return(nil)
+			mockAgqr.EXPECT().ArchiveFilePath(config, pgramB).Return("/archive/agqr/b.ts")
+
+			r := &ucRecorder{
+				programPersistence: programPersistence,
+				agqr:               mockAgqr,
+				clock:              fakeClock,
+			}
+			r.scheduler = newRecScheduler(fakeClock, r.recBroadcast)
+
+			r.recBroadcast(ctx, config, pgramA.Start.Add(-config.Margin), pgramA)
+
+			waitStatus(t, programPersistence, pgramA.UUID, tt.wantStatusA)
+			waitStatus(t, programPersistence, pgramB.UUID, program.StatusDone)
+		})
+	}
+}
+
+// goroutine で録画している番組が want になるまで待つ
+func waitStatus(t *testing.T, programPersistence repository.ProgramPersistence, uuid string, want program.Status) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := programPersistence.LoadByUUID(context.Background(), uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status of %s = %s, want %s", got.Title, got.Status, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
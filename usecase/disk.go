@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+// station 毎のおおよそのビットレート（bit/s）
+// 実測値より気持ち多めにとっておく
+var stationBitrate = map[program.Station]uint64{
+	program.StationOnsen: 1500 * 1000,
+	program.StationAgqr:  1000 * 1000,
+}
+
+// 未知の station 用
+const defaultBitrate = 1500 * 1000
+
+// ondemand は番組の長さが事前にわからないので、これくらいあるものとする
+const ondemandEstimateDuration = 60 * time.Minute
+
+// 録画に必要となるであろう容量（byte）を見積もる
+func estimateRecordingSize(config recorder.Config, pgram program.Program) uint64 {
+	var duration time.Duration
+	switch pgram.StreamType {
+	case program.StreamTypeBroadcast:
+		duration = pgram.End.Sub(pgram.Start) + 2*config.Margin
+	default:
+		duration = ondemandEstimateDuration
+	}
+	if duration < 0 {
+		duration = 0
+	}
+
+	bitrate, ok := stationBitrate[pgram.Station]
+	if !ok {
+		bitrate = defaultBitrate
+	}
+	return uint64(duration.Seconds()) * bitrate / 8
+}
+
+// 録画を開始してよいだけの空き容量があるか確認する
+// 返されるエラー
+// - errutil.ErrDiskSpaceShortage
+func (r *ucRecorder) checkDiskSpace(ctx context.Context, config recorder.Config, pgram program.Program) error {
+	if config.MinFreeSpace == 0 {
+		return nil
+	}
+
+	free, err := r.freeSpace(config.ArchiveDir)
+	if err != nil {
+		return errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+
+	need := estimateRecordingSize(config, pgram)
+	log.Ctx(ctx).Debug().Msgf("disk space (free = %d, need = %d, min free = %d)", free, need, config.MinFreeSpace)
+	if free < need || free-need < config.MinFreeSpace {
+		r.notify(ctx, notification.EventDiskLow, &pgram, "disk space is not enough to rec (free = %d, need = %d, min free = %d)", free, need, config.MinFreeSpace)
+		return errors.Wrapf(errutil.ErrDiskSpaceShortage, "free = %d, need = %d, min free = %d", free, need, config.MinFreeSpace)
+	}
+	return nil
+}
+
+// 録画中の空き容量を監視し、下回っていれば警告する
+// ctx がキャンセルされるまで続くので goroutine として呼び出すこと
+func (r *ucRecorder) monitorDiskSpace(ctx context.Context, config recorder.Config) {
+	if config.MinFreeSpace == 0 || config.DiskCheckInterval <= 0 {
+		return
+	}
+
+	ticker := r.clock.NewTicker(config.DiskCheckInterval)
+	defer ticker.Stop()
+	// 下回り続けている間に何度も通知しないように
+	low := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			free, err := r.freeSpace(config.ArchiveDir)
+			if errors.Is(err, errutil.ErrUnsupported) {
+				// 何度確認しても変わらない
+				log.Ctx(ctx).Warn().Msgf("stop monitoring disk space: %+v", err)
+				return
+			}
+			if err != nil {
+				log.Ctx(ctx).Warn().Msgf("failed to check disk space: %+v", err)
+				continue
+			}
+			if free < config.MinFreeSpace {
+				log.Ctx(ctx).Warn().Msgf("disk space is running low while recording (free = %d, min free = %d)", free, config.MinFreeSpace)
+				if !low {
+					r.notify(ctx, notification.EventDiskLow, nil, "disk space is running low while recording (free = %d, min free = %d)", free, config.MinFreeSpace)
+				}
+			}
+			low = free < config.MinFreeSpace
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+const mib = 1024 * 1024
+
+func Test_estimateRecordingSize(t *testing.T) {
+	type args struct {
+		config recorder.Config
+		pgram  program.Program
+	}
+	tests := []struct {
+		name string
+		args args
+		want uint64
+	}{
+		{
+			name: "broadcast はマージン込みの番組時間から見積もる",
+			args: args{
+				config: recorder.Config{Margin: 1 * time.Minute},
+				pgram: program.Program{
+					Station:    program.StationAgqr,
+					Start:      time.Date(2022, 8, 10, 0, 0, 0, 0, timeutil.LocationJST()),
+					End:        time.Date(2022, 8, 10, 0, 30, 0, 0, timeutil.LocationJST()),
+					StreamType: program.StreamTypeBroadcast,
+				},
+			},
+			// (30 + 2) 分 * 1000 kbps
+			want: 32 * 60 * 1000 * 1000 / 8,
+		},
+		{
+			name: "ondemand は決め打ちの時間から見積もる",
+			args: args{
+				config: recorder.Config{Margin: 1 * time.Minute},
+				pgram: program.Program{
+					Station:    program.StationOnsen,
+					StreamType: program.StreamTypeOndemand,
+				},
+			},
+			want: 60 * 60 * 1500 * 1000 / 8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateRecordingSize(tt.args.config, tt.args.pgram); got != tt.want {
+				t.Errorf("estimateRecordingSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ucRecorder_checkDiskSpace(t *testing.T) {
+	pgramBroadcast := program.Program{
+		Station:    program.StationAgqr,
+		Start:      time.Date(2022, 8, 10, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 10, 0, 30, 0, 0, timeutil.LocationJST()),
+		StreamType: program.StreamTypeBroadcast,
+	}
+
+	tests := []struct {
+		name    string
+		config  recorder.Config
+		free    uint64
+		wantErr error
+	}{
+		{
+			name:    "MinFreeSpace が 0 なら確認しない",
+			config:  recorder.Config{MinFreeSpace: 0},
+			free:    0,
+			wantErr: nil,
+		},
+		{
+			name:    "十分な空き容量があれば nil",
+			config:  recorder.Config{Margin: 1 * time.Minute, MinFreeSpace: 1024 * mib},
+			free:    10 * 1024 * mib,
+			wantErr: nil,
+		},
+		{
+			name:    "録画後に MinFreeSpace を下回る見込みであればエラー",
+			config:  recorder.Config{Margin: 1 * time.Minute, MinFreeSpace: 1024 * mib},
+			free:    1024 * mib,
+			wantErr: errutil.ErrDiskSpaceShortage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ucRecorder{
+				freeSpace: func(string) (uint64, error) { return tt.free, nil },
+			}
+			err := r.checkDiskSpace(context.Background(), tt.config, pgramBroadcast)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("ucRecorder.checkDiskSpace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ucRecorder_rec_diskSpaceShortage(t *testing.T) {
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, timeutil.LocationJST())
+	config := recorder.Config{
+		ArchiveDir:   "/archive",
+		Margin:       1 * time.Minute,
+		MinFreeSpace: 1024 * mib,
+	}
+
+	pgramOndemand := program.Program{
+		UUID:        "48e582f4-afd8-4a7b-9582-f479f94eff9e",
+		ID:          11134,
+		Station:     program.StationOnsen,
+		Title:       "セブン-イレブン presents 佐倉としたい大西",
+		Episode:     "第334回",
+		Start:       time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:      program.StatusScheduled,
+		StreamType:  program.StreamTypeOndemand,
+		PlaylistURL: "https://onsen.test/playlist.m3u8",
+	}
+	pgramBroadcast := program.Program{
+		UUID:       "b7750840-3407-44a0-b670-2b08cb8e0eb3",
+		ID:         514530,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 10, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 10, 0, 30, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeBroadcast,
+	}
+
+	tests := []struct {
+		name        string
+		targetPgram program.Program
+		prepare     func(m *mock_repository.MockProgramPersistence)
+		wantFailed  bool
+	}{
+		{
+			// status を一切変更しない
+			name:        "ondemand は録画を見送って scheduled のまま",
+			targetPgram: pgramOndemand,
+			prepare:     func(m *mock_repository.MockProgramPersistence) {},
+		},
+		{
+			name:        "broadcast は後から録画できないので failed",
+			targetPgram: pgramBroadcast,
+			prepare: func(m *mock_repository.MockProgramPersistence) {
+				m.EXPECT().
+					ChangeStatus(gomock.Any(), pgramBroadcast, program.StatusFailed).
+					Return(nil)
+			},
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+			tt.prepare(mockProgramPersistence)
+
+			notifier := make(chanNotifier, 1)
+			r := &ucRecorder{
+				programPersistence: mockProgramPersistence,
+				onsen:              mock_repository.NewMockStation(ctrl),
+				agqr:               mock_repository.NewMockStation(ctrl),
+				notifier:           notifier,
+				freeSpace:          func(string) (uint64, error) { return 512 * mib, nil },
+				clock:              clock.NewFake(now),
+			}
+			r.rec(context.Background(), config, now, tt.targetPgram)
+
+			if !tt.wantFailed {
+				return
+			}
+			select {
+			case n := <-notifier:
+				// 通知の時点で failed になっている
+				if n.Event != notification.EventRecFailed || n.Program.Status != program.StatusFailed {
+					t.Errorf("ucRecorder.rec() notification = %s (status = %s), want %s (status = %s)", n.Event, n.Program.Status, notification.EventRecFailed, program.StatusFailed)
+				}
+			case <-time.After(1 * time.Second):
+				t.Fatal("timeout waiting notification")
+			}
+		})
+	}
+}
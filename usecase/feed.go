@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/feed"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/fileutil"
+	"github.com/sobadon/anrd/internal/podcast"
+)
+
+type ucFeed struct {
+	programPersistence repository.ProgramPersistence
+	config             feed.Config
+
+	// ファイルサイズ（byte）を返す
+	fileSize func(path string) (int64, error)
+}
+
+func NewFeed(programPersistence repository.ProgramPersistence, config feed.Config) *ucFeed {
+	return &ucFeed{
+		programPersistence: programPersistence,
+		config:             config,
+		fileSize: func(path string) (int64, error) {
+			info, err := os.Stat(path)
+			if err != nil {
+				return 0, err
+			}
+			return info.Size(), nil
+		},
+	}
+}
+
+// station の録画済み番組すべてのフィード
+func (f *ucFeed) StationFeed(ctx context.Context, station program.Station) (podcast.Channel, error) {
+	pgrams, err := f.loadDone(ctx, func(pgram program.Program) bool {
+		return pgram.Station == station
+	})
+	if err != nil {
+		return podcast.Channel{}, err
+	}
+
+	ch := podcast.Channel{
+		Title:       station.String(),
+		Link:        f.config.BaseURL + stationFeedPath(station),
+		Description: station.String() + " の録画済み番組",
+	}
+	f.fillItems(&ch, pgrams)
+	return ch, nil
+}
+
+// station の title という番組のフィード
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+func (f *ucFeed) ProgramFeed(ctx context.Context, station program.Station, title string) (podcast.Channel, error) {
+	pgrams, err := f.loadDone(ctx, func(pgram program.Program) bool {
+		return pgram.Station == station && pgram.Title == title
+	})
+	if err != nil {
+		return podcast.Channel{}, err
+	}
+	if len(pgrams) == 0 {
+		return podcast.Channel{}, pkgerrors.Wrapf(errutil.ErrDatabaseNotFoundProgram, "not found program (title = %s)", title)
+	}
+
+	ch := podcast.Channel{
+		Title:       title,
+		Link:        f.config.BaseURL + programFeedPath(station, title),
+		Description: title,
+	}
+	f.fillItems(&ch, pgrams)
+	return ch, nil
+}
+
+// ArchiveDir からの相対パス（/ 区切り）が rel である録画済みファイルのパス
+// フィードの enclosure として公開しているファイルに限る
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+func (f *ucFeed) ArchiveFile(ctx context.Context, rel string) (string, error) {
+	pgrams, err := f.loadDone(ctx, func(pgram program.Program) bool {
+		archiveRel, ok := f.archiveRel(pgram)
+		return ok && archiveRel == rel
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(pgrams) == 0 {
+		return "", pkgerrors.Wrapf(errutil.ErrDatabaseNotFoundProgram, "not found archive file (path = %s)", rel)
+	}
+	return pgrams[0].FilePath, nil
+}
+
+func stationFeedPath(station program.Station) string {
+	return "/feeds/" + url.PathEscape(station.String()) + ".xml"
+}
+
+func programFeedPath(station program.Station, title string) string {
+	return "/feeds/" + url.PathEscape(station.String()) + "/" + url.PathEscape(title) + ".xml"
+}
+
+// done な番組のうち filter を満たすものを新しい順に返す
+func (f *ucFeed) loadDone(ctx context.Context, filter func(pgram program.Program) bool) ([]program.Program, error) {
+	pgrams, err := f.programPersistence.LoadDone(ctx)
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []program.Program
+	for _, pgram := range pgrams {
+		if filter(pgram) {
+			filtered = append(filtered, pgram)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Start.After(filtered[j].Start)
+	})
+	return filtered, nil
+}
+
+func (f *ucFeed) fillItems(ch *podcast.Channel, pgrams []program.Program) {
+	for _, pgram := range pgrams {
+		enclosureURL, ok := f.enclosureURL(pgram)
+		if !ok {
+			continue
+		}
+
+		title := pgram.Title
+		if pgram.Episode != "" {
+			title = pgram.Title + " " + pgram.Episode
+		}
+
+		var length int64
+		if pgram.FilePath != "" {
+			// アップロード後に削除されていることもあるので、取得できなくても気にしない
+			length, _ = f.fileSize(pgram.FilePath)
+		}
+		// 拡張子はローカルのファイルとオブジェクトで変わらない
+		typePath := pgram.FilePath
+		if typePath == "" {
+			typePath = pgram.ObjectKey
+		}
+
+		it := podcast.Item{
+			GUID:            pgram.UUID,
+			Title:           title,
+			PubDate:         pgram.Start,
+			ImageURL:        pgram.ImageURL,
+			EnclosureURL:    enclosureURL,
+			EnclosureLength: length,
+			EnclosureType:   fileutil.ContentType(typePath),
+		}
+		if pgram.StreamType == program.StreamTypeBroadcast {
+			it.Duration = pgram.End.Sub(pgram.Start)
+		}
+		ch.Items = append(ch.Items, it)
+
+		if ch.ImageURL == "" {
+			ch.ImageURL = pgram.ImageURL
+		}
+	}
+}
+
+func (f *ucFeed) enclosureURL(pgram program.Program) (string, bool) {
+	if pgram.ObjectKey != "" && f.config.ObjectBaseURL != "" {
+		return strings.TrimSuffix(f.config.ObjectBaseURL, "/") + "/" + escapePath(pgram.ObjectKey), true
+	}
+	rel, ok := f.archiveRel(pgram)
+	if !ok {
+		return "", false
+	}
+	return f.config.BaseURL + "/archive/" + escapePath(rel), true
+}
+
+// 録画済みファイルの ArchiveDir からの相対パス（/ 区切り）
+// ArchiveDir の外にあれば false
+func (f *ucFeed) archiveRel(pgram program.Program) (string, bool) {
+	if pgram.FilePath == "" {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(f.config.ArchiveDir, pgram.FilePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// / 区切りの各要素を escape する
+func escapePath(p string) string {
+	elems := strings.Split(p, "/")
+	for i, elem := range elems {
+		elems[i] = url.PathEscape(elem)
+	}
+	return strings.Join(elems, "/")
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/feed"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/podcast"
+	"github.com/sobadon/anrd/internal/testutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func Test_ucFeed_ProgramFeed(t *testing.T) {
+	config := feed.Config{
+		ArchiveDir:    "/archive",
+		BaseURL:       "http://anrd.test",
+		ObjectBaseURL: "https://minio.test/archive",
+	}
+
+	pgram333 := program.Program{
+		UUID:       "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+		ID:         11133,
+		Station:    program.StationOnsen,
+		Title:      "セブン-イレブン presents 佐倉としたい大西",
+		Episode:    "第333回",
+		Start:      time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusDone,
+		StreamType: program.StreamTypeOndemand,
+		FilePath:   "/archive/onsen/title/333 回.ts",
+	}
+	pgram334 := program.Program{
+		UUID:       "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+		ID:         11134,
+		Station:    program.StationOnsen,
+		Title:      "セブン-イレブン presents 佐倉としたい大西",
+		Episode:    "第334回",
+		Start:      time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusDone,
+		StreamType: program.StreamTypeOndemand,
+		ImageURL:   "https://onsen.test/image.png",
+		FilePath:   "/archive/onsen/title/334.ts",
+		ObjectKey:  "onsen/334.ts",
+	}
+	pgramOther := program.Program{
+		UUID:    "b7750840-3407-44a0-b670-2b08cb8e0eb3",
+		Station: program.StationAgqr,
+		Title:   "鷲崎健のヨルナイト×ヨルナイト",
+		Status:  program.StatusDone,
+	}
+
+	tests := []struct {
+		name    string
+		title   string
+		want    podcast.Channel
+		wantErr error
+	}{
+		{
+			name:  "新しい順に enclosure が並ぶ",
+			title: "セブン-イレブン presents 佐倉としたい大西",
+			want: podcast.Channel{
+				Title:       "セブン-イレブン presents 佐倉としたい大西",
+				Link:        "http://anrd.test/feeds/onsen/%E3%82%BB%E3%83%96%E3%83%B3-%E3%82%A4%E3%83%AC%E3%83%96%E3%83%B3%20presents%20%E4%BD%90%E5%80%89%E3%81%A8%E3%81%97%E3%81%9F%E3%81%84%E5%A4%A7%E8%A5%BF.xml",
+				Description: "セブン-イレブン presents 佐倉としたい大西",
+				ImageURL:    "https://onsen.test/image.png",
+				Items: []podcast.Item{
+					{
+						GUID:            "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+						Title:           "セブン-イレブン presents 佐倉としたい大西 第334回",
+						PubDate:         time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+						ImageURL:        "https://onsen.test/image.png",
+						EnclosureURL:    "https://minio.test/archive/onsen/334.ts",
+						EnclosureLength: 100,
+						EnclosureType:   "video/mp2t",
+					},
+					{
+						GUID:            "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+						Title:           "セブン-イレブン presents 佐倉としたい大西 第333回",
+						PubDate:         time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+						EnclosureURL:    "http://anrd.test/archive/onsen/title/333%20%E5%9B%9E.ts",
+						EnclosureLength: 100,
+						EnclosureType:   "video/mp2t",
+					},
+				},
+			},
+		},
+		{
+			name:    "該当する番組がなければ ErrDatabaseNotFoundProgram",
+			title:   "存在しない番組",
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+			mockProgramPersistence.EXPECT().
+				LoadDone(gomock.Any()).
+				Return([]program.Program{pgram333, pgram334, pgramOther}, nil)
+
+			f := &ucFeed{
+				programPersistence: mockProgramPersistence,
+				config:             config,
+				fileSize:           func(string) (int64, error) { return 100, nil },
+			}
+			got, err := f.ProgramFeed(context.Background(), program.StationOnsen, tt.title)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("ucFeed.ProgramFeed() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ucFeed.ProgramFeed() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ucFeed_ArchiveFile(t *testing.T) {
+	config := feed.Config{
+		ArchiveDir: "/archive",
+		BaseURL:    "http://anrd.test",
+	}
+	pgramDone := program.Program{
+		UUID:     "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+		Station:  program.StationOnsen,
+		Title:    "セブン-イレブン presents 佐倉としたい大西",
+		Status:   program.StatusDone,
+		FilePath: "/archive/onsen/title/333 回.ts",
+	}
+	pgramOutside := program.Program{
+		UUID:     "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+		Station:  program.StationOnsen,
+		Title:    "セブン-イレブン presents 佐倉としたい大西",
+		Status:   program.StatusDone,
+		FilePath: "/tmp/334.ts",
+	}
+
+	tests := []struct {
+		name    string
+		rel     string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "done な番組の録画済みファイル",
+			rel:  "onsen/title/333 回.ts",
+			want: "/archive/onsen/title/333 回.ts",
+		},
+		{
+			name:    "録画済みファイル以外は ErrDatabaseNotFoundProgram",
+			rel:     "onsen/title/333 回.log",
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+		{
+			name:    "ディレクトリは ErrDatabaseNotFoundProgram",
+			rel:     "onsen/title",
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+		{
+			name:    "ArchiveDir の外にあるファイルは ErrDatabaseNotFoundProgram",
+			rel:     "../tmp/334.ts",
+			wantErr: errutil.ErrDatabaseNotFoundProgram,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+			mockProgramPersistence.EXPECT().
+				LoadDone(gomock.Any()).
+				Return([]program.Program{pgramDone, pgramOutside}, nil)
+
+			f := &ucFeed{
+				programPersistence: mockProgramPersistence,
+				config:             config,
+			}
+			got, err := f.ArchiveFile(context.Background(), tt.rel)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("ucFeed.ArchiveFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ucFeed.ArchiveFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
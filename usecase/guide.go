@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/xmltv"
+)
+
+// 保存している番組表を XMLTV として他のツールに渡す
+type ucGuide struct {
+	programPersistence repository.ProgramPersistence
+	clock              clock.Clock
+
+	// 今よりこれだけ前に始まった番組まで含める
+	past time.Duration
+}
+
+func NewGuide(programPersistence repository.ProgramPersistence, clk clock.Clock, past time.Duration) *ucGuide {
+	return &ucGuide{
+		programPersistence: programPersistence,
+		clock:              clk,
+		past:               past,
+	}
+}
+
+// station 毎の channel と、その番組
+// station が空であればすべての station
+// 録画するか否かによらず、番組表にあるものはすべて含める（手動で deleted にしたものは除く）
+// ondemand な番組は配信日の 0 時に始まり、終わりのないものとする
+func (g *ucGuide) Guide(ctx context.Context, station program.Station) (xmltv.Guide, error) {
+	stations := []program.Station{program.StationAgqr, program.StationOnsen}
+	if station != "" {
+		stations = []program.Station{station}
+	}
+
+	var guide xmltv.Guide
+	for _, s := range stations {
+		guide.Channels = append(guide.Channels, xmltv.Channel{
+			ID:          guideChannelID(s),
+			DisplayName: s.DisplayName(),
+		})
+	}
+
+	pgrams, err := g.programPersistence.Load(ctx, program.Filter{
+		Station:   station,
+		StartFrom: g.clock.Now().Add(-g.past),
+	})
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		return guide, nil
+	}
+	if err != nil {
+		return xmltv.Guide{}, err
+	}
+	for _, pgram := range pgrams {
+		if pgram.Status == program.StatusDeleted {
+			continue
+		}
+		guide.Programmes = append(guide.Programmes, xmltv.Programme{
+			Channel:     guideChannelID(pgram.Station),
+			Start:       pgram.Start,
+			Stop:        pgram.End,
+			Title:       pgram.Title,
+			SubTitle:    pgram.Episode,
+			Description: pgram.Description,
+			Presenters:  pgram.Personalities,
+			IconURL:     pgram.ImageURL,
+		})
+	}
+	return guide, nil
+}
+
+// XMLTV で推奨されている、ドメイン名のような形式
+func guideChannelID(station program.Station) string {
+	return station.String() + ".anrd"
+}
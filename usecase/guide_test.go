@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/timeutil"
+	"github.com/sobadon/anrd/internal/xmltv"
+)
+
+func Test_ucGuide_Guide(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, timeutil.LocationJST())
+
+	yorunight := program.Program{
+		UUID:          "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		ID:            514569,
+		Station:       program.StationAgqr,
+		Title:         "鷲崎健のヨルナイト×ヨルナイト",
+		Start:         time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:           time.Date(2022, 8, 18, 1, 0, 0, 0, timeutil.LocationJST()),
+		Status:        program.StatusSkipped,
+		StreamType:    program.StreamTypeBroadcast,
+		Description:   "鷲崎健による生放送。",
+		Personalities: []string{"鷲崎健", "青木佑磨"},
+	}
+	// past を過ぎているので含めない
+	old := program.Program{
+		UUID:       "8a1d0d55-4f0f-4f8e-9d4b-3c9c4d0f3a11",
+		ID:         514000,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 16, 1, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusDone,
+		StreamType: program.StreamTypeBroadcast,
+	}
+	ondemand := program.Program{
+		UUID:          "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+		ID:            11134,
+		Station:       program.StationOnsen,
+		Title:         "セブン-イレブン presents 佐倉としたい大西",
+		Episode:       "第334回",
+		Start:         time.Date(2022, 8, 17, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:        program.StatusDone,
+		StreamType:    program.StreamTypeOndemand,
+		ImageURL:      "https://onsen.test/image.jpg",
+		Personalities: []string{"佐倉綾音", "大西沙織"},
+	}
+
+	programPersistence := memory.New()
+	for _, pgram := range []program.Program{yorunight, old, ondemand} {
+		err := programPersistence.Save(ctx, pgram)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	yorunightProgramme := xmltv.Programme{
+		Channel:     "agqr.anrd",
+		Start:       yorunight.Start,
+		Stop:        yorunight.End,
+		Title:       "鷲崎健のヨルナイト×ヨルナイト",
+		Description: "鷲崎健による生放送。",
+		Presenters:  []string{"鷲崎健", "青木佑磨"},
+	}
+	tests := []struct {
+		name    string
+		station program.Station
+		want    xmltv.Guide
+	}{
+		{
+			name: "すべての station",
+			want: xmltv.Guide{
+				Channels: []xmltv.Channel{
+					{ID: "agqr.anrd", DisplayName: "超！A&G+"},
+					{ID: "onsen.anrd", DisplayName: "インターネットラジオステーション＜音泉＞"},
+				},
+				Programmes: []xmltv.Programme{
+					{
+						Channel:    "onsen.anrd",
+						Start:      ondemand.Start,
+						Title:      "セブン-イレブン presents 佐倉としたい大西",
+						SubTitle:   "第334回",
+						Presenters: []string{"佐倉綾音", "大西沙織"},
+						IconURL:    "https://onsen.test/image.jpg",
+					},
+					yorunightProgramme,
+				},
+			},
+		},
+		{
+			name:    "station を指定",
+			station: program.StationAgqr,
+			want: xmltv.Guide{
+				Channels: []xmltv.Channel{
+					{ID: "agqr.anrd", DisplayName: "超！A&G+"},
+				},
+				Programmes: []xmltv.Programme{yorunightProgramme},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGuide(programPersistence, clock.NewFake(now), 1*24*time.Hour)
+			got, err := g.Guide(ctx, tt.station)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Guide() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/sobadon/anrd/domain/model/health"
+	"github.com/sobadon/anrd/domain/repository"
+)
+
+type lastUpdatedAtGetter interface {
+	LastUpdatedAt() time.Time
+}
+
+type runningChecker interface {
+	IsRunning() bool
+}
+
+type ucHealth struct {
+	programPersistence repository.ProgramPersistence
+	recorder           lastUpdatedAtGetter
+	scheduler          runningChecker
+
+	// 最後に番組表の更新に成功してからこれ以上経過していれば ready ではない
+	updateThreshold time.Duration
+
+	lookPath func(file string) (string, error)
+}
+
+func NewHealth(
+	programPersistence repository.ProgramPersistence,
+	recorder lastUpdatedAtGetter,
+	scheduler runningChecker,
+	updateThreshold time.Duration,
+) *ucHealth {
+	return &ucHealth{
+		programPersistence: programPersistence,
+		recorder:           recorder,
+		scheduler:          scheduler,
+		updateThreshold:    updateThreshold,
+		lookPath:           exec.LookPath,
+	}
+}
+
+// プロセスが生きていて、データベースに接続できるか
+func (h *ucHealth) Liveness(ctx context.Context) health.Report {
+	return health.NewReport([]health.Check{
+		h.checkDatabase(ctx),
+	})
+}
+
+// 録画を行える状態であるか
+func (h *ucHealth) Readiness(ctx context.Context, now time.Time) health.Report {
+	return health.NewReport([]health.Check{
+		h.checkDatabase(ctx),
+		h.checkSchema(ctx),
+		h.checkScheduler(),
+		h.checkLastUpdate(now),
+		h.checkFfmpeg(),
+	})
+}
+
+func (h *ucHealth) checkDatabase(ctx context.Context) health.Check {
+	check := health.Check{Name: "database", Status: health.StatusOK}
+	err := h.programPersistence.Ping(ctx)
+	if err != nil {
+		check.Status = health.StatusFail
+		check.Message = err.Error()
+	}
+	return check
+}
+
+func (h *ucHealth) checkSchema(ctx context.Context) health.Check {
+	check := health.Check{Name: "schema", Status: health.StatusOK}
+	err := h.programPersistence.CheckSchema(ctx)
+	if err != nil {
+		check.Status = health.StatusFail
+		check.Message = err.Error()
+	}
+	return check
+}
+
+func (h *ucHealth) checkScheduler() health.Check {
+	check := health.Check{Name: "scheduler", Status: health.StatusOK}
+	if !h.scheduler.IsRunning() {
+		check.Status = health.StatusFail
+		check.Message = "scheduler is not running"
+	}
+	return check
+}
+
+func (h *ucHealth) checkLastUpdate(now time.Time) health.Check {
+	check := health.Check{Name: "update_program", Status: health.StatusOK}
+	lastUpdatedAt := h.recorder.LastUpdatedAt()
+	if lastUpdatedAt.IsZero() {
+		check.Status = health.StatusFail
+		check.Message = "program has never been updated successfully"
+		return check
+	}
+
+	elapsed := now.Sub(lastUpdatedAt)
+	check.Message = fmt.Sprintf("last updated at %s", lastUpdatedAt.Format(time.RFC3339))
+	if elapsed > h.updateThreshold {
+		check.Status = health.StatusFail
+		check.Message = fmt.Sprintf("last updated at %s (%s ago, threshold = %s)", lastUpdatedAt.Format(time.RFC3339), elapsed.Round(time.Second), h.updateThreshold)
+	}
+	return check
+}
+
+func (h *ucHealth) checkFfmpeg() health.Check {
+	check := health.Check{Name: "ffmpeg", Status: health.StatusOK}
+	path, err := h.lookPath("ffmpeg")
+	if err != nil {
+		check.Status = health.StatusFail
+		check.Message = err.Error()
+		return check
+	}
+	check.Message = path
+	return check
+}
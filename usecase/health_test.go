@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/health"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+type fakeLastUpdatedAt time.Time
+
+func (f fakeLastUpdatedAt) LastUpdatedAt() time.Time {
+	return time.Time(f)
+}
+
+type fakeRunning bool
+
+func (f fakeRunning) IsRunning() bool {
+	return bool(f)
+}
+
+func Test_ucHealth_Readiness(t *testing.T) {
+	now := time.Date(2022, 8, 10, 0, 0, 0, 0, timeutil.LocationJST())
+
+	tests := []struct {
+		name          string
+		schemaErr     error
+		running       bool
+		lastUpdatedAt time.Time
+		lookPathErr   error
+		want          health.Report
+	}{
+		{
+			name:          "すべて問題なければ ok",
+			running:       true,
+			lastUpdatedAt: now.Add(-10 * time.Minute),
+			want: health.Report{
+				Status: health.StatusOK,
+				Checks: []health.Check{
+					{Name: "database", Status: health.StatusOK},
+					{Name: "schema", Status: health.StatusOK},
+					{Name: "scheduler", Status: health.StatusOK},
+					{Name: "update_program", Status: health.StatusOK, Message: "last updated at 2022-08-09T23:50:00+09:00"},
+					{Name: "ffmpeg", Status: health.StatusOK, Message: "/usr/bin/ffmpeg"},
+				},
+			},
+		},
+		{
+			name:          "失敗したものを理由とともに返す",
+			schemaErr:     errors.New("table programs does not exist"),
+			running:       false,
+			lastUpdatedAt: now.Add(-2 * time.Hour),
+			lookPathErr:   errors.New(`exec: "ffmpeg": executable file not found in $PATH`),
+			want: health.Report{
+				Status: health.StatusFail,
+				Checks: []health.Check{
+					{Name: "database", Status: health.StatusOK},
+					{Name: "schema", Status: health.StatusFail, Message: "table programs does not exist"},
+					{Name: "scheduler", Status: health.StatusFail, Message: "scheduler is not running"},
+					{Name: "update_program", Status: health.StatusFail, Message: "last updated at 2022-08-09T22:00:00+09:00 (2h0m0s ago, threshold = 1h0m0s)"},
+					{Name: "ffmpeg", Status: health.StatusFail, Message: `exec: "ffmpeg": executable file not found in $PATH`},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+			mockProgramPersistence.EXPECT().Ping(gomock.Any()).Return(nil)
+			mockProgramPersistence.EXPECT().CheckSchema(gomock.Any()).Return(tt.schemaErr)
+
+			h := &ucHealth{
+				programPersistence: mockProgramPersistence,
+				recorder:           fakeLastUpdatedAt(tt.lastUpdatedAt),
+				scheduler:          fakeRunning(tt.running),
+				updateThreshold:    1 * time.Hour,
+				lookPath: func(string) (string, error) {
+					if tt.lookPathErr != nil {
+						return "", tt.lookPathErr
+					}
+					return "/usr/bin/ffmpeg", nil
+				},
+			}
+			got := h.Readiness(context.Background(), now)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ucHealth.Readiness() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
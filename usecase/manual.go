@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/date"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+// 番組表から ondemand な番組を探す
+// title は部分一致で、複数の番組に該当すればエラー
+// episode が空であれば最新のもの、そうでなければ部分一致するもの
+// 返されるエラー
+// - errutil.ErrGuideNotFoundProgram
+func (r *ucRecorder) FindOndemand(ctx context.Context, station program.Station, title string, episode string) (program.Program, error) {
+	s := r.station(station)
+	if s == nil {
+		return program.Program{}, pkgerrors.Wrapf(errutil.ErrGuideNotFoundProgram, "unknown station %q", station)
+	}
+
+	pgrams, err := s.GetPrograms(ctx, date.Date{})
+	if err != nil {
+		return program.Program{}, err
+	}
+
+	var candidates []program.Program
+	titles := map[string]struct{}{}
+	for _, pgram := range pgrams {
+		if pgram.StreamType != program.StreamTypeOndemand || !strings.Contains(pgram.Title, title) {
+			continue
+		}
+		titles[pgram.Title] = struct{}{}
+		if episode != "" && !strings.Contains(pgram.Episode, episode) {
+			continue
+		}
+		candidates = append(candidates, pgram)
+	}
+
+	if len(titles) > 1 {
+		var matched []string
+		for t := range titles {
+			matched = append(matched, t)
+		}
+		sort.Strings(matched)
+		return program.Program{}, pkgerrors.Wrapf(errutil.ErrGuideNotFoundProgram, "title %q matches multiple programs: %s", title, strings.Join(matched, ", "))
+	}
+	if len(candidates) == 0 {
+		return program.Program{}, pkgerrors.Wrapf(errutil.ErrGuideNotFoundProgram, "title = %q, episode = %q", title, episode)
+	}
+	if episode != "" && len(candidates) > 1 {
+		return program.Program{}, pkgerrors.Wrapf(errutil.ErrGuideNotFoundProgram, "episode %q matches %d episodes", episode, len(candidates))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Start.After(candidates[j].Start)
+	})
+	return candidates[0], nil
+}
+
+// スケジューラーを介さずに 1 番組を録画する
+// データベースに存在しない番組であれば登録してから、定期実行と同じ rec を同期的に呼び出す
+// broadcast な番組は Start - Margin まで待つ
+// scheduled, failed, skipped な番組のみ
+// 返されるエラー
+// - errutil.ErrInvalidStatus
+// - errutil.ErrRecFailed
+func (r *ucRecorder) RecManual(ctx context.Context, config recorder.Config, pgram program.Program) (program.Program, error) {
+	stored, err := r.storeManual(ctx, pgram)
+	if err != nil {
+		return program.Program{}, err
+	}
+
+	switch stored.Status {
+	case program.StatusScheduled, program.StatusFailed, program.StatusSkipped:
+	default:
+		return stored, pkgerrors.Wrapf(errutil.ErrInvalidStatus, "cannot rec %s program (uuid = %s)", stored.Status, stored.UUID)
+	}
+
+	r.rec(ctx, config, stored.Start.Add(-config.Margin), stored)
+
+	result, err := r.programPersistence.LoadByUUID(ctx, stored.UUID)
+	if err != nil {
+		return program.Program{}, err
+	}
+	if result.Status != program.StatusDone {
+		return result, pkgerrors.Wrapf(errutil.ErrRecFailed, "status = %s", result.Status)
+	}
+	return result, nil
+}
+
+// pgram をデータベースに登録し、登録されている番組を返す
+// 既に同じ番組が登録されていれば、登録済みの方を返す
+func (r *ucRecorder) storeManual(ctx context.Context, pgram program.Program) (program.Program, error) {
+	stored, err := r.programPersistence.LoadByUUID(ctx, pgram.UUID)
+	if err == nil {
+		return stored, nil
+	}
+	if !errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		return program.Program{}, err
+	}
+
+	// Save は同じ ID の番組が既にあれば何もしないので、登録済みのものを探しなおす
+	err = r.programPersistence.Save(ctx, pgram)
+	if err != nil {
+		return program.Program{}, err
+	}
+	pgrams, err := r.programPersistence.Load(ctx, program.Filter{Station: pgram.Station, Title: pgram.Title})
+	if err != nil {
+		return program.Program{}, err
+	}
+	for _, p := range pgrams {
+		if p.ID == pgram.ID {
+			return p, nil
+		}
+	}
+	return program.Program{}, pkgerrors.Wrapf(errutil.ErrDatabaseNotFoundProgram, "not found saved program (id = %d)", pgram.ID)
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func Test_ucRecorder_FindOndemand(t *testing.T) {
+	ondemand := func(id int, title string, episode string, day int) program.Program {
+		return program.Program{
+			ID:         id,
+			Station:    program.StationOnsen,
+			Title:      title,
+			Episode:    episode,
+			Start:      time.Date(2022, 8, day, 0, 0, 0, 0, timeutil.LocationJST()),
+			Status:     program.StatusScheduled,
+			StreamType: program.StreamTypeOndemand,
+		}
+	}
+	guide := []program.Program{
+		ondemand(11054, "セブン-イレブン presents 佐倉としたい大西", "第333回", 16),
+		ondemand(11134, "セブン-イレブン presents 佐倉としたい大西", "第334回", 23),
+		ondemand(11200, "鷲崎健・青木瑠璃子のぷちゃへんざ！", "第100回", 20),
+		ondemand(11201, "鷲崎健のぷちゃへんざ！ 特別編", "第1回", 21),
+	}
+
+	tests := []struct {
+		name    string
+		title   string
+		episode string
+		want    program.Program
+		wantErr error
+	}{
+		{
+			name:    "episode が空であれば最新のもの",
+			title:   "佐倉としたい大西",
+			want:    guide[1],
+			wantErr: nil,
+		},
+		{
+			name:    "episode を指定すればそれ",
+			title:   "佐倉としたい大西",
+			episode: "第333回",
+			want:    guide[0],
+			wantErr: nil,
+		},
+		{
+			name:    "title が複数の番組に該当すれば ErrGuideNotFoundProgram",
+			title:   "ぷちゃへんざ",
+			wantErr: errutil.ErrGuideNotFoundProgram,
+		},
+		{
+			name:    "該当する番組がなければ ErrGuideNotFoundProgram",
+			title:   "佐倉としたい大西",
+			episode: "第999回",
+			wantErr: errutil.ErrGuideNotFoundProgram,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOnsen := mock_repository.NewMockStation(ctrl)
+			mockOnsen.EXPECT().GetPrograms(gomock.Any(), gomock.Any()).Return(guide, nil)
+
+			r := &ucRecorder{onsen: mockOnsen}
+			got, err := r.FindOndemand(context.Background(), program.StationOnsen, tt.title, tt.episode)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("ucRecorder.FindOndemand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ucRecorder.FindOndemand() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ucRecorder_RecManual(t *testing.T) {
+	now := time.Date(2022, 8, 10, 23, 0, 0, 0, timeutil.LocationJST())
+	config := recorder.Config{ArchiveDir: "/archive", RetryMaxCount: 0}
+
+	// 番組表にはない、手動で録画する broadcast
+	manual := program.NewProgramManualBroadcast(program.StationAgqr, "manual", now, now.Add(30*time.Minute))
+	stored := manual
+	stored.UUID = "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a"
+	done := stored
+	done.Status = program.StatusDone
+	done.FilePath = "/archive/agqr/manual.ts"
+	failed := stored
+	failed.Status = program.StatusFailed
+
+	tests := []struct {
+		name    string
+		recErr  error
+		want    program.Program
+		wantErr error
+	}{
+		{
+			name:    "登録してから録画し、録画後の番組を返す",
+			recErr:  nil,
+			want:    done,
+			wantErr: nil,
+		},
+		{
+			name:    "録画に失敗すれば ErrRecFailed",
+			recErr:  errors.Wrap(errutil.ErrFfmpeg, "something error"),
+			want:    failed,
+			wantErr: errutil.ErrRecFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+			mockAgqr := mock_repository.NewMockStation(ctrl)
+
+			mockProgramPersistence.EXPECT().LoadByUUID(gomock.Any(), manual.UUID).Return(program.Program{}, errutil.ErrDatabaseNotFoundProgram)
+			mockProgramPersistence.EXPECT().Save(gomock.Any(), manual).Return(nil)
+			mockProgramPersistence.EXPECT().Load(gomock.Any(), program.Filter{Station: program.StationAgqr, Title: "manual"}).Return([]program.Program{stored}, nil)
+			mockProgramPersistence.EXPECT().ChangeStatus(gomock.Any(), stored, program.StatusRecording).Return(nil)
+			mockAgqr.EXPECT().Rec(gomock.Any(), config, stored).Return(tt.recErr)
+			if tt.recErr == nil {
+				mockAgqr.EXPECT().ArchiveFilePath(config, stored).Return(done.FilePath)
+				mockProgramPersistence.EXPECT().ChangeFilePath(gomock.Any(), stored, done.FilePath).Return(nil)
+				mockProgramPersistence.EXPECT().ChangeStatus(gomock.Any(), stored, program.StatusDone).Return(nil)
+			} else {
+				mockProgramPersistence.EXPECT().ChangeStatus(gomock.Any(), stored, program.StatusFailed).Return(nil)
+			}
+			mockProgramPersistence.EXPECT().LoadByUUID(gomock.Any(), stored.UUID).Return(tt.want, nil)
+
+			r := &ucRecorder{
+				programPersistence: mockProgramPersistence,
+				agqr:               mockAgqr,
+				clock:              clock.NewFake(now),
+			}
+			got, err := r.RecManual(context.Background(), config, manual)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("ucRecorder.RecManual() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ucRecorder.RecManual() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+// イベントを通知する
+// 通知先が設定されていなければ何もしない
+// 通知の送信（リトライ含む）で録画処理を待たせないよう goroutine で送る
+func (r *ucRecorder) notify(ctx context.Context, event notification.Event, pgram *program.Program, format string, args ...interface{}) {
+	if r.notifier == nil {
+		return
+	}
+
+	n := notification.Notification{
+		Event:   event,
+		Time:    r.clock.Now().In(timeutil.LocationJST()),
+		Program: pgram,
+		Message: fmt.Sprintf(format, args...),
+	}
+	// 呼び出し元の ctx がキャンセルされても送りきれるように logger だけ引き継ぐ
+	notifyCtx := log.Ctx(ctx).WithContext(context.Background())
+	go func() {
+		err := r.notifier.Notify(notifyCtx, n)
+		if err != nil {
+			log.Ctx(notifyCtx).Error().Msgf("failed to notify (event = %s): %+v", event, err)
+		}
+	}()
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/notification"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+// 受け取った通知を channel に流す
+type chanNotifier chan notification.Notification
+
+func (c chanNotifier) Notify(ctx context.Context, n notification.Notification) error {
+	c <- n
+	return nil
+}
+
+func Test_ucRecorder_rec_notify(t *testing.T) {
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, timeutil.LocationJST())
+	config := recorder.Config{ArchiveDir: "/archive", RetryMaxCount: 3}
+	pgramOndemand := program.Program{
+		UUID:        "48e582f4-afd8-4a7b-9582-f479f94eff9e",
+		ID:          11134,
+		Station:     program.StationOnsen,
+		Title:       "セブン-イレブン presents 佐倉としたい大西",
+		Episode:     "第334回",
+		Start:       time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:      program.StatusScheduled,
+		StreamType:  program.StreamTypeOndemand,
+		PlaylistURL: "https://onsen.test/playlist.m3u8",
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+	mockOnsen := mock_repository.NewMockStation(ctrl)
+	mockProgramPersistence.EXPECT().
+		ChangeStatus(gomock.Any(), pgramOndemand, program.StatusRecording).
+		Return(nil)
+	mockOnsen.EXPECT().
+		Rec(gomock.Any(), config, pgramOndemand).
+		Return(errors.Wrap(errutil.ErrFfmpeg, "something error")).
+		Times(4)
+	mockProgramPersistence.EXPECT().
+		ChangeStatus(gomock.Any(), pgramOndemand, program.StatusFailed).
+		Return(nil)
+
+	notifier := make(chanNotifier, 10)
+	r := &ucRecorder{
+		programPersistence: mockProgramPersistence,
+		onsen:              mockOnsen,
+		notifier:           notifier,
+		clock:              clock.NewFake(now),
+	}
+	r.rec(context.Background(), config, now, pgramOndemand)
+
+	var got []notification.Event
+	for i := 0; i < 2; i++ {
+		select {
+		case n := <-notifier:
+			got = append(got, n.Event)
+			// 通知の時点で failed になっている
+			if n.Event == notification.EventRecFailed && n.Program.Status != program.StatusFailed {
+				t.Errorf("ucRecorder.rec() rec_failed program status = %s, want %s", n.Program.Status, program.StatusFailed)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatal("timeout waiting notification")
+		}
+	}
+	// goroutine で送られるので順不同
+	sortEvents := cmpopts.SortSlices(func(a, b notification.Event) bool { return a < b })
+	if diff := cmp.Diff([]notification.Event{notification.EventRecStarted, notification.EventRecFailed}, got, sortEvents); diff != "" {
+		t.Errorf("ucRecorder.rec() notification mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+// 番組データベースを人が確認・編集するためのもの
+type ucPrograms struct {
+	programPersistence repository.ProgramPersistence
+}
+
+func NewPrograms(programPersistence repository.ProgramPersistence) *ucPrograms {
+	return &ucPrograms{
+		programPersistence: programPersistence,
+	}
+}
+
+// 該当する番組がなければ空
+// deleted な番組は filter.Status に指定したときのみ含める
+func (p *ucPrograms) List(ctx context.Context, filter program.Filter) ([]program.Program, error) {
+	pgrams, err := p.programPersistence.Load(ctx, filter)
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if filter.Status == program.StatusDeleted {
+		return pgrams, nil
+	}
+
+	var listed []program.Program
+	for _, pgram := range pgrams {
+		if pgram.Status != program.StatusDeleted {
+			listed = append(listed, pgram)
+		}
+	}
+	return listed, nil
+}
+
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+func (p *ucPrograms) Show(ctx context.Context, uuid string) (program.Program, error) {
+	return p.programPersistence.LoadByUUID(ctx, uuid)
+}
+
+// 録画ログを古い順に返す
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+func (p *ucPrograms) RecordingLogs(ctx context.Context, uuid string) ([]recorder.RecordingLog, error) {
+	_, err := p.programPersistence.LoadByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return p.programPersistence.LoadRecordingLogs(ctx, uuid)
+}
+
+// status を強制的に変更する
+// recording のまま残ってしまった番組を戻すなど、遷移の妥当性は確認しない
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+// - errutil.ErrInvalidStatus
+func (p *ucPrograms) SetStatus(ctx context.Context, uuid string, status program.Status) (program.Program, error) {
+	if !status.Valid() {
+		return program.Program{}, pkgerrors.Wrapf(errutil.ErrInvalidStatus, "unknown status %q", status)
+	}
+	pgram, err := p.programPersistence.LoadByUUID(ctx, uuid)
+	if err != nil {
+		return program.Program{}, err
+	}
+	return p.changeStatus(ctx, pgram, status)
+}
+
+// 録画対象から外す
+// scheduled か failed な番組のみ
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+// - errutil.ErrInvalidStatus
+func (p *ucPrograms) Skip(ctx context.Context, uuid string) (program.Program, error) {
+	pgram, err := p.programPersistence.LoadByUUID(ctx, uuid)
+	if err != nil {
+		return program.Program{}, err
+	}
+	if pgram.Status != program.StatusScheduled && pgram.Status != program.StatusFailed {
+		return program.Program{}, pkgerrors.Wrapf(errutil.ErrInvalidStatus, "cannot skip %s program", pgram.Status)
+	}
+	return p.changeStatus(ctx, pgram, program.StatusSkipped)
+}
+
+// もう一度録画対象にする
+// recording, scheduled な番組と、既に始まった broadcast な番組は対象外
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+// - errutil.ErrInvalidStatus
+func (p *ucPrograms) Requeue(ctx context.Context, uuid string, now time.Time) (program.Program, error) {
+	pgram, err := p.programPersistence.LoadByUUID(ctx, uuid)
+	if err != nil {
+		return program.Program{}, err
+	}
+	if pgram.Status == program.StatusRecording || pgram.Status == program.StatusScheduled {
+		return program.Program{}, pkgerrors.Wrapf(errutil.ErrInvalidStatus, "cannot requeue %s program", pgram.Status)
+	}
+	if pgram.StreamType == program.StreamTypeBroadcast && !now.Before(pgram.Start) {
+		// 録画されることがないまま scheduled として残ってしまう
+		return program.Program{}, pkgerrors.Wrapf(errutil.ErrInvalidStatus, "broadcast program already started at %s", pgram.Start)
+	}
+	return p.changeStatus(ctx, pgram, program.StatusScheduled)
+}
+
+// 番組を deleted にする
+// 行ごと削除すると、番組表に残っている番組は次の更新で scheduled として登録しなおされてしまう
+// requeue すれば元に戻せる
+// 録画済みファイルは削除しない
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+// - errutil.ErrInvalidStatus
+func (p *ucPrograms) Delete(ctx context.Context, uuid string) (program.Program, error) {
+	pgram, err := p.programPersistence.LoadByUUID(ctx, uuid)
+	if err != nil {
+		return program.Program{}, err
+	}
+	if pgram.Status == program.StatusRecording {
+		return program.Program{}, pkgerrors.Wrap(errutil.ErrInvalidStatus, "cannot delete recording program")
+	}
+	return p.changeStatus(ctx, pgram, program.StatusDeleted)
+}
+
+// retention による削除から保護するか否か
+// 返されるエラー
+// - errutil.ErrDatabaseNotFoundProgram
+func (p *ucPrograms) SetProtected(ctx context.Context, uuid string, protected bool) (program.Program, error) {
+	pgram, err := p.programPersistence.LoadByUUID(ctx, uuid)
+	if err != nil {
+		return program.Program{}, err
+	}
+	err = p.programPersistence.ChangeProtected(ctx, pgram, protected)
+	if err != nil {
+		return program.Program{}, err
+	}
+	pgram.Protected = protected
+	return pgram, nil
+}
+
+func (p *ucPrograms) changeStatus(ctx context.Context, pgram program.Program, status program.Status) (program.Program, error) {
+	err := p.programPersistence.ChangeStatus(ctx, pgram, status)
+	if err != nil {
+		return program.Program{}, err
+	}
+	pgram.Status = status
+	return pgram, nil
+}
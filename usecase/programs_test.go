@@ -0,0 +1,301 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func Test_ucPrograms_Skip(t *testing.T) {
+	pgram := program.Program{
+		UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeBroadcast,
+	}
+
+	tests := []struct {
+		name    string
+		status  program.Status
+		want    program.Status
+		wantErr error
+	}{
+		{
+			name:    "scheduled であれば skipped にする",
+			status:  program.StatusScheduled,
+			want:    program.StatusSkipped,
+			wantErr: nil,
+		},
+		{
+			name:    "recording であれば ErrInvalidStatus",
+			status:  program.StatusRecording,
+			wantErr: errutil.ErrInvalidStatus,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			loaded := pgram
+			loaded.Status = tt.status
+			mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+			mockProgramPersistence.EXPECT().LoadByUUID(gomock.Any(), pgram.UUID).Return(loaded, nil)
+			if tt.wantErr == nil {
+				mockProgramPersistence.EXPECT().ChangeStatus(gomock.Any(), loaded, tt.want).Return(nil)
+			}
+
+			p := NewPrograms(mockProgramPersistence)
+			got, err := p.Skip(context.Background(), pgram.UUID)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("ucPrograms.Skip() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil && got.Status != tt.want {
+				t.Errorf("ucPrograms.Skip() status = %v, want %v", got.Status, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ucPrograms_Requeue(t *testing.T) {
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		pgram   program.Program
+		want    program.Program
+		wantErr error
+	}{
+		{
+			name: "失敗した ondemand を scheduled に戻す",
+			pgram: program.Program{
+				UUID:       "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+				Start:      now.Add(-7 * 24 * time.Hour),
+				Status:     program.StatusFailed,
+				StreamType: program.StreamTypeOndemand,
+			},
+			want: program.Program{
+				UUID:       "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+				Start:      now.Add(-7 * 24 * time.Hour),
+				Status:     program.StatusScheduled,
+				StreamType: program.StreamTypeOndemand,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "skipped な broadcast でもこれから始まるなら scheduled に戻す",
+			pgram: program.Program{
+				UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+				Start:      now.Add(1 * time.Hour),
+				Status:     program.StatusSkipped,
+				StreamType: program.StreamTypeBroadcast,
+			},
+			want: program.Program{
+				UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+				Start:      now.Add(1 * time.Hour),
+				Status:     program.StatusScheduled,
+				StreamType: program.StreamTypeBroadcast,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "既に始まった broadcast は ErrInvalidStatus",
+			pgram: program.Program{
+				UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+				Start:      now.Add(-1 * time.Hour),
+				Status:     program.StatusFailed,
+				StreamType: program.StreamTypeBroadcast,
+			},
+			wantErr: errutil.ErrInvalidStatus,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+			mockProgramPersistence.EXPECT().LoadByUUID(gomock.Any(), tt.pgram.UUID).Return(tt.pgram, nil)
+			if tt.wantErr == nil {
+				mockProgramPersistence.EXPECT().ChangeStatus(gomock.Any(), tt.pgram, program.StatusScheduled).Return(nil)
+			}
+
+			p := NewPrograms(mockProgramPersistence)
+			got, err := p.Requeue(context.Background(), tt.pgram.UUID, now)
+			if !testutil.ErrorsAs(err, tt.wantErr) {
+				t.Errorf("ucPrograms.Requeue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ucPrograms.Requeue() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ucPrograms_List(t *testing.T) {
+	ctx := context.Background()
+	pgramDone := program.Program{
+		UUID:       "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+		ID:         11133,
+		Station:    program.StationOnsen,
+		Title:      "セブン-イレブン presents 佐倉としたい大西",
+		Episode:    "第333回",
+		Start:      time.Date(2022, 8, 9, 0, 0, 0, 0, time.UTC),
+		Status:     program.StatusDone,
+		StreamType: program.StreamTypeOndemand,
+	}
+	pgramDeleted := program.Program{
+		UUID:       "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+		ID:         11134,
+		Station:    program.StationOnsen,
+		Title:      "セブン-イレブン presents 佐倉としたい大西",
+		Episode:    "第334回",
+		Start:      time.Date(2022, 8, 16, 0, 0, 0, 0, time.UTC),
+		Status:     program.StatusDeleted,
+		StreamType: program.StreamTypeOndemand,
+	}
+
+	programPersistence := memory.New()
+	for _, pgram := range []program.Program{pgramDone, pgramDeleted} {
+		err := programPersistence.Save(ctx, pgram)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := NewPrograms(programPersistence)
+
+	tests := []struct {
+		name   string
+		filter program.Filter
+		want   []string
+	}{
+		{
+			name:   "deleted な番組は含めない",
+			filter: program.Filter{Station: program.StationOnsen},
+			want:   []string{pgramDone.UUID},
+		},
+		{
+			name:   "status に deleted を指定すれば含める",
+			filter: program.Filter{Status: program.StatusDeleted},
+			want:   []string{pgramDeleted.UUID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgrams, err := p.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ucPrograms.List() error = %v", err)
+			}
+			var got []string
+			for _, pgram := range pgrams {
+				got = append(got, pgram.UUID)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ucPrograms.List() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ucPrograms_lifecycle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, time.UTC)
+	pgram := program.Program{
+		UUID:        "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+		ID:          11134,
+		Station:     program.StationOnsen,
+		Title:       "セブン-イレブン presents 佐倉としたい大西",
+		Episode:     "第334回",
+		Start:       time.Date(2022, 8, 16, 0, 0, 0, 0, time.UTC),
+		Status:      program.StatusScheduled,
+		StreamType:  program.StreamTypeOndemand,
+		PlaylistURL: "https://onsen.test/playlist.m3u8",
+	}
+
+	programPersistence := memory.New()
+	err := programPersistence.Save(ctx, pgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPrograms(programPersistence)
+
+	steps := []struct {
+		name       string
+		do         func() (program.Program, error)
+		wantStatus program.Status
+		wantErr    error
+	}{
+		{
+			name:       "scheduled を skip する",
+			do:         func() (program.Program, error) { return p.Skip(ctx, pgram.UUID) },
+			wantStatus: program.StatusSkipped,
+		},
+		{
+			name:    "skipped はもう一度 skip できない",
+			do:      func() (program.Program, error) { return p.Skip(ctx, pgram.UUID) },
+			wantErr: errutil.ErrInvalidStatus,
+		},
+		{
+			name:       "skipped を requeue する",
+			do:         func() (program.Program, error) { return p.Requeue(ctx, pgram.UUID, now) },
+			wantStatus: program.StatusScheduled,
+		},
+		{
+			name:       "recording に強制的に変更する",
+			do:         func() (program.Program, error) { return p.SetStatus(ctx, pgram.UUID, program.StatusRecording) },
+			wantStatus: program.StatusRecording,
+		},
+		{
+			name:    "recording は削除できない",
+			do:      func() (program.Program, error) { return p.Delete(ctx, pgram.UUID) },
+			wantErr: errutil.ErrInvalidStatus,
+		},
+		{
+			name:       "failed に強制的に変更する",
+			do:         func() (program.Program, error) { return p.SetStatus(ctx, pgram.UUID, program.StatusFailed) },
+			wantStatus: program.StatusFailed,
+		},
+		{
+			name:       "failed は削除できる",
+			do:         func() (program.Program, error) { return p.Delete(ctx, pgram.UUID) },
+			wantStatus: program.StatusDeleted,
+		},
+		{
+			name: "削除した番組は番組表の更新で scheduled に戻らない",
+			do: func() (program.Program, error) {
+				err := programPersistence.Save(ctx, pgram)
+				if err != nil {
+					return program.Program{}, err
+				}
+				return p.Show(ctx, pgram.UUID)
+			},
+			wantStatus: program.StatusDeleted,
+		},
+		{
+			name:       "削除した番組を requeue する",
+			do:         func() (program.Program, error) { return p.Requeue(ctx, pgram.UUID, now) },
+			wantStatus: program.StatusScheduled,
+		},
+	}
+	// 各ステップは前のステップの結果に依存する
+	for _, step := range steps {
+		got, err := step.do()
+		if !testutil.ErrorsAs(err, step.wantErr) {
+			t.Fatalf("%s: error = %v, wantErr %v", step.name, err, step.wantErr)
+		}
+		if step.wantErr == nil && got.Status != step.wantStatus {
+			t.Fatalf("%s: status = %v, want %v", step.name, got.Status, step.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/ffmpegutil"
+)
+
+// pgrams を録画中として登録し、ffmpeg の進み具合を記録する context を返す
+// まとめて録画する番組には同じ進み具合を記録する
+// 録画が終われば返された関数を呼ぶ
+func (r *ucRecorder) trackRecording(ctx context.Context, pgrams ...program.Program) (context.Context, func()) {
+	startedAt := r.clock.Now()
+
+	r.mu.Lock()
+	if r.recordings == nil {
+		r.recordings = map[string]*recorder.Recording{}
+	}
+	for _, pgram := range pgrams {
+		r.recordings[pgram.UUID] = &recorder.Recording{Program: pgram, StartedAt: startedAt}
+	}
+	r.mu.Unlock()
+
+	ctx = ffmpegutil.WithProgress(ctx, func(p recorder.Progress) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, pgram := range pgrams {
+			if recording, ok := r.recordings[pgram.UUID]; ok {
+				recording.Progress = p
+			}
+		}
+	})
+	return ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, pgram := range pgrams {
+			delete(r.recordings, pgram.UUID)
+		}
+	}
+}
+
+// 録画中の番組を録画開始日時の昇順で返す
+func (r *ucRecorder) Recordings() []recorder.Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recordings := make([]recorder.Recording, 0, len(r.recordings))
+	for _, recording := range r.recordings {
+		recordings = append(recordings, *recording)
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		if !recordings[i].StartedAt.Equal(recordings[j].StartedAt) {
+			return recordings[i].StartedAt.Before(recordings[j].StartedAt)
+		}
+		return recordings[i].Program.UUID < recordings[j].Program.UUID
+	})
+	return recordings
+}
@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/ffmpegutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func Test_ucRecorder_Recordings(t *testing.T) {
+	ctx := context.Background()
+	pgram := newAgqrBroadcast("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", "鷲崎健のヨルナイト×ヨルナイト", time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()), 30*time.Minute)
+	config := recorder.Config{ArchiveDir: "/archive", Margin: 1 * time.Minute}
+	startAt := pgram.Start.Add(-config.Margin)
+	progress := recorder.Progress{
+		OutTime:   90 * time.Second,
+		Bitrate:   129.4,
+		TotalSize: 1456128,
+		UpdatedAt: startAt.Add(90 * time.Second),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	programPersistence := memory.New()
+	err := programPersistence.Save(ctx, pgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &ucRecorder{
+		programPersistence: programPersistence,
+		clock:              clock.NewFake(startAt),
+	}
+	var recording []recorder.Recording
+	mockAgqr := mock_repository.NewMockStation(ctrl)
+	mockAgqr.EXPECT().
+		Rec(gomock.Any(), config, pgram).
+		DoAndReturn(func(ctx context.Context, config recorder.Config, pgram program.Program) error {
+			// ffmpeg が報告した進み具合
+			ffmpegutil.ReportProgress(ctx, progress)
+			recording = r.Recordings()
+			return nil
+		})
+	mockAgqr.EXPECT().ArchiveFilePath(config, pgram).Return("/archive/agqr/file.ts")
+	r.agqr = mockAgqr
+
+	r.rec(ctx, config, startAt, pgram)
+
+	want := []recorder.Recording{{Program: pgram, StartedAt: startAt, Progress: progress}}
+	if diff := cmp.Diff(want, recording); diff != "" {
+		t.Errorf("Recordings() while rec mismatch (-want +got):\n%s", diff)
+	}
+	if got := r.Recordings(); len(got) != 0 {
+		t.Errorf("Recordings() after rec = %+v, want empty", got)
+	}
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/fileutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+// ctx の logger に出力されるものを録画ログにも書き込む
+// ffmpeg の出力も ctx の logger に流れてくる
+type recLogHook struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	clock clock.Clock
+}
+
+func (h recLogHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	label := "---"
+	if level != zerolog.NoLevel {
+		label = strings.ToUpper(level.String())
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(h.w, "%s %s %s\n", h.clock.Now().In(timeutil.LocationJST()).Format("2006-01-02T15:04:05.000Z07:00"), label, msg)
+}
+
+// pgrams の録画ログを作成してデータベースに記録し、書き込む logger を入れた context を返す
+// まとめて録画する番組は同じ録画ログを参照する
+// 録画が終われば返された関数を呼ぶ
+// 作成できなくても録画は続ける
+func (r *ucRecorder) openRecLog(ctx context.Context, config recorder.Config, station repository.Station, pgrams ...program.Program) (context.Context, func()) {
+	if !config.RecordingLog || config.DryRun {
+		return ctx, func() {}
+	}
+
+	startedAt := r.clock.Now()
+	path := recLogFilePath(config, station.ArchiveFilePath(config, pgrams[0]), pgrams[0], startedAt)
+	err := fileutil.MkdirAllIfNotExist(filepath.Dir(path))
+	if err != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to create recording log: %+v", err)
+		return ctx, func() {}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to create recording log: %+v", err)
+		return ctx, func() {}
+	}
+
+	for _, pgram := range pgrams {
+		err := r.programPersistence.SaveRecordingLog(ctx, pgram, recorder.RecordingLog{FilePath: path, StartedAt: startedAt})
+		if err != nil {
+			log.Ctx(ctx).Warn().Msgf("failed to save recording log (program = %+v): %+v", pgram, err)
+		}
+	}
+
+	logger := *log.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		// context に logger がなければ何も書き込まれない
+		logger = log.Logger
+	}
+	logger = logger.Hook(recLogHook{mu: &sync.Mutex{}, w: file, clock: r.clock})
+	ctx = logger.WithContext(ctx)
+	for _, pgram := range pgrams {
+		log.Ctx(ctx).Info().Msgf("recording log for %s %s (uuid = %s, start = %s, end = %s)", pgram.Station, pgram.Title, pgram.UUID, pgram.Start, pgram.End)
+	}
+	return ctx, func() {
+		err := file.Close()
+		if err != nil {
+			log.Ctx(ctx).Warn().Msgf("failed to close recording log: %+v", err)
+		}
+	}
+}
+
+// RecordingLogDir が空であれば録画済みファイルの隣に、録画を始めた日時を付けて作る
+// 例: /archive/agqr/2022-08-17/2022-08-17_2300_鷲崎健のヨルナイト×ヨルナイト_20220817-225900.log
+func recLogFilePath(config recorder.Config, archive string, pgram program.Program, startedAt time.Time) string {
+	base := strings.TrimSuffix(filepath.Base(archive), filepath.Ext(archive)) + "_" + startedAt.In(timeutil.LocationJST()).Format("20060102-150405") + ".log"
+	if config.RecordingLogDir == "" {
+		return filepath.Join(filepath.Dir(archive), base)
+	}
+	return filepath.Join(config.RecordingLogDir, pgram.Station.String(), base)
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func Test_ucRecorder_rec_recordingLog(t *testing.T) {
+	pgram := newAgqrBroadcast("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", "鷲崎健のヨルナイト×ヨルナイト", time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()), 30*time.Minute)
+
+	tests := []struct {
+		name    string
+		logDir  func(dir string) string
+		wantLog func(dir string) string
+	}{
+		{
+			name:    "録画済みファイルの隣に作る",
+			logDir:  func(dir string) string { return "" },
+			wantLog: func(dir string) string { return filepath.Join(dir, "agqr", "file_20220817-225900.log") },
+		},
+		{
+			name:    "ディレクトリを指定すれば station 毎にまとめる",
+			logDir:  func(dir string) string { return filepath.Join(dir, "logs") },
+			wantLog: func(dir string) string { return filepath.Join(dir, "logs", "agqr", "file_20220817-225900.log") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := log.Logger.Level(zerolog.Disabled).WithContext(context.Background())
+			dir := t.TempDir()
+			config := recorder.Config{
+				ArchiveDir:      dir,
+				Margin:          1 * time.Minute,
+				RecordingLog:    true,
+				RecordingLogDir: tt.logDir(dir),
+			}
+			startAt := pgram.Start.Add(-config.Margin)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			programPersistence := memory.New()
+			err := programPersistence.Save(ctx, pgram)
+			if err != nil {
+				t.Fatal(err)
+			}
+			archive := filepath.Join(dir, "agqr", "file.ts")
+			mockAgqr := mock_repository.NewMockStation(ctrl)
+			mockAgqr.EXPECT().ArchiveFilePath(config, pgram).Return(archive).AnyTimes()
+			mockAgqr.EXPECT().
+				Rec(gomock.Any(), config, pgram).
+				DoAndReturn(func(ctx context.Context, config recorder.Config, pgram program.Program) error {
+					// ffmpeg の出力
+					_, err := log.Ctx(ctx).Write([]byte("[hls @ 0x55d0] Opening 'aandg1next-1650291.ts' for reading\n"))
+					return err
+				})
+
+			r := &ucRecorder{
+				programPersistence: programPersistence,
+				agqr:               mockAgqr,
+				clock:              clock.NewFake(startAt),
+			}
+			r.rec(ctx, config, startAt, pgram)
+
+			recLogs, err := programPersistence.LoadRecordingLogs(ctx, pgram.UUID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.wantLog(dir)
+			if len(recLogs) != 1 || recLogs[0].FilePath != want || !recLogs[0].StartedAt.Equal(startAt) {
+				t.Fatalf("LoadRecordingLogs() = %+v, want %s started at %s", recLogs, want, startAt)
+			}
+
+			content, err := os.ReadFile(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// logger の level に関わらず書き込む
+			for _, line := range []string{
+				"2022-08-17T22:59:00.000+09:00 --- [hls @ 0x55d0] Opening 'aandg1next-1650291.ts' for reading",
+				"INFO successfully rec program",
+			} {
+				if !strings.Contains(string(content), line) {
+					t.Errorf("recording log does not contain %q:\n%s", line, content)
+				}
+			}
+		})
+	}
+}
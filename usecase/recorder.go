@@ -3,15 +3,21 @@ package usecase
 import (
 	"context"
 	"errors"
-	"flag"
+	"os"
+	"sync"
 	"time"
 
+	pkgerrors "github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/sobadon/anrd/domain/model/date"
+	"github.com/sobadon/anrd/domain/model/notification"
 	"github.com/sobadon/anrd/domain/model/program"
 	"github.com/sobadon/anrd/domain/model/recorder"
 	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/diskutil"
 	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/metrics"
 	"github.com/sobadon/anrd/internal/timeutil"
 )
 
@@ -19,33 +25,84 @@ type ucRecorder struct {
 	programPersistence repository.ProgramPersistence
 	onsen              repository.Station
 	agqr               repository.Station
+
+	// nil であればアップロードしない
+	objectStorage repository.ObjectStorage
+
+	// nil であれば通知しない
+	notifier repository.Notifier
+
+	// 空き容量（byte）を返す
+	freeSpace func(path string) (uint64, error)
+
+	clock clock.Clock
+
+	// broadcast な番組の録画開始を待つもの
+	scheduler *recScheduler
+
+	mu sync.Mutex
+	// 最後に UpdateProgram に成功した日時
+	lastUpdatedAt time.Time
+	// 録画中の番組
+	// key は番組の UUID
+	recordings map[string]*recorder.Recording
 }
 
 func NewRecorder(
 	programPersistence repository.ProgramPersistence,
 	onsen repository.Station,
 	agqr repository.Station,
+	objectStorage repository.ObjectStorage,
+	notifier repository.Notifier,
+	clk clock.Clock,
 ) *ucRecorder {
-	return &ucRecorder{
+	r := &ucRecorder{
 		programPersistence: programPersistence,
 		onsen:              onsen,
 		agqr:               agqr,
+		objectStorage:      objectStorage,
+		notifier:           notifier,
+		freeSpace:          diskutil.FreeBytes,
+		clock:              clk,
 	}
+	r.scheduler = newRecScheduler(clk, r.recBroadcast)
+	return r
 }
 
 func (r *ucRecorder) UpdateProgram(ctx context.Context) error {
+	err := r.updateProgram(ctx)
+	if err != nil {
+		r.notify(ctx, notification.EventUpdateProgramFailed, nil, "failed to update program: %v", err)
+		return err
+	}
+
+	r.mu.Lock()
+	r.lastUpdatedAt = r.clock.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// 最後に UpdateProgram に成功した日時
+// 一度も成功していなければゼロ値
+func (r *ucRecorder) LastUpdatedAt() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastUpdatedAt
+}
+
+func (r *ucRecorder) updateProgram(ctx context.Context) error {
 	var pgrams []program.Program
 
 	// 音泉
-	pgrams_temp, err := r.onsen.GetPrograms(ctx, date.Date{})
+	pgrams_temp, err := r.getPrograms(ctx, program.StationOnsen, date.Date{})
 	if err != nil {
 		return err
 	}
 	pgrams = append(pgrams, pgrams_temp...)
 
 	// agqr
-	now := time.Now().In(timeutil.LocationJST())
-	pgrams_temp, err = r.agqr.GetPrograms(ctx, date.NewFromToday(now))
+	now := r.clock.Now().In(timeutil.LocationJST())
+	pgrams_temp, err = r.getPrograms(ctx, program.StationAgqr, date.NewFromToday(now))
 	if err != nil {
 		return err
 	}
@@ -62,26 +119,119 @@ func (r *ucRecorder) UpdateProgram(ctx context.Context) error {
 	return nil
 }
 
+// 番組表を取得し、その結果をメトリクスに記録する
+func (r *ucRecorder) getPrograms(ctx context.Context, station program.Station, d date.Date) ([]program.Program, error) {
+	start := time.Now()
+	pgrams, err := r.station(station).GetPrograms(ctx, d)
+	metrics.GuideFetchDuration.WithLabelValues(station.String()).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.GuideFetchesTotal.WithLabelValues(station.String(), metrics.ResultFailure).Inc()
+		return nil, err
+	}
+	metrics.GuideFetchesTotal.WithLabelValues(station.String(), metrics.ResultSuccess).Inc()
+	metrics.GuidePrograms.WithLabelValues(station.String()).Set(float64(len(pgrams)))
+	return pgrams, nil
+}
+
+// PrepareAfter 後までに始まる broadcast な番組を scheduler に登録し、scheduler の状態をデータベースに合わせる
+// - 新しく見つかった番組は Start - Margin に録画を開始するよう登録する
+// - 番組表の変更で Start, End が変わっていれば登録しなおす（手動での変更は破棄される）
+// - scheduled でなくなった番組や削除された番組は取り消す
 func (r *ucRecorder) RecBroadcastPrepare(ctx context.Context, config recorder.Config, now time.Time) error {
 	targetPgrams, err := r.programPersistence.LoadBroadcastStartIn(ctx, now, config.PrepareAfter)
-	if errors.As(err, &errutil.ErrDatabaseNotFoundProgram) {
-		log.Ctx(ctx).Debug().Msg("not found program")
-		return nil
-	}
-	if err != nil {
+	if err != nil && !errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
 		return err
 	}
+	if len(targetPgrams) == 0 {
+		log.Ctx(ctx).Debug().Msg("not found program")
+	}
 
+	loaded := map[string]bool{}
 	for _, targetPgram := range targetPgrams {
-		go r.rec(ctx, config, now, targetPgram)
+		loaded[targetPgram.UUID] = true
+		current, ok := r.scheduler.get(targetPgram.UUID)
+		if ok && sameSchedule(current.Program, targetPgram) {
+			continue
+		}
+		if ok {
+			log.Ctx(ctx).Info().Msgf("program is changed, reschedule (program = %+v)", targetPgram)
+		}
+		r.scheduler.add(ctx, config, recorder.Scheduled{
+			Program: targetPgram,
+			At:      targetPgram.Start.Add(-config.Margin),
+		})
+	}
+
+	for _, scheduled := range r.scheduler.list() {
+		if loaded[scheduled.Program.UUID] {
+			continue
+		}
+		// 手動で開始日時を変更したものは LoadBroadcastStartIn の範囲外になりうるので、1 件ずつ確認する
+		pgram, err := r.programPersistence.LoadByUUID(ctx, scheduled.Program.UUID)
+		if err != nil && !errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+			log.Ctx(ctx).Warn().Msgf("failed to check scheduled program, keep waiting (program = %+v): %+v", scheduled.Program, err)
+			continue
+		}
+		if err == nil && pgram.Status == program.StatusScheduled && sameSchedule(scheduled.Program, pgram) {
+			continue
+		}
+		log.Ctx(ctx).Info().Msgf("program is no longer scheduled, cancel (program = %+v)", scheduled.Program)
+		r.scheduler.cancel(scheduled.Program.UUID)
 	}
 
 	return nil
 }
 
+// 録画開始を待っている番組を、録画開始日時の昇順で返す
+func (r *ucRecorder) Scheduled() []recorder.Scheduled {
+	return r.scheduler.list()
+}
+
+// 録画開始を待っている番組を取り消し、status を skipped にする
+// skipped にしないと、次の RecBroadcastPrepare で再び登録される
+// 返されるエラー
+// - errutil.ErrNotScheduled
+// - errutil.ErrDatabaseNotFoundProgram
+func (r *ucRecorder) CancelScheduled(ctx context.Context, uuid string) (recorder.Scheduled, error) {
+	scheduled, ok := r.scheduler.cancel(uuid)
+	if !ok {
+		return recorder.Scheduled{}, pkgerrors.Wrapf(errutil.ErrNotScheduled, "uuid = %s", uuid)
+	}
+	err := r.programPersistence.ChangeStatus(ctx, scheduled.Program, program.StatusSkipped)
+	if err != nil {
+		return recorder.Scheduled{}, err
+	}
+	scheduled.Program.Status = program.StatusSkipped
+	return scheduled, nil
+}
+
+// 録画開始を待っている番組の録画開始日時を at に変更する
+// 番組表の変更で Start, End が変われば、番組表に合わせた日時に戻る
+// 返されるエラー
+// - errutil.ErrNotScheduled
+func (r *ucRecorder) Reschedule(uuid string, at time.Time) (recorder.Scheduled, error) {
+	scheduled, ok := r.scheduler.reschedule(uuid, at)
+	if !ok {
+		return recorder.Scheduled{}, pkgerrors.Wrapf(errutil.ErrNotScheduled, "uuid = %s", uuid)
+	}
+	return scheduled, nil
+}
+
+func sameSchedule(a, b program.Program) bool {
+	return a.Start.Equal(b.Start) && a.End.Equal(b.End)
+}
+
 func (r *ucRecorder) RecOndemandPrepare(ctx context.Context, config recorder.Config, now time.Time) error {
-	// あまり多いとリソース割かれちゃうので、ひとまず一気に 2 件
-	targetPgrams, err := r.programPersistence.LoadOndemandScheduled(ctx, 2)
+	// メトリクスのためだけなので、失敗しても録画は続ける
+	count, err := r.programPersistence.CountOndemandScheduled(ctx)
+	if err != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to count ondemand scheduled programs: %+v", err)
+	} else {
+		metrics.OndemandQueueDepth.Set(float64(count))
+	}
+
+	// あまり多いとリソース割かれちゃうので、一気に録画するのは OndemandLimit 件まで
+	targetPgrams, err := r.programPersistence.LoadOndemandScheduled(ctx, config.OndemandLimit)
 	if errors.As(err, &errutil.ErrDatabaseNotFoundProgram) {
 		log.Ctx(ctx).Debug().Msg("not found program")
 		return nil
@@ -93,61 +243,225 @@ func (r *ucRecorder) RecOndemandPrepare(ctx context.Context, config recorder.Con
 	for _, targetPgram := range *targetPgrams {
 		go r.rec(ctx, config, now, targetPgram)
 		// 一気に録画開始は負荷高そうなので気持ちズラす
-		time.Sleep(30 * time.Second)
+		err := r.clock.Sleep(ctx, config.OndemandInterval)
+		if err != nil {
+			// 停止中なので残りは次回以降に回す
+			return nil
+		}
 	}
 
 	return nil
 }
 
 // 録画（録音）処理を呼び出す
+// broadcast な番組は startAt まで待ってから録画を開始する
 // 内部でリトライあり
 // これは goroutine として呼び出されることを想定
 // エラーが発生すればこの関数内でログ出力してしまう
-func (r *ucRecorder) rec(ctx context.Context, config recorder.Config, now time.Time, targetPgram program.Program) {
-	// retryCount=0, 1, 2, 3 の計 4 回トライする
-	const retryMaxCount = 3
+func (r *ucRecorder) rec(ctx context.Context, config recorder.Config, startAt time.Time, targetPgram program.Program) {
+	// retryMaxCount=3 であれば retryCount=0, 1, 2, 3 の計 4 回トライする
+	retryMaxCount := config.RetryMaxCount
 	retryCount := 0
 
-	err := r.programPersistence.ChangeStatus(ctx, targetPgram, program.StatusRecording)
+	err := r.checkDiskSpace(ctx, config, targetPgram)
+	if errors.Is(err, errutil.ErrDiskSpaceShortage) {
+		if targetPgram.StreamType == program.StreamTypeOndemand {
+			// ondemand は後からでも録画できるので、status は scheduled のまま次回以降に回す
+			log.Ctx(ctx).Warn().Msgf("defer rec because of disk space shortage (program = %+v): %+v", targetPgram, err)
+			return
+		}
+		log.Ctx(ctx).Error().Msgf("refuse rec because of disk space shortage (program = %+v): %+v", targetPgram, err)
+		r.recFailed(ctx, targetPgram, "refuse rec because of disk space shortage: %v", err)
+		return
+	}
+	if err != nil {
+		// 空き容量が確認できないだけで録画を諦めることはしない
+		log.Ctx(ctx).Warn().Msgf("failed to check disk space: %+v", err)
+	}
+
+	err = r.programPersistence.ChangeStatus(ctx, targetPgram, program.StatusRecording)
 	if err != nil {
 		return
 	}
 
 	if targetPgram.StreamType == program.StreamTypeBroadcast {
 		// ffmpeg 叩き前の sleep
-		sleepDuration := targetPgram.Start.Sub(now) - config.Margin
+		sleepDuration := startAt.Sub(r.clock.Now())
 		log.Ctx(ctx).Debug().Msgf("sleep ... (duration = %s)", sleepDuration)
-		if flag.Lookup("test.v") == nil {
-			// テスト実行時に time.Sleep() されると困っちゃうから無理くり無効に
-			time.Sleep(sleepDuration)
+		err = r.clock.Sleep(ctx, sleepDuration)
+		if err != nil {
+			log.Ctx(ctx).Error().Msgf("canceled while waiting for program to start (program = %+v): %+v", targetPgram, err)
+			// ctx はキャンセル済みなので logger だけ引き継ぐ
+			err = r.programPersistence.ChangeStatus(log.Ctx(ctx).WithContext(context.Background()), targetPgram, program.StatusFailed)
+			if err != nil {
+				log.Ctx(ctx).Error().Msgf("%+v", err)
+			}
+			return
 		}
 	}
 
-	for retryCount <= retryMaxCount {
-		var err error
-		switch targetPgram.Station {
-		case program.StationOnsen:
-			err = r.onsen.Rec(ctx, config, targetPgram)
-		case program.StationAgqr:
-			err = r.agqr.Rec(ctx, config, targetPgram)
+	monitorCtx, cancelMonitor := context.WithCancel(ctx)
+	defer cancelMonitor()
+	go r.monitorDiskSpace(monitorCtx, config)
+
+	station := r.station(targetPgram.Station)
+	if station == nil {
+		log.Ctx(ctx).Error().Msgf("unknown station (program = %+v)", targetPgram)
+		err = r.programPersistence.ChangeStatus(ctx, targetPgram, program.StatusFailed)
+		if err != nil {
+			log.Ctx(ctx).Error().Msgf("%+v", err)
+		}
+		return
+	}
+
+	ctx, closeRecLog := r.openRecLog(ctx, config, station, targetPgram)
+	defer closeRecLog()
+	ctx, untrack := r.trackRecording(ctx, targetPgram)
+	defer untrack()
+
+	r.notify(ctx, notification.EventRecStarted, &targetPgram, "rec started")
+	metrics.RecordingsStartedTotal.WithLabelValues(targetPgram.Station.String(), targetPgram.StreamType.String()).Inc()
+
+	if config.DryRun {
+		r.recDryRun(ctx, config, station, recMode(config, capableStation(station), targetPgram), targetPgram)
+		return
+	}
+
+	switch recMode(config, station, targetPgram) {
+	case recorder.ModeRedundant:
+		err := r.recRedundant(ctx, config, station.(repository.RedundantStation), targetPgram)
+		if err != nil {
+			log.Ctx(ctx).Error().Msgf("failed to rec (program = %+v): %+v", targetPgram, err)
+			r.recFailed(ctx, targetPgram, "rec failed: %v", err)
+			return
+		}
+		log.Ctx(ctx).Info().Msgf("successfully rec program (program = %+v)", targetPgram)
+		r.recSucceeded(ctx, config, station, targetPgram)
+		return
+	case recorder.ModeResumable:
+		err := r.recParts(ctx, config, station.(repository.ResumableStation), targetPgram)
+		if err != nil {
+			log.Ctx(ctx).Error().Msgf("failed to rec (program = %+v): %+v", targetPgram, err)
+			r.recFailed(ctx, targetPgram, "rec failed: %v", err)
+			return
 		}
+		log.Ctx(ctx).Info().Msgf("successfully rec program (program = %+v)", targetPgram)
+		r.recSucceeded(ctx, config, station, targetPgram)
+		return
+	}
+
+	for retryCount <= retryMaxCount {
+		metrics.FfmpegActive.Inc()
+		err := station.Rec(ctx, config, targetPgram)
+		metrics.FfmpegActive.Dec()
 		if err == nil {
 			log.Ctx(ctx).Info().Msgf("successfully rec program (program = %+v)", targetPgram)
-			err := r.programPersistence.ChangeStatus(ctx, targetPgram, program.StatusDone)
-			if err != nil {
-				log.Ctx(ctx).Error().Msgf("%+v", err)
-				return
-			}
+			r.recSucceeded(ctx, config, station, targetPgram)
 			return
 		}
 
-		log.Ctx(ctx).Warn().Msgf("failed to rec (retryCount = %d)", retryCount)
+		log.Ctx(ctx).Warn().Msgf("failed to rec (retryCount = %d): %+v", retryCount, err)
 		retryCount++
 	}
 
 	log.Ctx(ctx).Error().Msgf("rec retry count exceeded retryMaxCount (program = %+v)", targetPgram)
-	err = r.programPersistence.ChangeStatus(ctx, targetPgram, program.StatusFailed)
+	r.recFailed(ctx, targetPgram, "rec failed after %d retries", retryMaxCount)
+}
+
+// targetPgram を単独で録画するときの録画の仕方
+// station が対応しているものから決める
+func recMode(config recorder.Config, station repository.Station, targetPgram program.Program) recorder.Mode {
+	if targetPgram.StreamType != program.StreamTypeBroadcast {
+		return recorder.ModeSingle
+	}
+	if redundant, ok := station.(repository.RedundantStation); ok && redundant.Sources() >= 2 && matchRedundant(config.RedundantRules, targetPgram) {
+		return recorder.ModeRedundant
+	}
+	if _, ok := station.(repository.ResumableStation); ok {
+		return recorder.ModeResumable
+	}
+	return recorder.ModeSingle
+}
+
+// 録画の仕方を決めるための Station
+// dry-run で差し替えられていれば、差し替える前のものが対応しているかで決める
+func capableStation(station repository.Station) repository.Station {
+	if planner, ok := station.(repository.PlanStation); ok {
+		return planner.Unwrap()
+	}
+	return station
+}
+
+// dry-run では録画せず、pgrams を mode で録画するはずだったことを station に記録させる
+// status は recording のまま、同じ番組を何度も録画しようとしない
+func (r *ucRecorder) recDryRun(ctx context.Context, config recorder.Config, station repository.Station, mode recorder.Mode, pgrams ...program.Program) {
+	planner, ok := station.(repository.PlanStation)
+	if !ok {
+		log.Ctx(ctx).Error().Msgf("dry-run: station does not record plans, skip rec (programs = %d)", len(pgrams))
+		return
+	}
+	err := planner.Plan(ctx, config, mode, pgrams)
+	if err != nil {
+		log.Ctx(ctx).Error().Msgf("dry-run: failed to record plan: %+v", err)
+		for _, pgram := range pgrams {
+			r.recFailed(ctx, pgram, "dry-run failed: %v", err)
+		}
+		return
+	}
+	for _, pgram := range pgrams {
+		log.Ctx(ctx).Info().Msgf("dry-run: skip marking program done (mode = %s, program = %+v)", mode, pgram)
+	}
+}
+
+// 録画済みファイルを記録して done にし、通知・アップロードする
+func (r *ucRecorder) recSucceeded(ctx context.Context, config recorder.Config, station repository.Station, targetPgram program.Program) {
+	filePath := station.ArchiveFilePath(config, targetPgram)
+	err := r.programPersistence.ChangeFilePath(ctx, targetPgram, filePath)
 	if err != nil {
-		log.Printf("%+v", err)
+		log.Ctx(ctx).Error().Msgf("%+v", err)
+		return
 	}
+	err = r.programPersistence.ChangeStatus(ctx, targetPgram, program.StatusDone)
+	if err != nil {
+		log.Ctx(ctx).Error().Msgf("%+v", err)
+		return
+	}
+
+	donePgram := targetPgram
+	donePgram.FilePath = filePath
+	donePgram.Status = program.StatusDone
+	r.notify(ctx, notification.EventRecSucceeded, &donePgram, "rec succeeded")
+	metrics.RecordingsSucceededTotal.WithLabelValues(targetPgram.Station.String(), targetPgram.StreamType.String()).Inc()
+	if info, err := os.Stat(filePath); err == nil {
+		metrics.RecordedBytesTotal.WithLabelValues(targetPgram.Station.String()).Add(float64(info.Size()))
+	}
+
+	err = r.upload(ctx, config, donePgram)
+	if err != nil {
+		// 録画自体は成功しているので status は done のまま
+		log.Ctx(ctx).Error().Msgf("failed to upload (program = %+v): %+v", donePgram, err)
+	}
+}
+
+// failed にして通知する
+func (r *ucRecorder) recFailed(ctx context.Context, targetPgram program.Program, format string, args ...interface{}) {
+	err := r.programPersistence.ChangeStatus(ctx, targetPgram, program.StatusFailed)
+	if err != nil {
+		log.Ctx(ctx).Error().Msgf("%+v", err)
+	}
+
+	failedPgram := targetPgram
+	failedPgram.Status = program.StatusFailed
+	r.notify(ctx, notification.EventRecFailed, &failedPgram, format, args...)
+	metrics.RecordingsFailedTotal.WithLabelValues(targetPgram.Station.String(), targetPgram.StreamType.String()).Inc()
+}
+
+func (r *ucRecorder) station(station program.Station) repository.Station {
+	switch station {
+	case program.StationOnsen:
+		return r.onsen
+	case program.StationAgqr:
+		return r.agqr
+	}
+	return nil
 }
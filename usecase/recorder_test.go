@@ -7,20 +7,28 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sobadon/anrd/domain/model/date"
 	"github.com/sobadon/anrd/domain/model/program"
 	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
 	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/metrics"
 	"github.com/sobadon/anrd/internal/timeutil"
 	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
 )
 
 func Test_ucRecorder_rec(t *testing.T) {
-	now := time.Now().In(timeutil.LocationJST())
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, timeutil.LocationJST())
 
 	configCommon := recorder.Config{
 		ArchiveDir:   "/archive",
 		PrepareAfter: 2 * time.Minute,
 		Margin:       1 * time.Minute,
+
+		RetryMaxCount: 3,
 	}
 
 	pgramOndemand := program.Program{
@@ -60,6 +68,12 @@ func Test_ucRecorder_rec(t *testing.T) {
 				f.onsen.EXPECT().
 					Rec(gomock.Any(), configCommon, pgramOndemand).
 					Return(nil)
+				f.onsen.EXPECT().
+					ArchiveFilePath(configCommon, pgramOndemand).
+					Return("/archive/onsen/file.ts")
+				f.programPersistence.EXPECT().
+					ChangeFilePath(gomock.Any(), pgramOndemand, "/archive/onsen/file.ts").
+					Return(nil)
 				f.programPersistence.EXPECT().
 					ChangeStatus(gomock.Any(), pgramOndemand, program.StatusDone).
 					Return(nil)
@@ -83,6 +97,12 @@ func Test_ucRecorder_rec(t *testing.T) {
 					Rec(gomock.Any(), configCommon, pgramOndemand).
 					Return(nil).
 					Times(1)
+				f.onsen.EXPECT().
+					ArchiveFilePath(configCommon, pgramOndemand).
+					Return("/archive/onsen/file.ts")
+				f.programPersistence.EXPECT().
+					ChangeFilePath(gomock.Any(), pgramOndemand, "/archive/onsen/file.ts").
+					Return(nil)
 				f.programPersistence.EXPECT().
 					ChangeStatus(gomock.Any(), pgramOndemand, program.StatusDone).
 					Return(nil)
@@ -123,6 +143,7 @@ func Test_ucRecorder_rec(t *testing.T) {
 			r := &ucRecorder{
 				programPersistence: mockProgramPersistence,
 				onsen:              mockOnsen,
+				clock:              clock.NewFake(now),
 			}
 			f := &fields{
 				programPersistence: mockProgramPersistence,
@@ -133,3 +154,277 @@ func Test_ucRecorder_rec(t *testing.T) {
 		})
 	}
 }
+
+// dry-run では録画せず、実際に録画するときと同じ録画の仕方を記録させる
+func Test_ucRecorder_recBroadcast_dryRun(t *testing.T) {
+	start := time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST())
+	pgramA := newAgqrBroadcast("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", "鷲崎健のヨルナイト×ヨルナイト", start, 30*time.Minute)
+	pgramB := newAgqrBroadcast("0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10", "ハチャメチャ☆ライブ", start.Add(30*time.Minute), 30*time.Minute)
+	pgramOndemand := program.Program{
+		UUID:        "48e582f4-afd8-4a7b-9582-f479f94eff9e",
+		ID:          11134,
+		Station:     program.StationOnsen,
+		Title:       "セブン-イレブン presents 佐倉としたい大西",
+		Episode:     "第334回",
+		Start:       start,
+		Status:      program.StatusScheduled,
+		StreamType:  program.StreamTypeOndemand,
+		PlaylistURL: "https://onsen.test/playlist.m3u8",
+	}
+
+	configCommon := recorder.Config{
+		ArchiveDir:           "/archive",
+		Margin:               1 * time.Minute,
+		RetryMaxCount:        3,
+		ContinuousCaptureMax: 6 * time.Hour,
+		DryRun:               true,
+	}
+
+	tests := []struct {
+		name        string
+		config      func(c *recorder.Config)
+		targetPgram program.Program
+		// 差し替える前の Station
+		unwrap     func(ctrl *gomock.Controller) repository.Station
+		wantMode   recorder.Mode
+		wantPgrams []program.Program
+	}{
+		{
+			name:        "ondemand は single",
+			config:      func(c *recorder.Config) {},
+			targetPgram: pgramOndemand,
+			unwrap: func(ctrl *gomock.Controller) repository.Station {
+				return mock_repository.NewMockStation(ctrl)
+			},
+			wantMode:   recorder.ModeSingle,
+			wantPgrams: []program.Program{pgramOndemand},
+		},
+		{
+			name: "続けて放送される番組は continuous でまとめる",
+			config: func(c *recorder.Config) {
+				c.ContinuousCapture = true
+			},
+			targetPgram: pgramA,
+			unwrap: func(ctrl *gomock.Controller) repository.Station {
+				return mock_repository.NewMockContinuousStation(ctrl)
+			},
+			wantMode:   recorder.ModeContinuous,
+			wantPgrams: []program.Program{pgramA, pgramB},
+		},
+		{
+			name: "条件に該当すれば redundant",
+			config: func(c *recorder.Config) {
+				c.RedundantRules = []recorder.RedundantRule{{Station: program.StationAgqr}}
+			},
+			targetPgram: pgramA,
+			unwrap: func(ctrl *gomock.Controller) repository.Station {
+				m := mock_repository.NewMockRedundantStation(ctrl)
+				m.EXPECT().Sources().Return(2).AnyTimes()
+				return m
+			},
+			wantMode:   recorder.ModeRedundant,
+			wantPgrams: []program.Program{pgramA},
+		},
+		{
+			name:        "再開できる Station の broadcast は resumable",
+			config:      func(c *recorder.Config) {},
+			targetPgram: pgramA,
+			unwrap: func(ctrl *gomock.Controller) repository.Station {
+				return mock_repository.NewMockResumableStation(ctrl)
+			},
+			wantMode:   recorder.ModeResumable,
+			wantPgrams: []program.Program{pgramA},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			config := configCommon
+			tt.config(&config)
+
+			programPersistence := memory.New()
+			for _, pgram := range []program.Program{pgramA, pgramB, pgramOndemand} {
+				err := programPersistence.Save(ctx, pgram)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// 録画に使うメソッドは呼び出されない
+			mockPlanStation := mock_repository.NewMockPlanStation(ctrl)
+			mockPlanStation.EXPECT().Unwrap().Return(tt.unwrap(ctrl)).AnyTimes()
+			mockPlanStation.EXPECT().
+				Plan(gomock.Any(), config, tt.wantMode, tt.wantPgrams).
+				Return(nil)
+
+			startAt := tt.targetPgram.Start.Add(-config.Margin)
+			fakeClock := clock.NewFake(startAt)
+			r := &ucRecorder{
+				programPersistence: programPersistence,
+				onsen:              mockPlanStation,
+				agqr:               mockPlanStation,
+				clock:              fakeClock,
+			}
+			r.scheduler = newRecScheduler(fakeClock, r.recBroadcast)
+			r.recBroadcast(ctx, config, startAt, tt.targetPgram)
+
+			// status は recording のまま
+			for _, pgram := range tt.wantPgrams {
+				got, err := programPersistence.LoadByUUID(ctx, pgram.UUID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Status != program.StatusRecording {
+					t.Errorf("status of %s = %s, want %s", got.Title, got.Status, program.StatusRecording)
+				}
+			}
+		})
+	}
+}
+
+func Test_ucRecorder_getPrograms(t *testing.T) {
+	tests := []struct {
+		name       string
+		returnErr  error
+		wantResult string
+	}{
+		{
+			name:       "取得に成功すれば success を数える",
+			returnErr:  nil,
+			wantResult: metrics.ResultSuccess,
+		},
+		{
+			name:       "取得に失敗すれば failure を数える",
+			returnErr:  errors.Wrap(errutil.ErrHTTPRequest, "something error"),
+			wantResult: metrics.ResultFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAgqr := mock_repository.NewMockStation(ctrl)
+			mockAgqr.EXPECT().
+				GetPrograms(gomock.Any(), gomock.Any()).
+				Return([]program.Program{{}, {}}, tt.returnErr)
+
+			r := &ucRecorder{
+				agqr: mockAgqr,
+			}
+			counter := metrics.GuideFetchesTotal.WithLabelValues(program.StationAgqr.String(), tt.wantResult)
+			before := prom_testutil.ToFloat64(counter)
+
+			_, err := r.getPrograms(context.Background(), program.StationAgqr, date.Date{})
+			if (err != nil) != (tt.returnErr != nil) {
+				t.Errorf("ucRecorder.getPrograms() error = %v, wantErr %v", err, tt.returnErr)
+			}
+			if got := prom_testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("ucRecorder.getPrograms() counter increased by %v, want 1", got)
+			}
+		})
+	}
+}
+
+func Test_ucRecorder_rec_waitUntilStart(t *testing.T) {
+	now := time.Date(2022, 8, 17, 22, 58, 0, 0, timeutil.LocationJST())
+	config := recorder.Config{
+		ArchiveDir:    "/archive",
+		PrepareAfter:  2 * time.Minute,
+		Margin:        1 * time.Minute,
+		RetryMaxCount: 3,
+	}
+	pgramBroadcast := program.Program{
+		UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		ID:         1660140000,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeBroadcast,
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fakeClock := clock.NewFake(now)
+	recStartedAt := make(chan time.Time, 1)
+
+	mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+	mockAgqr := mock_repository.NewMockStation(ctrl)
+	mockProgramPersistence.EXPECT().
+		ChangeStatus(gomock.Any(), pgramBroadcast, program.StatusRecording).
+		Return(nil)
+	mockAgqr.EXPECT().
+		Rec(gomock.Any(), config, pgramBroadcast).
+		DoAndReturn(func(ctx context.Context, config recorder.Config, pgram program.Program) error {
+			recStartedAt <- fakeClock.Now()
+			return nil
+		})
+	mockAgqr.EXPECT().
+		ArchiveFilePath(config, pgramBroadcast).
+		Return("/archive/agqr/file.ts")
+	mockProgramPersistence.EXPECT().
+		ChangeFilePath(gomock.Any(), pgramBroadcast, "/archive/agqr/file.ts").
+		Return(nil)
+	mockProgramPersistence.EXPECT().
+		ChangeStatus(gomock.Any(), pgramBroadcast, program.StatusDone).
+		Return(nil)
+
+	r := &ucRecorder{
+		programPersistence: mockProgramPersistence,
+		agqr:               mockAgqr,
+		clock:              fakeClock,
+	}
+	done := make(chan struct{})
+	go func() {
+		r.rec(context.Background(), config, pgramBroadcast.Start.Add(-config.Margin), pgramBroadcast)
+		close(done)
+	}()
+
+	// 開始 1 分前（マージン）までは録画を始めない
+	fakeClock.BlockUntil(1)
+	fakeClock.Advance(59 * time.Second)
+	select {
+	case got := <-recStartedAt:
+		t.Fatalf("rec started too early at %v", got)
+	default:
+	}
+
+	fakeClock.Advance(1 * time.Second)
+	want := pgramBroadcast.Start.Add(-config.Margin)
+	if got := <-recStartedAt; !got.Equal(want) {
+		t.Errorf("rec started at %v, want %v", got, want)
+	}
+	<-done
+}
+
+func Test_ucRecorder_RecOndemandPrepare_countError(t *testing.T) {
+	now := time.Date(2022, 8, 17, 12, 0, 0, 0, timeutil.LocationJST())
+	config := recorder.Config{OndemandLimit: 2}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+	mockProgramPersistence.EXPECT().
+		CountOndemandScheduled(gomock.Any()).
+		Return(0, errors.Wrap(errutil.ErrDatabaseQuery, "something error"))
+	// メトリクス用の件数を取得できなくても録画対象は取得する
+	mockProgramPersistence.EXPECT().
+		LoadOndemandScheduled(gomock.Any(), config.OndemandLimit).
+		Return(nil, errutil.ErrDatabaseNotFoundProgram)
+
+	r := &ucRecorder{
+		programPersistence: mockProgramPersistence,
+		clock:              clock.NewFake(now),
+	}
+	err := r.RecOndemandPrepare(context.Background(), config, now)
+	if err != nil {
+		t.Errorf("ucRecorder.RecOndemandPrepare() error = %v", err)
+	}
+}
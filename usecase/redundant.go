@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/metrics"
+)
+
+// RedundantRules のいずれかに一致する番組であるか
+func matchRedundant(rules []recorder.RedundantRule, pgram program.Program) bool {
+	for _, rule := range rules {
+		if rule.Match(pgram) {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcast な番組をすべての配信元から同時に録画し、最も良いものを ArchiveFilePath に残す
+// いずれかの配信元が番組全体を録画できていれば良いので、配信元毎のリトライはしない
+// どの配信元も番組全体を録画できておらず、station が repository.ResumableStation であれば、残りの時間を recParts で録画する
+// 配信元毎の結果は SaveAttempts で記録する
+// 返されるエラー
+// - errutil.ErrRecFailed
+// - recParts が返すもの
+// - ファイルの移動に失敗したもの
+func (r *ucRecorder) recRedundant(ctx context.Context, config recorder.Config, station repository.RedundantStation, targetPgram program.Program) error {
+	sources := station.Sources()
+	errs := make([]error, sources)
+	var wg sync.WaitGroup
+	for source := 0; source < sources; source++ {
+		wg.Add(1)
+		go func(source int) {
+			defer wg.Done()
+			metrics.FfmpegActive.Inc()
+			defer metrics.FfmpegActive.Dec()
+			errs[source] = station.RecSource(ctx, config, targetPgram, source)
+		}(source)
+	}
+	wg.Wait()
+
+	attempts := make([]recorder.Attempt, sources)
+	best := 0
+	for source := 0; source < sources; source++ {
+		attempts[source] = r.probeAttempt(ctx, config, station, targetPgram, source, errs[source])
+		if attempts[source].Better(attempts[best]) {
+			best = source
+		}
+	}
+
+	expected := targetPgram.End.Sub(targetPgram.Start) + 2*config.Margin
+	if !attempts[best].Usable(expected) {
+		// 調査できるように録画したファイルは残しておく
+		r.saveAttempts(ctx, targetPgram, attempts)
+		if resumable, ok := station.(repository.ResumableStation); ok && r.clock.Now().Before(targetPgram.End.Add(config.Margin)) {
+			log.Ctx(ctx).Warn().Msgf("no source covers the program, resume rec (best = %+v, program = %+v)", attempts[best].Probe, targetPgram)
+			return r.recParts(ctx, config, resumable, targetPgram)
+		}
+		return pkgerrors.Wrapf(errutil.ErrRecFailed, "no usable source (sources = %d, best = %s, expected = %s)", sources, attempts[best].Probe.Duration, expected)
+	}
+
+	archive := station.ArchiveFilePath(config, targetPgram)
+	err := os.Rename(attempts[best].FilePath, archive)
+	if err != nil {
+		r.saveAttempts(ctx, targetPgram, attempts)
+		return pkgerrors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	attempts[best].FilePath = archive
+	attempts[best].Chosen = true
+	for _, attempt := range attempts {
+		if attempt.Chosen {
+			continue
+		}
+		err := os.Remove(attempt.FilePath)
+		if err != nil && !os.IsNotExist(err) {
+			log.Ctx(ctx).Warn().Msgf("failed to remove unused source file: %+v", err)
+		}
+	}
+	log.Ctx(ctx).Info().Msgf("chose source %d (probe = %+v, program = %+v)", best, attempts[best].Probe, targetPgram)
+
+	r.saveAttempts(ctx, targetPgram, attempts)
+	return nil
+}
+
+// source の録画結果を調べる
+// recErr は RecSource が返したもの
+func (r *ucRecorder) probeAttempt(ctx context.Context, config recorder.Config, station repository.RedundantStation, targetPgram program.Program, source int, recErr error) recorder.Attempt {
+	attempt := recorder.Attempt{
+		ProgramUUID: targetPgram.UUID,
+		Source:      source,
+		FilePath:    station.SourceFilePath(config, targetPgram, source),
+	}
+	if recErr != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to rec (source = %d): %+v", source, recErr)
+		attempt.Error = recErr.Error()
+	}
+
+	// ffmpeg が途中で失敗しても、それまでに録画できた分は使えることがある
+	if _, err := os.Stat(attempt.FilePath); err != nil {
+		return attempt
+	}
+	probe, err := station.Probe(ctx, attempt.FilePath)
+	if err != nil {
+		log.Ctx(ctx).Warn().Msgf("failed to probe (source = %d): %+v", source, err)
+		if attempt.Error == "" {
+			attempt.Error = err.Error()
+		}
+		return attempt
+	}
+	attempt.Probe = probe
+	return attempt
+}
+
+// 記録に失敗しても録画自体の成否は変えない
+func (r *ucRecorder) saveAttempts(ctx context.Context, targetPgram program.Program, attempts []recorder.Attempt) {
+	err := r.programPersistence.SaveAttempts(ctx, targetPgram, attempts)
+	if err != nil {
+		log.Ctx(ctx).Error().Msgf("failed to save attempts (program = %+v): %+v", targetPgram, err)
+	}
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+// 冗長録画と録画の再開の両方ができる Station
+type redundantResumableStation struct {
+	*mock_repository.MockRedundantStation
+	resumable *mock_repository.MockResumableStation
+}
+
+func (s redundantResumableStation) RecPart(ctx context.Context, config recorder.Config, pgram program.Program, index int, duration time.Duration) error {
+	return s.resumable.RecPart(ctx, config, pgram, index, duration)
+}
+
+func (s redundantResumableStation) ConcatParts(ctx context.Context, config recorder.Config, pgram program.Program, parts []recorder.Part) error {
+	return s.resumable.ConcatParts(ctx, config, pgram, parts)
+}
+
+func Test_ucRecorder_rec_redundant(t *testing.T) {
+	pgram := newAgqrBroadcast("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", "鷲崎健のヨルナイト×ヨルナイト", time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()), 30*time.Minute)
+	errFfmpeg := errors.Wrap(errutil.ErrFfmpeg, "connection reset")
+	at := func(hour, min int) time.Time {
+		return time.Date(2022, 8, 17, hour, min, 0, 0, timeutil.LocationJST())
+	}
+
+	type source struct {
+		// 空であればファイルを作らない
+		content string
+		err     error
+		probe   recorder.Probe
+	}
+
+	tests := []struct {
+		name    string
+		rules   []recorder.RedundantRule
+		sources []source
+		// 配信元からの録画が終わった日時
+		recEnd time.Time
+		// nil でなければ Station を repository.ResumableStation にする
+		prepareResume func(station *mock_repository.MockResumableStation, clk *clock.Fake, config recorder.Config)
+		wantStatus    program.Status
+		wantArchive   string
+		// FilePath を除く
+		wantAttempts []recorder.Attempt
+	}{
+		{
+			name:  "長く録画できた配信元を残す",
+			rules: []recorder.RedundantRule{{Station: program.StationAgqr}},
+			sources: []source{
+				{content: "primary", err: errFfmpeg, probe: recorder.Probe{Duration: 20 * time.Minute}},
+				{content: "secondary", probe: recorder.Probe{Duration: 32 * time.Minute, Discontinuities: 1}},
+			},
+			recEnd:      at(23, 31),
+			wantStatus:  program.StatusDone,
+			wantArchive: "secondary",
+			wantAttempts: []recorder.Attempt{
+				{Source: 0, Probe: recorder.Probe{Duration: 20 * time.Minute}, Error: errFfmpeg.Error()},
+				{Source: 1, Probe: recorder.Probe{Duration: 32 * time.Minute, Discontinuities: 1}, Chosen: true},
+			},
+		},
+		{
+			name:  "同じ長さであれば欠落の少ない配信元を残す",
+			rules: []recorder.RedundantRule{{Title: "ヨルナイト"}},
+			sources: []source{
+				{content: "primary", probe: recorder.Probe{Duration: 32 * time.Minute, Discontinuities: 3}},
+				{content: "secondary", probe: recorder.Probe{Duration: 32*time.Minute - 1*time.Second}},
+			},
+			recEnd:      at(23, 31),
+			wantStatus:  program.StatusDone,
+			wantArchive: "secondary",
+			wantAttempts: []recorder.Attempt{
+				{Source: 0, Probe: recorder.Probe{Duration: 32 * time.Minute, Discontinuities: 3}},
+				{Source: 1, Probe: recorder.Probe{Duration: 32*time.Minute - 1*time.Second}, Chosen: true},
+			},
+		},
+		{
+			name:  "どちらも録画できなければ failed",
+			rules: []recorder.RedundantRule{{Station: program.StationAgqr}},
+			sources: []source{
+				{err: errFfmpeg},
+				{err: errFfmpeg},
+			},
+			recEnd:     at(23, 31),
+			wantStatus: program.StatusFailed,
+			wantAttempts: []recorder.Attempt{
+				{Source: 0, Error: errFfmpeg.Error()},
+				{Source: 1, Error: errFfmpeg.Error()},
+			},
+		},
+		{
+			name:  "どの配信元も番組全体を録画できていなければ failed",
+			rules: []recorder.RedundantRule{{Station: program.StationAgqr}},
+			sources: []source{
+				{content: "primary", probe: recorder.Probe{Duration: 25 * time.Minute}},
+				{content: "secondary", probe: recorder.Probe{Duration: 31 * time.Minute}},
+			},
+			recEnd:     at(23, 31),
+			wantStatus: program.StatusFailed,
+			wantAttempts: []recorder.Attempt{
+				{Source: 0, Probe: recorder.Probe{Duration: 25 * time.Minute}},
+				{Source: 1, Probe: recorder.Probe{Duration: 31 * time.Minute}},
+			},
+		},
+		{
+			name:  "どの配信元も途中で失敗すれば、残りの時間を録画しなおして結合する",
+			rules: []recorder.RedundantRule{{Station: program.StationAgqr}},
+			sources: []source{
+				{content: "primary", err: errFfmpeg, probe: recorder.Probe{Duration: 11 * time.Minute}},
+				{content: "secondary", err: errFfmpeg, probe: recorder.Probe{Duration: 10 * time.Minute}},
+			},
+			recEnd: at(23, 10),
+			prepareResume: func(station *mock_repository.MockResumableStation, clk *clock.Fake, config recorder.Config) {
+				gomock.InOrder(
+					station.EXPECT().RecPart(gomock.Any(), config, pgram, 0, 21*time.Minute).DoAndReturn(func(context.Context, recorder.Config, program.Program, int, time.Duration) error {
+						clk.Set(at(23, 31))
+						return nil
+					}),
+					station.EXPECT().ConcatParts(gomock.Any(), config, pgram, []recorder.Part{
+						{Index: 0, Start: at(23, 10), End: at(23, 31)},
+					}).Return(nil),
+				)
+			},
+			wantStatus: program.StatusDone,
+			wantAttempts: []recorder.Attempt{
+				{Source: 0, Probe: recorder.Probe{Duration: 11 * time.Minute}, Error: errFfmpeg.Error()},
+				{Source: 1, Probe: recorder.Probe{Duration: 10 * time.Minute}, Error: errFfmpeg.Error()},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dir := t.TempDir()
+			config := recorder.Config{ArchiveDir: dir, Margin: 1 * time.Minute, RedundantRules: tt.rules}
+			archive := filepath.Join(dir, "file.ts")
+
+			programPersistence := memory.New()
+			err := programPersistence.Save(ctx, pgram)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clk := clock.NewFake(pgram.Start.Add(-config.Margin))
+			mockAgqr := mock_repository.NewMockRedundantStation(ctrl)
+			mockAgqr.EXPECT().Sources().Return(len(tt.sources)).AnyTimes()
+			mockAgqr.EXPECT().ArchiveFilePath(config, pgram).Return(archive).AnyTimes()
+			for i, src := range tt.sources {
+				i, src := i, src
+				file := filepath.Join(dir, fmt.Sprintf("file.source%d.ts", i))
+				mockAgqr.EXPECT().SourceFilePath(config, pgram, i).Return(file).AnyTimes()
+				mockAgqr.EXPECT().RecSource(gomock.Any(), config, pgram, i).DoAndReturn(func(context.Context, recorder.Config, program.Program, int) error {
+					if src.content != "" {
+						if err := os.WriteFile(file, []byte(src.content), 0644); err != nil {
+							t.Error(err)
+						}
+					}
+					clk.Set(tt.recEnd)
+					return src.err
+				})
+				if src.content != "" {
+					mockAgqr.EXPECT().Probe(gomock.Any(), file).Return(src.probe, nil)
+				}
+			}
+
+			r := &ucRecorder{
+				programPersistence: programPersistence,
+				agqr:               mockAgqr,
+				clock:              clk,
+			}
+			if tt.prepareResume != nil {
+				mockResumable := mock_repository.NewMockResumableStation(ctrl)
+				tt.prepareResume(mockResumable, clk, config)
+				r.agqr = redundantResumableStation{MockRedundantStation: mockAgqr, resumable: mockResumable}
+			}
+			r.rec(ctx, config, pgram.Start.Add(-config.Margin), pgram)
+
+			got, err := programPersistence.LoadByUUID(ctx, pgram.UUID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if tt.wantArchive != "" {
+				content, err := os.ReadFile(archive)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(content) != tt.wantArchive {
+					t.Errorf("archive = %q, want %q", content, tt.wantArchive)
+				}
+				// 残さなかった配信元のファイルは削除する
+				matches, _ := filepath.Glob(filepath.Join(dir, "*.source*.ts"))
+				if len(matches) != 0 {
+					t.Errorf("source files are not removed: %v", matches)
+				}
+			}
+
+			attempts, err := programPersistence.LoadAttempts(ctx, pgram.UUID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range attempts {
+				if attempts[i].Chosen && attempts[i].FilePath != archive {
+					t.Errorf("chosen FilePath = %s, want %s", attempts[i].FilePath, archive)
+				}
+				attempts[i].FilePath = ""
+				attempts[i].ProgramUUID = ""
+			}
+			if diff := cmp.Diff(tt.wantAttempts, attempts); diff != "" {
+				t.Errorf("attempts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/metrics"
+)
+
+// 録画しなおすまでに待つ時間の上限
+const resumeBackoffMax = 1 * time.Minute
+
+// broadcast な番組を End + Margin まで録画する
+// ffmpeg が途中で失敗すれば、ResumeBackoff だけ待ってから残りの時間だけ別の部分ファイルに録画しなおす
+// End + Margin を過ぎるかリトライの上限に達すれば、それまでに録画できた部分ファイルを結合する
+// 部分ファイルの間や最後の欠落は ConcatParts によってメタデータに記録される
+// 欠落の合計が MaxGap を超えていれば、結合したファイルは残してエラーを返す
+// 返されるエラー
+// - errutil.ErrRecFailed
+// - ConcatParts が返すもの（何も録画できていなかったときなど）
+func (r *ucRecorder) recParts(ctx context.Context, config recorder.Config, station repository.ResumableStation, targetPgram program.Program) error {
+	until := targetPgram.End.Add(config.Margin)
+	var parts []recorder.Part
+	for retryCount := 0; retryCount <= config.RetryMaxCount; retryCount++ {
+		if retryCount > 0 {
+			backoff := resumeBackoff(config, retryCount)
+			if !r.clock.Now().Add(backoff).Before(until) {
+				break
+			}
+			err := r.clock.Sleep(ctx, backoff)
+			if err != nil {
+				log.Ctx(ctx).Warn().Msgf("canceled while waiting to resume rec (program = %+v): %+v", targetPgram, err)
+				break
+			}
+		}
+
+		start := r.clock.Now()
+		remaining := until.Sub(start)
+		if remaining <= 0 {
+			break
+		}
+
+		index := len(parts)
+		metrics.FfmpegActive.Inc()
+		err := station.RecPart(ctx, config, targetPgram, index, remaining)
+		metrics.FfmpegActive.Dec()
+		parts = append(parts, recorder.Part{Index: index, Start: start, End: r.clock.Now()})
+		if err == nil {
+			break
+		}
+
+		log.Ctx(ctx).Warn().Msgf("failed to rec (retryCount = %d, part = %d): %+v", retryCount, index, err)
+	}
+	if len(parts) == 0 {
+		return pkgerrors.Wrapf(errutil.ErrRecFailed, "program already ended (program = %+v)", targetPgram)
+	}
+
+	var totalGap time.Duration
+	for _, gap := range recorder.Gaps(parts, until) {
+		log.Ctx(ctx).Warn().Msgf("rec has gap %s - %s (%s) (program = %+v)", gap.Start, gap.End, gap.Duration(), targetPgram)
+		totalGap += gap.Duration()
+	}
+	err := station.ConcatParts(ctx, config, targetPgram, parts)
+	if err != nil {
+		return err
+	}
+	if config.MaxGap > 0 && totalGap > config.MaxGap {
+		return pkgerrors.Wrapf(errutil.ErrRecFailed, "rec has too much gap (total = %s, max = %s, file = %s)", totalGap, config.MaxGap, station.ArchiveFilePath(config, targetPgram))
+	}
+	return nil
+}
+
+// retryCount 回目に録画しなおすまでに待つ時間
+// ResumeBackoff から失敗する度に倍にし、resumeBackoffMax を上限とする
+func resumeBackoff(config recorder.Config, retryCount int) time.Duration {
+	backoff := config.ResumeBackoff
+	for i := 1; i < retryCount && backoff < resumeBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > resumeBackoffMax {
+		return resumeBackoffMax
+	}
+	return backoff
+}
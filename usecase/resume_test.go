@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func Test_ucRecorder_rec_resume(t *testing.T) {
+	pgram := newAgqrBroadcast("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", "鷲崎健のヨルナイト×ヨルナイト", time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()), 30*time.Minute)
+	configCommon := recorder.Config{
+		ArchiveDir:    "/archive",
+		Margin:        1 * time.Minute,
+		RetryMaxCount: 3,
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2022, 8, 17, hour, min, 0, 0, timeutil.LocationJST())
+	}
+	errFfmpeg := errors.Wrap(errutil.ErrFfmpeg, "connection reset")
+
+	type fields struct {
+		station *mock_repository.MockResumableStation
+		clock   *clock.Fake
+	}
+	// ffmpeg が end まで動いて返ってくる
+	recUntil := func(f *fields, end time.Time, err error) func(context.Context, recorder.Config, program.Program, int, time.Duration) error {
+		return func(context.Context, recorder.Config, program.Program, int, time.Duration) error {
+			f.clock.Set(end)
+			return err
+		}
+	}
+
+	tests := []struct {
+		name       string
+		config     func(c *recorder.Config)
+		prepare    func(f *fields, config recorder.Config)
+		wantStatus program.Status
+	}{
+		{
+			name:   "途中で失敗すれば残りの時間だけ別のファイルに録画し、最後に結合する",
+			config: func(c *recorder.Config) {},
+			prepare: func(f *fields, config recorder.Config) {
+				gomock.InOrder(
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 0, 32*time.Minute).DoAndReturn(recUntil(f, at(23, 20), errFfmpeg)),
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 1, 11*time.Minute).DoAndReturn(recUntil(f, at(23, 31), nil)),
+					f.station.EXPECT().ConcatParts(gomock.Any(), config, pgram, []recorder.Part{
+						{Index: 0, Start: at(22, 59), End: at(23, 20)},
+						{Index: 1, Start: at(23, 20), End: at(23, 31)},
+					}).Return(nil),
+					f.station.EXPECT().ArchiveFilePath(config, pgram).Return("/archive/agqr/file.ts"),
+				)
+			},
+			wantStatus: program.StatusDone,
+		},
+		{
+			name: "リトライの上限を超えれば録画できた部分だけを結合する",
+			config: func(c *recorder.Config) {
+				c.RetryMaxCount = 1
+			},
+			prepare: func(f *fields, config recorder.Config) {
+				gomock.InOrder(
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 0, 32*time.Minute).DoAndReturn(recUntil(f, at(23, 10), errFfmpeg)),
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 1, 21*time.Minute).DoAndReturn(recUntil(f, at(23, 20), errFfmpeg)),
+					f.station.EXPECT().ConcatParts(gomock.Any(), config, pgram, []recorder.Part{
+						{Index: 0, Start: at(22, 59), End: at(23, 10)},
+						{Index: 1, Start: at(23, 10), End: at(23, 20)},
+					}).Return(nil),
+					f.station.EXPECT().ArchiveFilePath(config, pgram).Return("/archive/agqr/file.ts"),
+				)
+			},
+			wantStatus: program.StatusDone,
+		},
+		{
+			name:   "番組が終わっていれば録画しなおさず、録画できた部分を結合する",
+			config: func(c *recorder.Config) {},
+			prepare: func(f *fields, config recorder.Config) {
+				gomock.InOrder(
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 0, 32*time.Minute).DoAndReturn(recUntil(f, at(23, 31), errFfmpeg)),
+					f.station.EXPECT().ConcatParts(gomock.Any(), config, pgram, []recorder.Part{
+						{Index: 0, Start: at(22, 59), End: at(23, 31)},
+					}).Return(nil),
+					f.station.EXPECT().ArchiveFilePath(config, pgram).Return("/archive/agqr/file.ts"),
+				)
+			},
+			wantStatus: program.StatusDone,
+		},
+		{
+			name: "録画しなおす前に ResumeBackoff だけ待つ",
+			config: func(c *recorder.Config) {
+				c.ResumeBackoff = 10 * time.Second
+			},
+			prepare: func(f *fields, config recorder.Config) {
+				gomock.InOrder(
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 0, 32*time.Minute).DoAndReturn(func(context.Context, recorder.Config, program.Program, int, time.Duration) error {
+						f.clock.Set(at(23, 20))
+						go func() {
+							f.clock.BlockUntil(1)
+							f.clock.Advance(10 * time.Second)
+						}()
+						return errFfmpeg
+					}),
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 1, 10*time.Minute+50*time.Second).DoAndReturn(recUntil(f, at(23, 31), nil)),
+					f.station.EXPECT().ConcatParts(gomock.Any(), config, pgram, []recorder.Part{
+						{Index: 0, Start: at(22, 59), End: at(23, 20)},
+						{Index: 1, Start: at(23, 20).Add(10 * time.Second), End: at(23, 31)},
+					}).Return(nil),
+					f.station.EXPECT().ArchiveFilePath(config, pgram).Return("/archive/agqr/file.ts"),
+				)
+			},
+			wantStatus: program.StatusDone,
+		},
+		{
+			name: "欠落の合計が MaxGap を超えれば、結合したファイルは残して failed",
+			config: func(c *recorder.Config) {
+				c.RetryMaxCount = 1
+				c.MaxGap = 5 * time.Minute
+			},
+			prepare: func(f *fields, config recorder.Config) {
+				gomock.InOrder(
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 0, 32*time.Minute).DoAndReturn(recUntil(f, at(23, 10), errFfmpeg)),
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 1, 21*time.Minute).DoAndReturn(recUntil(f, at(23, 20), errFfmpeg)),
+					f.station.EXPECT().ConcatParts(gomock.Any(), config, pgram, []recorder.Part{
+						{Index: 0, Start: at(22, 59), End: at(23, 10)},
+						{Index: 1, Start: at(23, 10), End: at(23, 20)},
+					}).Return(nil),
+					f.station.EXPECT().ArchiveFilePath(config, pgram).Return("/archive/agqr/file.ts"),
+				)
+			},
+			wantStatus: program.StatusFailed,
+		},
+		{
+			name: "何も録画できていなければ failed",
+			config: func(c *recorder.Config) {
+				c.RetryMaxCount = 0
+			},
+			prepare: func(f *fields, config recorder.Config) {
+				gomock.InOrder(
+					f.station.EXPECT().RecPart(gomock.Any(), config, pgram, 0, 32*time.Minute).DoAndReturn(recUntil(f, at(22, 59), errFfmpeg)),
+					f.station.EXPECT().ConcatParts(gomock.Any(), config, pgram, []recorder.Part{
+						{Index: 0, Start: at(22, 59), End: at(22, 59)},
+					}).Return(errors.Wrap(errutil.ErrFfmpeg, "no part is recorded")),
+				)
+			},
+			wantStatus: program.StatusFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			config := configCommon
+			tt.config(&config)
+
+			programPersistence := memory.New()
+			err := programPersistence.Save(ctx, pgram)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := &fields{
+				station: mock_repository.NewMockResumableStation(ctrl),
+				clock:   clock.NewFake(pgram.Start.Add(-config.Margin)),
+			}
+			tt.prepare(f, config)
+
+			r := &ucRecorder{
+				programPersistence: programPersistence,
+				agqr:               f.station,
+				clock:              f.clock,
+			}
+			r.rec(ctx, config, pgram.Start.Add(-config.Margin), pgram)
+
+			got, err := programPersistence.LoadByUUID(ctx, pgram.UUID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_resumeBackoff(t *testing.T) {
+	config := recorder.Config{ResumeBackoff: 5 * time.Second}
+	tests := []struct {
+		name       string
+		retryCount int
+		want       time.Duration
+	}{
+		{
+			name:       "1 回目は ResumeBackoff",
+			retryCount: 1,
+			want:       5 * time.Second,
+		},
+		{
+			name:       "失敗する度に倍にする",
+			retryCount: 3,
+			want:       20 * time.Second,
+		},
+		{
+			name:       "上限を超えない",
+			retryCount: 10,
+			want:       resumeBackoffMax,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resumeBackoff(config, tt.retryCount); got != tt.want {
+				t.Errorf("resumeBackoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
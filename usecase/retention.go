@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/retention"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+)
+
+type ucRetention struct {
+	programPersistence repository.ProgramPersistence
+
+	// ファイルサイズ（byte）を返す
+	fileSize func(path string) (int64, error)
+
+	removeFile func(path string) error
+}
+
+func NewRetention(programPersistence repository.ProgramPersistence) *ucRetention {
+	return &ucRetention{
+		programPersistence: programPersistence,
+		fileSize: func(path string) (int64, error) {
+			info, err := os.Stat(path)
+			if err != nil {
+				return 0, err
+			}
+			return info.Size(), nil
+		},
+		removeFile: os.Remove,
+	}
+}
+
+// ファイルを削除せず、削除するはずだったファイルをログに出力する
+func NewRetentionDryRun(programPersistence repository.ProgramPersistence) *ucRetention {
+	r := NewRetention(programPersistence)
+	r.removeFile = func(path string) error {
+		log.Info().Msgf("dry-run: would remove file %s", path)
+		return nil
+	}
+	return r
+}
+
+// rules に従って録画済みファイルを削除し、status を purged に変更する
+// オブジェクトストレージにアップロード済みの番組は file path を空にするだけで done のままにする
+// 各番組は rules のうち最初に該当したルールにのみ従う
+// protected な番組は削除しない
+func (r *ucRetention) Purge(ctx context.Context, rules []retention.Rule, now time.Time) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	pgrams, err := r.programPersistence.LoadDone(ctx)
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		log.Ctx(ctx).Debug().Msg("not found program")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	matched := make([][]program.Program, len(rules))
+	for _, pgram := range pgrams {
+		// ローカルにファイルがない（オブジェクトストレージにのみある）ものは対象外
+		if pgram.FilePath == "" {
+			continue
+		}
+		for i, rule := range rules {
+			if rule.Match(pgram) {
+				matched[i] = append(matched[i], pgram)
+				break
+			}
+		}
+	}
+
+	purgedCount := 0
+	for i, rule := range rules {
+		sizes := make(map[string]int64, len(matched[i]))
+		for _, pgram := range matched[i] {
+			size, err := r.fileSize(pgram.FilePath)
+			if err != nil && !os.IsNotExist(err) {
+				log.Ctx(ctx).Warn().Msgf("failed to get file size: %+v", err)
+			}
+			sizes[pgram.UUID] = size
+		}
+
+		for _, target := range selectPurgeTargets(rule, matched[i], sizes, now) {
+			err := r.removeFile(target.FilePath)
+			if err != nil && !os.IsNotExist(err) {
+				log.Ctx(ctx).Error().Msgf("failed to remove file (program = %+v): %+v", target, err)
+				continue
+			}
+
+			// オブジェクトストレージにもあれば、ローカルのファイルがなくなるだけなので done のままにする
+			if target.ObjectKey != "" {
+				err = r.programPersistence.ChangeFilePath(ctx, target, "")
+				if err != nil {
+					return err
+				}
+				log.Ctx(ctx).Info().Msgf("removed local file, kept in object storage (file = %s, key = %s)", target.FilePath, target.ObjectKey)
+				purgedCount++
+				continue
+			}
+
+			err = r.programPersistence.ChangeStatus(ctx, target, program.StatusPurged)
+			if err != nil {
+				return err
+			}
+			log.Ctx(ctx).Info().Msgf("purged (file = %s)", target.FilePath)
+			purgedCount++
+		}
+	}
+
+	log.Ctx(ctx).Info().Msgf("successfully purge programs (count = %d)", purgedCount)
+	return nil
+}
+
+// retention より前に始めた録画のログをファイルとともに削除する
+// retention が 0 であれば何もしない
+func (r *ucRetention) PurgeRecordingLogs(ctx context.Context, retention time.Duration, now time.Time) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	recLogs, err := r.programPersistence.DeleteRecordingLogsBefore(ctx, now.Add(-retention))
+	if err != nil {
+		return err
+	}
+	// まとめて録画した番組は同じファイルを参照している
+	removed := make(map[string]bool)
+	for _, recLog := range recLogs {
+		if removed[recLog.FilePath] {
+			continue
+		}
+		removed[recLog.FilePath] = true
+		err := r.removeFile(recLog.FilePath)
+		if err != nil && !os.IsNotExist(err) {
+			log.Ctx(ctx).Warn().Msgf("failed to remove recording log: %+v", err)
+		}
+	}
+
+	log.Ctx(ctx).Info().Msgf("successfully purge recording logs (count = %d)", len(removed))
+	return nil
+}
+
+// rule に該当する pgrams のうち、削除すべきものを番組開始日時の古い順に返す
+// sizes は UUID -> ファイルサイズ
+func selectPurgeTargets(rule retention.Rule, pgrams []program.Program, sizes map[string]int64, now time.Time) []program.Program {
+	sorted := make([]program.Program, len(pgrams))
+	copy(sorted, pgrams)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	purge := make(map[string]bool)
+
+	if rule.MaxAge > 0 {
+		for _, pgram := range sorted {
+			if now.Sub(pgram.Start) >= rule.MaxAge {
+				purge[pgram.UUID] = true
+			}
+		}
+	}
+
+	if rule.KeepLast > 0 {
+		kept := make(map[string]int)
+		for i := len(sorted) - 1; i >= 0; i-- {
+			pgram := sorted[i]
+			if kept[pgram.Title] < rule.KeepLast {
+				kept[pgram.Title]++
+				continue
+			}
+			purge[pgram.UUID] = true
+		}
+	}
+
+	if rule.MaxTotalSize > 0 {
+		var total int64
+		for _, pgram := range sorted {
+			if !purge[pgram.UUID] || pgram.Protected {
+				total += sizes[pgram.UUID]
+			}
+		}
+		for _, pgram := range sorted {
+			if total <= rule.MaxTotalSize {
+				break
+			}
+			if purge[pgram.UUID] || pgram.Protected {
+				continue
+			}
+			purge[pgram.UUID] = true
+			total -= sizes[pgram.UUID]
+		}
+	}
+
+	var targets []program.Program
+	for _, pgram := range sorted {
+		if purge[pgram.UUID] && !pgram.Protected {
+			targets = append(targets, pgram)
+		}
+	}
+	return targets
+}
@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/domain/model/retention"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func retentionTestPrograms() []program.Program {
+	return []program.Program{
+		{
+			UUID:     "e07df7c6-eae8-40f8-8922-6b7ef0497dc8",
+			ID:       11132,
+			Station:  program.StationOnsen,
+			Title:    "セブン-イレブン presents 佐倉としたい大西",
+			Episode:  "第332回",
+			Start:    time.Date(2022, 8, 9, 0, 0, 0, 0, timeutil.LocationJST()),
+			Status:   program.StatusDone,
+			FilePath: "/archive/onsen/332.ts",
+		},
+		{
+			UUID:     "4ba3b9ff-5e0b-44ae-a99d-6dfb27deac0e",
+			ID:       11133,
+			Station:  program.StationOnsen,
+			Title:    "セブン-イレブン presents 佐倉としたい大西",
+			Episode:  "第333回",
+			Start:    time.Date(2022, 8, 16, 0, 0, 0, 0, timeutil.LocationJST()),
+			Status:   program.StatusDone,
+			FilePath: "/archive/onsen/333.ts",
+		},
+		{
+			UUID:     "89350da4-7f3b-4438-b99f-41ae9aa52bf5",
+			ID:       11134,
+			Station:  program.StationOnsen,
+			Title:    "セブン-イレブン presents 佐倉としたい大西",
+			Episode:  "第334回",
+			Start:    time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+			Status:   program.StatusDone,
+			FilePath: "/archive/onsen/334.ts",
+		},
+	}
+}
+
+func Test_selectPurgeTargets(t *testing.T) {
+	now := time.Date(2022, 8, 30, 0, 0, 0, 0, timeutil.LocationJST())
+	pgrams := retentionTestPrograms()
+	pgramsProtected := retentionTestPrograms()
+	pgramsProtected[0].Protected = true
+
+	sizes := map[string]int64{
+		pgrams[0].UUID: 100,
+		pgrams[1].UUID: 100,
+		pgrams[2].UUID: 100,
+	}
+
+	type args struct {
+		rule   retention.Rule
+		pgrams []program.Program
+	}
+	tests := []struct {
+		name string
+		args args
+		want []program.Program
+	}{
+		{
+			name: "条件がなければ何も削除しない",
+			args: args{
+				rule:   retention.Rule{},
+				pgrams: pgrams,
+			},
+			want: nil,
+		},
+		{
+			name: "KeepLast 件だけ新しいものを残す",
+			args: args{
+				rule:   retention.Rule{KeepLast: 2},
+				pgrams: pgrams,
+			},
+			want: pgrams[:1],
+		},
+		{
+			name: "MaxAge 以上経過したものを削除する",
+			args: args{
+				rule:   retention.Rule{MaxAge: 14 * 24 * time.Hour},
+				pgrams: pgrams,
+			},
+			want: pgrams[:2],
+		},
+		{
+			name: "MaxTotalSize 以下になるまで古いものから削除する",
+			args: args{
+				rule:   retention.Rule{MaxTotalSize: 150},
+				pgrams: pgrams,
+			},
+			want: pgrams[:2],
+		},
+		{
+			name: "protected は削除しない",
+			args: args{
+				rule:   retention.Rule{KeepLast: 1},
+				pgrams: pgramsProtected,
+			},
+			want: pgramsProtected[1:2],
+		},
+		{
+			name: "protected は削除しないが合計サイズには含める",
+			args: args{
+				rule:   retention.Rule{MaxTotalSize: 150},
+				pgrams: pgramsProtected,
+			},
+			want: pgramsProtected[1:3],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectPurgeTargets(tt.args.rule, tt.args.pgrams, sizes, now)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("selectPurgeTargets() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ucRetention_Purge(t *testing.T) {
+	now := time.Date(2022, 8, 30, 0, 0, 0, 0, timeutil.LocationJST())
+	pgrams := retentionTestPrograms()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+	mockProgramPersistence.EXPECT().
+		LoadDone(gomock.Any()).
+		Return(pgrams, nil)
+	mockProgramPersistence.EXPECT().
+		ChangeStatus(gomock.Any(), pgrams[0], program.StatusPurged).
+		Return(nil)
+
+	var removed []string
+	r := &ucRetention{
+		programPersistence: mockProgramPersistence,
+		fileSize:           func(string) (int64, error) { return 100, nil },
+		removeFile: func(path string) error {
+			removed = append(removed, path)
+			return nil
+		},
+	}
+
+	rules := []retention.Rule{
+		// 先に該当したルールが優先される
+		{Station: program.StationOnsen, Title: "佐倉としたい大西", KeepLast: 2},
+		{Station: program.StationOnsen, KeepLast: 1},
+	}
+	err := r.Purge(context.Background(), rules, now)
+	if err != nil {
+		t.Fatalf("ucRetention.Purge() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"/archive/onsen/332.ts"}, removed); diff != "" {
+		t.Errorf("ucRetention.Purge() removed mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_ucRetention_Purge_uploaded(t *testing.T) {
+	now := time.Date(2022, 8, 30, 0, 0, 0, 0, timeutil.LocationJST())
+	pgrams := retentionTestPrograms()
+	pgrams[0].ObjectKey = "onsen/332.ts"
+	// アップロード後にローカルのファイルを削除したもの
+	pgrams[1].FilePath = ""
+	pgrams[1].ObjectKey = "onsen/333.ts"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+	mockProgramPersistence.EXPECT().
+		LoadDone(gomock.Any()).
+		Return(pgrams, nil)
+	// オブジェクトストレージにあるので purged にはしない
+	mockProgramPersistence.EXPECT().
+		ChangeFilePath(gomock.Any(), pgrams[0], "").
+		Return(nil)
+
+	var removed []string
+	r := &ucRetention{
+		programPersistence: mockProgramPersistence,
+		fileSize:           func(string) (int64, error) { return 100, nil },
+		removeFile: func(path string) error {
+			removed = append(removed, path)
+			return nil
+		},
+	}
+
+	rules := []retention.Rule{
+		{Station: program.StationOnsen, MaxAge: 10 * 24 * time.Hour},
+	}
+	err := r.Purge(context.Background(), rules, now)
+	if err != nil {
+		t.Fatalf("ucRetention.Purge() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"/archive/onsen/332.ts"}, removed); diff != "" {
+		t.Errorf("ucRetention.Purge() removed mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_ucRetention_PurgeRecordingLogs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 9, 17, 12, 0, 0, 0, timeutil.LocationJST())
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+	mockProgramPersistence.EXPECT().
+		DeleteRecordingLogsBefore(gomock.Any(), now.Add(-30*24*time.Hour)).
+		Return([]recorder.RecordingLog{
+			// まとめて録画した番組は同じファイル
+			{ProgramUUID: "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", FilePath: "/archive/agqr/capture_20220817-225900.log"},
+			{ProgramUUID: "0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10", FilePath: "/archive/agqr/capture_20220817-225900.log"},
+			{ProgramUUID: "e07df7c6-eae8-40f8-8922-6b7ef0497dc8", FilePath: "/archive/onsen/332_20220809-120000.log"},
+		}, nil)
+
+	var removed []string
+	r := &ucRetention{
+		programPersistence: mockProgramPersistence,
+		removeFile: func(path string) error {
+			removed = append(removed, path)
+			return nil
+		},
+	}
+	err := r.PurgeRecordingLogs(ctx, 30*24*time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/archive/agqr/capture_20220817-225900.log", "/archive/onsen/332_20220809-120000.log"}
+	if diff := cmp.Diff(want, removed); diff != "" {
+		t.Errorf("removed mismatch (-want +got):\n%s", diff)
+	}
+
+	// 0 であれば何もしない
+	err = r.PurgeRecordingLogs(ctx, 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/clock"
+)
+
+// broadcast な番組の録画開始を待つもの
+// 番組毎にタイマーを持ち、発火すれば fire を呼び出す
+// 待っている間であれば取り消しや開始日時の変更ができる
+type recScheduler struct {
+	clock clock.Clock
+	// タイマーが発火したときに呼び出す
+	fire func(ctx context.Context, config recorder.Config, startAt time.Time, pgram program.Program)
+
+	mu sync.Mutex
+	// key は番組の UUID
+	entries map[string]*schedulerEntry
+}
+
+type schedulerEntry struct {
+	scheduled recorder.Scheduled
+	config    recorder.Config
+	// fire に渡す ctx
+	ctx context.Context
+	// 閉じられれば発火せずに終了する
+	stop chan struct{}
+}
+
+func newRecScheduler(clk clock.Clock, fire func(ctx context.Context, config recorder.Config, startAt time.Time, pgram program.Program)) *recScheduler {
+	return &recScheduler{
+		clock:   clk,
+		fire:    fire,
+		entries: map[string]*schedulerEntry{},
+	}
+}
+
+// scheduled.At に録画を開始するよう登録する
+// 同じ番組が既に登録されていれば置き換える
+func (s *recScheduler) add(ctx context.Context, config recorder.Config, scheduled recorder.Scheduled) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addLocked(ctx, config, scheduled)
+}
+
+func (s *recScheduler) addLocked(ctx context.Context, config recorder.Config, scheduled recorder.Scheduled) {
+	if old, ok := s.entries[scheduled.Program.UUID]; ok {
+		close(old.stop)
+	}
+	e := &schedulerEntry{
+		scheduled: scheduled,
+		config:    config,
+		ctx:       ctx,
+		stop:      make(chan struct{}),
+	}
+	s.entries[scheduled.Program.UUID] = e
+
+	timer := s.clock.NewTimer(scheduled.At.Sub(s.clock.Now()))
+	go s.wait(e, timer)
+}
+
+func (s *recScheduler) wait(e *schedulerEntry, timer clock.Timer) {
+	defer timer.Stop()
+
+	select {
+	case <-e.stop:
+		return
+	case <-e.ctx.Done():
+		// status は scheduled のままなので、次に起動したときに登録しなおされる
+		log.Ctx(e.ctx).Info().Msgf("stop waiting for rec (program = %+v)", e.scheduled.Program)
+		s.remove(e)
+		return
+	case <-timer.C():
+	}
+
+	// 発火と同時に取り消されたり置き換えられたりしていれば何もしない
+	if !s.remove(e) {
+		return
+	}
+	s.fire(e.ctx, e.config, e.scheduled.At, e.scheduled.Program)
+}
+
+// e が登録されたままであれば取り除いて true を返す
+func (s *recScheduler) remove(e *schedulerEntry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries[e.scheduled.Program.UUID] != e {
+		return false
+	}
+	delete(s.entries, e.scheduled.Program.UUID)
+	return true
+}
+
+// 登録されていなければ false
+func (s *recScheduler) cancel(uuid string) (recorder.Scheduled, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[uuid]
+	if !ok {
+		return recorder.Scheduled{}, false
+	}
+	close(e.stop)
+	delete(s.entries, uuid)
+	return e.scheduled, true
+}
+
+// 登録済みの番組の開始日時を at に変更する
+// 登録されていなければ false
+func (s *recScheduler) reschedule(uuid string, at time.Time) (recorder.Scheduled, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[uuid]
+	if !ok {
+		return recorder.Scheduled{}, false
+	}
+
+	scheduled := e.scheduled
+	scheduled.At = at
+	scheduled.Rescheduled = true
+	s.addLocked(e.ctx, e.config, scheduled)
+	return scheduled, true
+}
+
+func (s *recScheduler) get(uuid string) (recorder.Scheduled, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[uuid]
+	if !ok {
+		return recorder.Scheduled{}, false
+	}
+	return e.scheduled, true
+}
+
+// 録画開始日時の昇順
+func (s *recScheduler) list() []recorder.Scheduled {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scheduled := make([]recorder.Scheduled, 0, len(s.entries))
+	for _, e := range s.entries {
+		scheduled = append(scheduled, e.scheduled)
+	}
+	sort.Slice(scheduled, func(i, j int) bool {
+		if !scheduled[i].At.Equal(scheduled[j].At) {
+			return scheduled[i].At.Before(scheduled[j].At)
+		}
+		return scheduled[i].Program.UUID < scheduled[j].Program.UUID
+	})
+	return scheduled
+}
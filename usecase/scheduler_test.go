@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/clock"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/testutil"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+type fired struct {
+	uuid string
+	at   time.Time
+}
+
+// データベースの変更や取り消し、開始日時の変更が scheduler に反映されること
+func Test_ucRecorder_RecBroadcastPrepare(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 8, 17, 22, 58, 0, 0, timeutil.LocationJST())
+	config := recorder.Config{
+		PrepareAfter: 40 * time.Minute,
+		Margin:       1 * time.Minute,
+	}
+	newBroadcast := func(uuid string, id int, title string, start time.Time) program.Program {
+		return program.Program{
+			UUID:       uuid,
+			ID:         id,
+			Station:    program.StationAgqr,
+			Title:      title,
+			Start:      start,
+			End:        start.Add(30 * time.Minute),
+			Status:     program.StatusScheduled,
+			StreamType: program.StreamTypeBroadcast,
+		}
+	}
+	pgramA := newBroadcast("b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a", 1660741200, "鷲崎健のヨルナイト×ヨルナイト", time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()))
+	pgramB := newBroadcast("0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10", 1660743000, "ハチャメチャ☆ライブ", time.Date(2022, 8, 17, 23, 30, 0, 0, timeutil.LocationJST()))
+	pgramC := newBroadcast("5c3a1f0e-2a7e-4b59-9d0c-6f7e2b8d1a44", 1660743900, "あしたのラジオ", time.Date(2022, 8, 17, 23, 15, 0, 0, timeutil.LocationJST()))
+
+	programPersistence := memory.New()
+	for _, pgram := range []program.Program{pgramA, pgramB, pgramC} {
+		err := programPersistence.Save(ctx, pgram)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockOnsen := mock_repository.NewMockStation(ctrl)
+	mockAgqr := mock_repository.NewMockStation(ctrl)
+
+	fakeClock := clock.NewFake(now)
+	firedCh := make(chan fired, 3)
+	r := &ucRecorder{
+		programPersistence: programPersistence,
+		onsen:              mockOnsen,
+		agqr:               mockAgqr,
+		clock:              fakeClock,
+	}
+	r.scheduler = newRecScheduler(fakeClock, func(ctx context.Context, config recorder.Config, startAt time.Time, pgram program.Program) {
+		firedCh <- fired{uuid: pgram.UUID, at: fakeClock.Now()}
+	})
+
+	prepare := func() {
+		t.Helper()
+		err := r.RecBroadcastPrepare(ctx, config, fakeClock.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertScheduled := func(want []recorder.Scheduled) {
+		t.Helper()
+		if diff := cmp.Diff(want, r.Scheduled()); diff != "" {
+			t.Errorf("ucRecorder.Scheduled() mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	// Start - Margin に録画を開始するよう登録される
+	prepare()
+	assertScheduled([]recorder.Scheduled{
+		{Program: pgramA, At: time.Date(2022, 8, 17, 22, 59, 0, 0, timeutil.LocationJST())},
+		{Program: pgramC, At: time.Date(2022, 8, 17, 23, 14, 0, 0, timeutil.LocationJST())},
+		{Program: pgramB, At: time.Date(2022, 8, 17, 23, 29, 0, 0, timeutil.LocationJST())},
+	})
+
+	// 取り消せば skipped になり、再び登録されることはない
+	_, err := r.CancelScheduled(ctx, pgramB.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := programPersistence.LoadByUUID(ctx, pgramB.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != program.StatusSkipped {
+		t.Errorf("status = %s, want %s", got.Status, program.StatusSkipped)
+	}
+	_, err = r.CancelScheduled(ctx, pgramB.UUID)
+	if !testutil.ErrorsAs(err, errutil.ErrNotScheduled) {
+		t.Errorf("ucRecorder.CancelScheduled() error = %v, wantErr %v", err, errutil.ErrNotScheduled)
+	}
+
+	// 手動で変更した開始日時は RecBroadcastPrepare で上書きされない
+	rescheduledAt := time.Date(2022, 8, 17, 23, 5, 0, 0, timeutil.LocationJST())
+	_, err = r.Reschedule(pgramA.UUID, rescheduledAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 番組表の変更で開始日時が変われば登録しなおされる
+	// 番組表から取得した番組の UUID は取得する度に変わる
+	movedC := pgramC
+	movedC.Start = pgramC.Start.Add(10 * time.Minute)
+	movedC.End = pgramC.End.Add(10 * time.Minute)
+	guideC := movedC
+	guideC.UUID = "9f1e2d3c-4b5a-4978-8a6b-5c4d3e2f1a0b"
+	mockOnsen.EXPECT().GetPrograms(gomock.Any(), gomock.Any()).Return(nil, nil)
+	mockAgqr.EXPECT().GetPrograms(gomock.Any(), gomock.Any()).Return([]program.Program{pgramA, guideC}, nil)
+	err = r.UpdateProgram(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prepare()
+	assertScheduled([]recorder.Scheduled{
+		{Program: pgramA, At: rescheduledAt, Rescheduled: true},
+		{Program: movedC, At: time.Date(2022, 8, 17, 23, 24, 0, 0, timeutil.LocationJST())},
+	})
+
+	// 変更後の開始日時まで録画は始まらない
+	fakeClock.Set(rescheduledAt.Add(-1 * time.Second))
+	select {
+	case f := <-firedCh:
+		t.Fatalf("rec started too early (%+v)", f)
+	default:
+	}
+	fakeClock.Advance(1 * time.Second)
+	if f := <-firedCh; f != (fired{uuid: pgramA.UUID, at: rescheduledAt}) {
+		t.Errorf("fired = %+v, want %s at %v", f, pgramA.UUID, rescheduledAt)
+	}
+
+	// データベース側で scheduled でなくなれば取り消される
+	err = programPersistence.ChangeStatus(ctx, movedC, program.StatusSkipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepare()
+	assertScheduled([]recorder.Scheduled{})
+
+	fakeClock.Advance(1 * time.Hour)
+	select {
+	case f := <-firedCh:
+		t.Errorf("canceled program is fired (%+v)", f)
+	default:
+	}
+}
+
+func Test_recScheduler_ctxCanceled(t *testing.T) {
+	now := time.Date(2022, 8, 17, 22, 58, 0, 0, timeutil.LocationJST())
+	fakeClock := clock.NewFake(now)
+	s := newRecScheduler(fakeClock, func(ctx context.Context, config recorder.Config, startAt time.Time, pgram program.Program) {
+		t.Errorf("fired after ctx canceled (program = %+v)", pgram)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.add(ctx, recorder.Config{}, recorder.Scheduled{
+		Program: program.Program{UUID: "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a"},
+		At:      now.Add(1 * time.Minute),
+	})
+	fakeClock.BlockUntil(1)
+	cancel()
+
+	// 待つのをやめればタイマーは止まる
+	for {
+		if len(s.list()) == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	fakeClock.Advance(1 * time.Hour)
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/repository"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/programio"
+)
+
+// 番組データベースの書き出しと読み込み
+type ucTransfer struct {
+	programPersistence repository.ProgramPersistence
+}
+
+func NewTransfer(programPersistence repository.ProgramPersistence) *ucTransfer {
+	return &ucTransfer{
+		programPersistence: programPersistence,
+	}
+}
+
+// filter に該当する番組を冗長録画の結果とともに start の昇順で返す
+func (t *ucTransfer) Export(ctx context.Context, filter program.Filter) ([]programio.Record, error) {
+	pgrams, err := t.programPersistence.Load(ctx, filter)
+	if errors.Is(err, errutil.ErrDatabaseNotFoundProgram) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []programio.Record
+	for _, pgram := range pgrams {
+		attempts, err := t.programPersistence.LoadAttempts(ctx, pgram.UUID)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, programio.Record{Program: pgram, Attempts: attempts})
+	}
+	return records, nil
+}
+
+// station と id が同じ番組があれば置き換え、なければ追加する
+// 既にある番組の UUID は変えず、UUID が空の番組には新しく振る
+// 冗長録画の結果は、records に含まれていれば置き換える
+// 途中で失敗すると、それまでの番組は保存されたままとなる
+func (t *ucTransfer) Import(ctx context.Context, records []programio.Record) (created int, updated int, err error) {
+	for _, record := range records {
+		pgram := record.Program
+		if pgram.UUID == "" {
+			// panic 許容
+			pgram.UUID = uuid.NewString()
+		}
+
+		stored, isCreated, err := t.programPersistence.Upsert(ctx, pgram)
+		if err != nil {
+			return created, updated, pkgerrors.Wrapf(err, "import %s/%d", pgram.Station, pgram.ID)
+		}
+		if isCreated {
+			created++
+		} else {
+			updated++
+		}
+
+		if len(record.Attempts) == 0 {
+			continue
+		}
+		for i := range record.Attempts {
+			record.Attempts[i].ProgramUUID = stored.UUID
+		}
+		err = t.programPersistence.SaveAttempts(ctx, stored, record.Attempts)
+		if err != nil {
+			return created, updated, pkgerrors.Wrapf(err, "import attempts of %s/%d", pgram.Station, pgram.ID)
+		}
+	}
+	return created, updated, nil
+}
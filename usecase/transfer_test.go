@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/infrastructures/memory"
+	"github.com/sobadon/anrd/internal/programio"
+	"github.com/sobadon/anrd/internal/timeutil"
+)
+
+func Test_ucTransfer_Import(t *testing.T) {
+	ctx := context.Background()
+
+	stored := program.Program{
+		UUID:       "b0b7c4f1-51a4-4b0a-9b76-0c0d7a6e0a8a",
+		ID:         1650291,
+		Station:    program.StationAgqr,
+		Title:      "鷲崎健のヨルナイト×ヨルナイト",
+		Start:      time.Date(2022, 8, 17, 23, 0, 0, 0, timeutil.LocationJST()),
+		End:        time.Date(2022, 8, 18, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusFailed,
+		StreamType: program.StreamTypeBroadcast,
+	}
+	programPersistence := memory.New()
+	err := programPersistence.Save(ctx, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 別のマシンで振られた UUID
+	done := stored
+	done.UUID = "0e7d5e58-5bd1-4d7c-a8d4-8b3b2b7c6f10"
+	done.Status = program.StatusDone
+	done.FilePath = "/archive/agqr/yorunight.ts"
+	ondemand := program.Program{
+		ID:         11134,
+		Station:    program.StationOnsen,
+		Title:      "セブン-イレブン presents 佐倉としたい大西",
+		Episode:    "第334回",
+		Start:      time.Date(2022, 8, 23, 0, 0, 0, 0, timeutil.LocationJST()),
+		Status:     program.StatusScheduled,
+		StreamType: program.StreamTypeOndemand,
+	}
+
+	tr := NewTransfer(programPersistence)
+	created, updated, err := tr.Import(ctx, []programio.Record{
+		{
+			Program: done,
+			Attempts: []recorder.Attempt{
+				{ProgramUUID: done.UUID, Source: 0, FilePath: done.FilePath, Probe: recorder.Probe{Duration: time.Hour}, Chosen: true},
+			},
+		},
+		{Program: ondemand},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || updated != 1 {
+		t.Errorf("Import() = (%d, %d), want (1, 1)", created, updated)
+	}
+
+	got, err := tr.Export(ctx, program.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Export() = %+v, want 2 records", got)
+	}
+
+	// UUID は既にあるものを使う
+	wantDone := done
+	wantDone.UUID = stored.UUID
+	want := programio.Record{
+		Program: wantDone,
+		Attempts: []recorder.Attempt{
+			{ProgramUUID: stored.UUID, Source: 0, FilePath: done.FilePath, Probe: recorder.Probe{Duration: time.Hour}, Chosen: true},
+		},
+	}
+	if diff := cmp.Diff(want, got[0]); diff != "" {
+		t.Errorf("Export() mismatch (-want +got):\n%s", diff)
+	}
+
+	// UUID が空であれば振る
+	if got[1].Program.UUID == "" {
+		t.Errorf("Export() uuid of ondemand is empty")
+	}
+	ondemand.UUID = got[1].Program.UUID
+	if diff := cmp.Diff(programio.Record{Program: ondemand}, got[1]); diff != "" {
+		t.Errorf("Export() mismatch (-want +got):\n%s", diff)
+	}
+}
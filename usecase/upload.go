@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/errutil"
+	"github.com/sobadon/anrd/internal/fileutil"
+)
+
+// object key のテンプレートに渡す値
+// 例: {{.Station}}/{{.Start.Format "2006-01-02"}}/{{.FileName}}
+type objectKeyParams struct {
+	UUID    string
+	ID      int
+	Station string
+	// ファイル名に使えるよう置換済み
+	Title string
+	// ファイル名に使えるよう置換済み
+	Episode string
+	Start   time.Time
+	// 録画済みファイルのファイル名
+	FileName string
+}
+
+func buildObjectKey(keyTemplate string, pgram program.Program) (string, error) {
+	tmpl, err := template.New("object_key").Option("missingkey=error").Parse(keyTemplate)
+	if err != nil {
+		return "", errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, objectKeyParams{
+		UUID:     pgram.UUID,
+		ID:       pgram.ID,
+		Station:  pgram.Station.String(),
+		Title:    fileutil.SanitizeReplaceName(pgram.Title),
+		Episode:  fileutil.SanitizeReplaceName(pgram.Episode),
+		Start:    pgram.Start,
+		FileName: filepath.Base(pgram.FilePath),
+	})
+	if err != nil {
+		return "", errors.Wrap(errutil.ErrInternal, err.Error())
+	}
+	return buf.String(), nil
+}
+
+// 録画済みファイルをオブジェクトストレージにアップロードし、object key を記録する
+// RemoveAfterUpload であればローカルのファイルを削除し、file path を空にする
+// オブジェクトストレージが設定されていなければ何もしない
+func (r *ucRecorder) upload(ctx context.Context, config recorder.Config, pgram program.Program) error {
+	if r.objectStorage == nil {
+		return nil
+	}
+
+	key, err := buildObjectKey(config.ObjectKeyTemplate, pgram)
+	if err != nil {
+		return err
+	}
+
+	err = r.objectStorage.Upload(ctx, pgram.FilePath, key)
+	if err != nil {
+		return err
+	}
+
+	err = r.programPersistence.ChangeObjectKey(ctx, pgram, key)
+	if err != nil {
+		return err
+	}
+
+	if config.RemoveAfterUpload {
+		err = os.Remove(pgram.FilePath)
+		if err != nil {
+			return errors.Wrap(errutil.ErrInternal, err.Error())
+		}
+		log.Ctx(ctx).Debug().Msgf("removed local file (file = %s)", pgram.FilePath)
+
+		// 以降はオブジェクトストレージにあるものだけを参照させる
+		err = r.programPersistence.ChangeFilePath(ctx, pgram, "")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sobadon/anrd/domain/model/program"
+	"github.com/sobadon/anrd/domain/model/recorder"
+	"github.com/sobadon/anrd/internal/timeutil"
+	mock_repository "github.com/sobadon/anrd/testdata/mock/domain/repository"
+)
+
+func Test_buildObjectKey(t *testing.T) {
+	pgram := program.Program{
+		UUID:     "b7750840-3407-44a0-b670-2b08cb8e0eb3",
+		ID:       514530,
+		Station:  program.StationAgqr,
+		Title:    "鷲崎健のヨルナイト×ヨルナイト",
+		Start:    time.Date(2022, 8, 10, 0, 0, 0, 0, timeutil.LocationJST()),
+		FilePath: "/archive/agqr/2022-08-10/2022-08-10_0000_鷲崎健のヨルナイト×ヨルナイト.ts",
+	}
+	type args struct {
+		keyTemplate string
+		pgram       program.Program
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "station, 日付, ファイル名",
+			args: args{
+				keyTemplate: `{{.Station}}/{{.Start.Format "2006-01-02"}}/{{.FileName}}`,
+				pgram:       pgram,
+			},
+			want: "agqr/2022-08-10/2022-08-10_0000_鷲崎健のヨルナイト×ヨルナイト.ts",
+		},
+		{
+			name: "UUID",
+			args: args{
+				keyTemplate: `{{.Station}}/{{.UUID}}.ts`,
+				pgram:       pgram,
+			},
+			want: "agqr/b7750840-3407-44a0-b670-2b08cb8e0eb3.ts",
+		},
+		{
+			name: "存在しないフィールドはエラー",
+			args: args{
+				keyTemplate: `{{.Unknown}}`,
+				pgram:       pgram,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildObjectKey(tt.args.keyTemplate, tt.args.pgram)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildObjectKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("buildObjectKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ucRecorder_upload(t *testing.T) {
+	tests := []struct {
+		name              string
+		removeAfterUpload bool
+	}{
+		{
+			name:              "object key を記録する",
+			removeAfterUpload: false,
+		},
+		{
+			name:              "RemoveAfterUpload であればローカルのファイルを削除し、file path を空にする",
+			removeAfterUpload: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "test.ts")
+			err := os.WriteFile(filePath, []byte("anrd"), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config := recorder.Config{
+				ObjectKeyTemplate: `{{.Station}}/{{.FileName}}`,
+				RemoveAfterUpload: tt.removeAfterUpload,
+			}
+			pgram := program.Program{
+				UUID:     "b7750840-3407-44a0-b670-2b08cb8e0eb3",
+				Station:  program.StationAgqr,
+				Status:   program.StatusDone,
+				FilePath: filePath,
+			}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockProgramPersistence := mock_repository.NewMockProgramPersistence(ctrl)
+			mockObjectStorage := mock_repository.NewMockObjectStorage(ctrl)
+			mockObjectStorage.EXPECT().
+				Upload(gomock.Any(), filePath, "agqr/test.ts").
+				Return(nil)
+			mockProgramPersistence.EXPECT().
+				ChangeObjectKey(gomock.Any(), pgram, "agqr/test.ts").
+				Return(nil)
+			if tt.removeAfterUpload {
+				mockProgramPersistence.EXPECT().
+					ChangeFilePath(gomock.Any(), pgram, "").
+					Return(nil)
+			}
+
+			r := &ucRecorder{
+				programPersistence: mockProgramPersistence,
+				objectStorage:      mockObjectStorage,
+			}
+			err = r.upload(context.Background(), config, pgram)
+			if err != nil {
+				t.Errorf("ucRecorder.upload() error = %v", err)
+			}
+
+			_, err = os.Stat(filePath)
+			if removed := os.IsNotExist(err); removed != tt.removeAfterUpload {
+				t.Errorf("ucRecorder.upload() removed = %v, want %v", removed, tt.removeAfterUpload)
+			}
+		})
+	}
+}